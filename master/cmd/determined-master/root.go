@@ -6,6 +6,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
@@ -23,6 +27,10 @@ const defaultConfigPath = "/etc/determined/master.yaml"
 // logStoreSize is how many log events to keep in memory.
 const logStoreSize = 25000
 
+// restartsEnv counts how many times the master process has re-executed itself after its actor
+// system exited.
+const restartsEnv = "DET_MASTER_RESTARTS"
+
 func newRootCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use: "determined-master",
@@ -61,8 +69,60 @@ func runRoot() error {
 		return err
 	}
 
+	// Systemd's sockets are consumed by the first run, so a re-executed master couldn't get them.
+	socketActivated := os.Getenv("LISTEN_FDS") != ""
+	restarts, _ := strconv.Atoi(os.Getenv(restartsEnv))
+
+	// Stopping the master, as supervisors do with SIGTERM, drains the servers before exiting.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	m := internal.New(logStore, config)
-	return m.Run(context.TODO())
+	err = m.Run(ctx)
+	if ctx.Err() != nil {
+		log.Info("master stopped")
+		return nil
+	}
+	if !shouldReexec(err, config.ActorSystem, restarts, socketActivated) {
+		return err
+	}
+	log.WithError(err).Warnf(
+		"re-executing master (restart %d of %d)", restarts+1, config.ActorSystem.MaxRestarts,
+	)
+	stop()
+	return reexec(restarts + 1)
+}
+
+// shouldReexec returns whether the master should restart by re-executing itself after Run returned
+// err. Re-executing, rather than running again in this process, starts it afresh without the
+// goroutines, metrics and singletons left behind by the run that failed.
+func shouldReexec(
+	err error, cfg config.ActorSystemConfig, restarts int, socketActivated bool,
+) bool {
+	if errors.Cause(err) != internal.ErrActorSystemExited || !cfg.ShouldRestart(restarts) {
+		return false
+	}
+	if socketActivated {
+		log.Warn("not restarting a master started with systemd socket activation; exiting instead")
+		return false
+	}
+	return true
+}
+
+// reexec replaces the master process with a new one running the same command, which keeps its PID
+// so that supervisors see it as the same process.
+func reexec(restarts int) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "finding master executable")
+	}
+	env := []string{fmt.Sprintf("%s=%d", restartsEnv, restarts)}
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, restartsEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	return errors.Wrap(syscall.Exec(exe, os.Args, env), "re-executing master") // #nosec G204
 }
 
 // initializeConfig initializes master config with the validated configuration populated from config
@@ -6,9 +6,11 @@ import (
 	k8sV1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/pkg/errors"
 	"google.golang.org/api/compute/v1"
 	"gotest.tools/assert"
 
+	"github.com/determined-ai/determined/master/internal"
 	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/internal/config/provconfig"
 	"github.com/determined-ai/determined/master/pkg/aproto"
@@ -182,3 +184,17 @@ func TestApplyBackwardsCompatibility(t *testing.T) {
 		})
 	}
 }
+
+func TestShouldReexec(t *testing.T) {
+	cfg := config.ActorSystemConfig{OnExit: config.ActorSystemOnExitRestart, MaxRestarts: 1}
+	exited := errors.Wrap(internal.ErrActorSystemExited, "run failed")
+
+	assert.Assert(t, shouldReexec(exited, cfg, 0, false))
+	assert.Assert(t, !shouldReexec(exited, cfg, 1, false))
+	assert.Assert(t, !shouldReexec(errors.New("database unreachable"), cfg, 0, false))
+	// Systemd's sockets can't be handed on, so socket-activated masters exit for systemd to restart.
+	assert.Assert(t, !shouldReexec(exited, cfg, 0, true))
+
+	cfg.OnExit = config.ActorSystemOnExitExit
+	assert.Assert(t, !shouldReexec(exited, cfg, 0, false))
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Quoting styles for CSV exports that can be selected with the `quoting` query parameter.
+const (
+	// CSVQuotingEscape keeps each export's historical handling of nested lists, such as
+	// backslash-escaping the labels of raw resource allocation entries.
+	CSVQuotingEscape = "escape"
+	// CSVQuotingRFC4180 relies only on RFC 4180 quoting.
+	CSVQuotingRFC4180 = "rfc4180"
+)
+
+// CSVOptions configure how a CSV export is written.
+type CSVOptions struct {
+	Delimiter rune
+	Quoting   string
+}
+
+// ParseCSVOptions reads the `delimiter` and `quoting` query parameters, defaulting to a comma and
+// CSVQuotingEscape.
+func ParseCSVOptions(c echo.Context) (CSVOptions, error) {
+	opts := CSVOptions{Delimiter: ',', Quoting: CSVQuotingEscape}
+
+	if delimiter := c.QueryParam("delimiter"); delimiter != "" {
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+				"invalid delimiter %q: must be a single character other than a quote or newline",
+				delimiter))
+		}
+		opts.Delimiter = r
+	}
+
+	switch quoting := strings.ToLower(c.QueryParam("quoting")); quoting {
+	case "":
+	case CSVQuotingEscape, CSVQuotingRFC4180:
+		opts.Quoting = quoting
+	default:
+		return opts, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+			"invalid quoting %q: must be %s or %s", quoting, CSVQuotingEscape, CSVQuotingRFC4180))
+	}
+	return opts, nil
+}
+
+// NewWriter returns a CSV writer to w that uses the configured delimiter.
+func (o CSVOptions) NewWriter(w io.Writer) *csv.Writer {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = o.Delimiter
+	return csvWriter
+}
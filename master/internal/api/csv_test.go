@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/assert"
+)
+
+func TestParseCSVOptions(t *testing.T) {
+	cases := []struct {
+		target    string
+		delimiter rune
+		quoting   string
+		valid     bool
+	}{
+		{"/", ',', CSVQuotingEscape, true},
+		{"/?delimiter=%3B", ';', CSVQuotingEscape, true},
+		{"/?delimiter=%09&quoting=RFC4180", '\t', CSVQuotingRFC4180, true},
+		{"/?quoting=escape", ',', CSVQuotingEscape, true},
+		{"/?delimiter=%3B%3B", 0, "", false},
+		{"/?delimiter=%22", 0, "", false},
+		{"/?delimiter=%0A", 0, "", false},
+		{"/?quoting=none", 0, "", false},
+	}
+
+	e := echo.New()
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+		opts, err := ParseCSVOptions(c)
+		if !tc.valid {
+			assert.Assert(t, err != nil, tc.target)
+			continue
+		}
+		assert.NilError(t, err, tc.target)
+		assert.Equal(t, opts.Delimiter, tc.delimiter, tc.target)
+		assert.Equal(t, opts.Quoting, tc.quoting, tc.target)
+	}
+}
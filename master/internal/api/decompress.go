@@ -0,0 +1,92 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ErrDecompressedBodyTooLarge is returned when reading a compressed request body that expands
+// beyond the configured limit.
+var ErrDecompressedBodyTooLarge = errors.New("decompressed request body too large")
+
+// limitedBody reads at most limit bytes from a decompressing reader, failing with
+// ErrDecompressedBodyTooLarge rather than silently truncating if there is more.
+type limitedBody struct {
+	r        io.Reader
+	read     int64
+	limit    int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrDecompressedBodyTooLarge
+	}
+	n, err := b.r.Read(p)
+	if b.read+int64(n) > b.limit {
+		// Hide the excess so that callers can't mistake a truncated prefix for the whole body.
+		n = int(b.limit - b.read)
+		b.read = b.limit
+		b.exceeded = true
+		return n, ErrDecompressedBodyTooLarge
+	}
+	b.read += int64(n)
+	return n, err
+}
+
+// DecompressedBody returns a reader over the request body, transparently decompressing it if it
+// was sent with `Content-Encoding: gzip`. Decompressed bodies are limited to limit bytes, to guard
+// against decompression bombs; reading past the limit fails with ErrDecompressedBodyTooLarge.
+func DecompressedBody(c echo.Context, limit int64) (io.Reader, error) {
+	req := c.Request()
+	switch strings.ToLower(strings.TrimSpace(req.Header.Get(echo.HeaderContentEncoding))) {
+	case "", "identity":
+		return req.Body, nil
+	case "gzip":
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid gzip body: %s", err))
+		}
+		return &limitedBody{r: io.LimitReader(gz, limit+1), limit: limit}, nil
+	default:
+		return nil, echo.NewHTTPError(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("unsupported content encoding: %s", req.Header.Get(echo.HeaderContentEncoding)))
+	}
+}
+
+// DecodeJSONBody decodes a possibly-compressed JSON request body into v, as DecompressedBody
+// does. It responds with 413 if the decompressed body exceeds limit, or if the body as sent
+// exceeds a limit set by http.MaxBytesReader, and 400 if it is not valid JSON.
+func DecodeJSONBody(c echo.Context, limit int64, v interface{}) error {
+	body, err := DecompressedBody(c, limit)
+	if err != nil {
+		return err
+	}
+	err = json.NewDecoder(body).Decode(v)
+	lb, limited := body.(*limitedBody)
+	if limited && err == nil {
+		// The gzip checksum is only verified at the end of the stream, which the decoder may stop
+		// short of, so read on to reject corrupt bodies.
+		_, err = io.Copy(io.Discard, body)
+	}
+	// The decoder may report a body cut short by the limit as a syntax error, so ask the reader
+	// too.
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+	case errors.Is(err, ErrDecompressedBodyTooLarge) || limited && lb.exceeded:
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, ErrDecompressedBodyTooLarge.Error())
+	case err != nil:
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
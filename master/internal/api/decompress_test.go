@@ -0,0 +1,109 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/assert"
+)
+
+func gzipped(t *testing.T, s string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(s))
+	assert.NilError(t, err)
+	assert.NilError(t, gz.Close())
+	return &buf
+}
+
+func TestDecodeJSONBody(t *testing.T) {
+	e := echo.New()
+	payload := `["` + strings.Repeat("a", 100) + `"]`
+
+	cases := []struct {
+		name     string
+		encoding string
+		body     func() *bytes.Buffer
+		limit    int64
+		status   int
+	}{
+		{"plain", "", func() *bytes.Buffer { return bytes.NewBufferString(payload) }, 10, 0},
+		{"gzip", "gzip", func() *bytes.Buffer { return gzipped(t, payload) }, 1024, 0},
+		{"gzip at limit", "gzip", func() *bytes.Buffer { return gzipped(t, payload) }, 104, 0},
+		{
+			"gzip over limit", "gzip", func() *bytes.Buffer { return gzipped(t, payload) },
+			103, http.StatusRequestEntityTooLarge,
+		},
+		{
+			"corrupt gzip", "gzip", func() *bytes.Buffer { return bytes.NewBufferString(payload) },
+			1024, http.StatusBadRequest,
+		},
+		{
+			"gzip bad checksum", "gzip", func() *bytes.Buffer {
+				buf := gzipped(t, payload)
+				buf.Bytes()[buf.Len()-8] ^= 0xff
+				return buf
+			},
+			1024, http.StatusBadRequest,
+		},
+		{
+			"unsupported", "br", func() *bytes.Buffer { return bytes.NewBufferString(payload) },
+			1024, http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", tc.body())
+			if tc.encoding != "" {
+				req.Header.Set(echo.HeaderContentEncoding, tc.encoding)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			var out []string
+			err := DecodeJSONBody(c, tc.limit, &out)
+			if tc.status == 0 {
+				assert.NilError(t, err)
+				assert.DeepEqual(t, out, []string{strings.Repeat("a", 100)})
+				return
+			}
+			httpErr, ok := err.(*echo.HTTPError)
+			assert.Assert(t, ok, "expected an HTTP error, got %v", err)
+			assert.Equal(t, httpErr.Code, tc.status)
+		})
+	}
+}
+
+func TestDecodeJSONBodyMaxBytes(t *testing.T) {
+	numbers := make([]string, 1000)
+	for i := range numbers {
+		numbers[i] = strconv.Itoa(i)
+	}
+	payload := "[" + strings.Join(numbers, ",") + "]"
+
+	// The limit applies to the body as sent, so a compressed body is cut off too.
+	for _, encoding := range []string{"", "gzip"} {
+		body := bytes.NewBufferString(payload)
+		if encoding == "gzip" {
+			body = gzipped(t, payload)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		if encoding != "" {
+			req.Header.Set(echo.HeaderContentEncoding, encoding)
+		}
+		rec := httptest.NewRecorder()
+		req.Body = http.MaxBytesReader(rec, req.Body, 16)
+
+		var out interface{}
+		err := DecodeJSONBody(echo.New().NewContext(req, rec), 1<<20, &out)
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.Assert(t, ok, "expected an HTTP error, got %v", err)
+		assert.Equal(t, httpErr.Code, http.StatusRequestEntityTooLarge, encoding)
+	}
+}
@@ -11,16 +11,22 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// JSONErrorHandler sends a JSON response with a single "message" key containing the error message.
+// JSONErrorHandler sends a JSON response with a "message" key containing the error message and,
+// for a CodedError, a "code" key containing its code.
 func JSONErrorHandler(err error, c echo.Context) {
 	// Default to a 500 internal server error unless the endpoint explicitly returns otherwise.
 	var (
 		code             = http.StatusInternalServerError
 		msg  interface{} = err
+		body             = map[string]interface{}{}
 	)
+	var coded *CodedError
 	if he, ok := err.(*echo.HTTPError); ok {
 		code = he.Code
 		msg = he.Message
+	} else if errors.As(err, &coded) {
+		code = coded.Status
+		body["code"] = coded.Code
 	}
 	if code >= 500 {
 		c.Logger().Error(err)
@@ -30,7 +36,8 @@ func JSONErrorHandler(err error, c echo.Context) {
 		if c.Request().Method == echo.HEAD {
 			err = c.NoContent(code)
 		} else {
-			err = c.JSON(code, map[string]interface{}{"message": fmt.Sprint(msg)})
+			body["message"] = fmt.Sprint(msg)
+			err = c.JSON(code, body)
 		}
 		// Log the error returned from formatting the error response.
 		if err != nil {
@@ -39,6 +46,51 @@ func JSONErrorHandler(err error, c echo.Context) {
 	}
 }
 
+// CodedError is an error with a stable, machine-readable code that clients can switch on, along
+// with the HTTP status and human-readable message to respond with.
+type CodedError struct {
+	Status  int
+	Code    string
+	Message string
+	cause   error
+}
+
+// NewCodedError returns a CodedError with the status, code and formatted message.
+func NewCodedError(status int, code string, msg string, args ...interface{}) *CodedError {
+	return &CodedError{Status: status, Code: code, Message: fmt.Sprintf(msg, args...)}
+}
+
+// WithCause sets the underlying error of the CodedError, which is appended to its message.
+func (e *CodedError) WithCause(err error) *CodedError {
+	e.cause = err
+	return e
+}
+
+func (e *CodedError) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.cause
+}
+
+// GRPCStatus converts the CodedError when it is returned from a gRPC handler.
+func (e *CodedError) GRPCStatus() *status.Status {
+	c := codes.Unknown
+	switch e.Status {
+	case http.StatusBadRequest:
+		c = codes.InvalidArgument
+	case http.StatusNotFound:
+		c = codes.NotFound
+	case http.StatusNotImplemented:
+		c = codes.Unimplemented
+	}
+	return status.New(c, e.Error())
+}
+
 var (
 	// ErrInvalid is the inner error for errors that convert to a 400. Currently
 	// only apiServer.askAtDefaultSystem respects this.
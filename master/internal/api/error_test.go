@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gotest.tools/assert"
+)
+
+func TestJSONErrorHandler(t *testing.T) {
+	handle := func(err error) (int, map[string]string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		JSONErrorHandler(err, echo.New().NewContext(req, rec))
+		var body map[string]string
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec.Code, body
+	}
+
+	code, body := handle(echo.NewHTTPError(http.StatusNotFound, "not found"))
+	assert.Equal(t, code, http.StatusNotFound)
+	assert.DeepEqual(t, body, map[string]string{"message": "not found"})
+
+	coded := NewCodedError(http.StatusBadRequest, "INVALID_START_TIME", "invalid start time").
+		WithCause(errors.New("bad layout"))
+	// Wrapped coded errors keep their status and code.
+	code, body = handle(errors.Wrap(coded, "parsing args"))
+	assert.Equal(t, code, http.StatusBadRequest)
+	assert.DeepEqual(t, body, map[string]string{
+		"message": "parsing args: invalid start time: bad layout",
+		"code":    "INVALID_START_TIME",
+	})
+
+	s, ok := status.FromError(coded)
+	assert.Assert(t, ok)
+	assert.Equal(t, s.Code(), codes.InvalidArgument)
+	assert.Equal(t, s.Message(), "invalid start time: bad layout")
+}
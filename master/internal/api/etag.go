@@ -0,0 +1,48 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Cache-Control values for responses with ETags. Both are private, since the content is only
+// available to authorized users.
+const (
+	// CacheControlImmutable lets clients reuse content that never changes without revalidating it.
+	CacheControlImmutable = "private, max-age=31536000, immutable"
+	// CacheControlRevalidate lets clients cache content that may change, as long as they
+	// revalidate it with If-None-Match before each use.
+	CacheControlRevalidate = "private, no-cache"
+)
+
+// ContentETag returns an ETag that identifies the content by its hash.
+func ContentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckETag sets the ETag and Cache-Control headers of the response and reports whether the
+// request's If-None-Match header matches the ETag, in which case the caller should respond with
+// http.StatusNotModified rather than the content.
+func CheckETag(c echo.Context, etag, cacheControl string) bool {
+	quoted := `"` + etag + `"`
+	header := c.Response().Header()
+	header.Set("ETag", quoted)
+	header.Set(echo.HeaderCacheControl, cacheControl)
+
+	ifNoneMatch := c.Request().Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		// If-None-Match uses weak comparison, so weak ETags match too.
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == quoted || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/assert"
+)
+
+func TestCheckETag(t *testing.T) {
+	etag := ContentETag([]byte("model definition"))
+	assert.Equal(t, etag, ContentETag([]byte("model definition")))
+	assert.Assert(t, etag != ContentETag([]byte("other model definition")))
+
+	check := func(ifNoneMatch string) (bool, http.Header) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		matched := CheckETag(echo.New().NewContext(req, rec), etag, CacheControlRevalidate)
+		return matched, rec.Header()
+	}
+
+	matched, header := check("")
+	assert.Assert(t, !matched)
+	assert.Equal(t, header.Get("ETag"), `"`+etag+`"`)
+	assert.Equal(t, header.Get(echo.HeaderCacheControl), CacheControlRevalidate)
+
+	for _, ifNoneMatch := range []string{`"` + etag + `"`, `"stale", W/"` + etag + `"`, "*"} {
+		matched, _ = check(ifNoneMatch)
+		assert.Assert(t, matched, ifNoneMatch)
+	}
+	matched, _ = check(`"stale"`)
+	assert.Assert(t, !matched)
+}
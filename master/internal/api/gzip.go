@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Gzip returns a middleware that compresses responses to clients that accept gzip, once they
+// reach minLength bytes; smaller responses aren't worth the CPU. Responses are buffered until they
+// reach minLength, so a response that ends, or is flushed, before then is sent uncompressed.
+// Requests for which skipper returns true, and responses that already have a Content-Encoding or
+// no content, are never compressed.
+func Gzip(minLength int, skipper middleware.Skipper) echo.MiddlewareFunc {
+	pool := &sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skipper(c) {
+				return next(c)
+			}
+			res := c.Response()
+			res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			if !strings.Contains(c.Request().Header.Get(echo.HeaderAcceptEncoding), "gzip") {
+				return next(c)
+			}
+
+			w := &gzipResponseWriter{ResponseWriter: res.Writer, minLength: minLength, pool: pool}
+			res.Writer = w
+			defer func() {
+				w.close()
+				res.Writer = w.ResponseWriter
+			}()
+			return next(c)
+		}
+	}
+}
+
+// compressedMediaTypes are the media types of content that is already compressed, such as
+// checkpoint archives, which gzip would spend CPU on for no gain.
+var compressedMediaTypes = map[string]bool{
+	"application/gzip":   true,
+	"application/x-gzip": true,
+	"application/zip":    true,
+	"image/jpeg":         true,
+	"image/png":          true,
+	"font/woff2":         true,
+}
+
+// gzipResponseWriter holds back the status and body of a response until it either reaches
+// minLength bytes, when it starts compressing it, or ends or is flushed, when it sends it as is.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minLength int
+	pool      *sync.Pool
+
+	status int
+	buf    []byte
+	// decided is set once the status is sent, and gz once compressing.
+	decided  bool
+	gz       *gzip.Writer
+	hijacked bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.decided {
+		return
+	}
+	w.status = code
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minLength {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide sends the status, compressing the response from here on if asked to and it can be, and
+// then the buffered body.
+func (w *gzipResponseWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	switch w.status {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		compress = false
+	}
+	if header.Get(echo.HeaderContentType) == "" && len(w.buf) > 0 {
+		// Otherwise the compressed bytes would be sniffed for the content type.
+		header.Set(echo.HeaderContentType, http.DetectContentType(w.buf))
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get(echo.HeaderContentType))
+	if compress && header.Get(echo.HeaderContentEncoding) == "" && !compressedMediaTypes[mediaType] {
+		header.Del(echo.HeaderContentLength)
+		header.Set(echo.HeaderContentEncoding, "gzip")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends what has been written so far, uncompressed if it is still under minLength, so that
+// streamed responses aren't held back.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close sends a response that never reached minLength and finishes compressing one that did.
+func (w *gzipResponseWriter) close() {
+	if w.hijacked {
+		return
+	}
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(io.Discard)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
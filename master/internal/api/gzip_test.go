@@ -0,0 +1,132 @@
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"gotest.tools/assert"
+)
+
+func gzipServer(gz echo.MiddlewareFunc) *echo.Echo {
+	e := echo.New()
+	e.Use(gz)
+	e.GET("/text/:n", func(c echo.Context) error {
+		n, err := strconv.Atoi(c.Param("n"))
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, strings.Repeat("a", n))
+	})
+	e.GET("/stream", func(c echo.Context) error {
+		for _, chunk := range []string{"small", strings.Repeat("b", 2048), "end"} {
+			if _, err := c.Response().Write([]byte(chunk)); err != nil {
+				return err
+			}
+			c.Response().Flush()
+		}
+		return nil
+	})
+	e.GET("/archive", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, "application/gzip", []byte(strings.Repeat("c", 2048)))
+	})
+	e.GET("/empty", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	return e
+}
+
+func gzipGet(e *echo.Echo, path string) (*httptest.ResponseRecorder, string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Header().Get(echo.HeaderContentEncoding) != "gzip" {
+		return rec, rec.Body.String()
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		return rec, ""
+	}
+	body, _ := io.ReadAll(zr)
+	return rec, string(body)
+}
+
+func TestGzip(t *testing.T) {
+	e := gzipServer(Gzip(1024, middleware.DefaultSkipper))
+
+	rec, body := gzipGet(e, "/text/100")
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentEncoding), "")
+	assert.Equal(t, rec.Header().Get(echo.HeaderVary), echo.HeaderAcceptEncoding)
+	assert.Equal(t, body, strings.Repeat("a", 100))
+
+	rec, body = gzipGet(e, "/text/4096")
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentEncoding), "gzip")
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentType), echo.MIMETextPlainCharsetUTF8)
+	assert.Equal(t, body, strings.Repeat("a", 4096))
+
+	// Flushing before the threshold sends the response as is, so the rest is never compressed.
+	rec, body = gzipGet(e, "/stream")
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentEncoding), "")
+	assert.Equal(t, body, "small"+strings.Repeat("b", 2048)+"end")
+
+	rec, body = gzipGet(e, "/archive")
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentEncoding), "")
+	assert.Equal(t, body, strings.Repeat("c", 2048))
+
+	rec, _ = gzipGet(e, "/empty")
+	assert.Equal(t, rec.Code, http.StatusNoContent)
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentEncoding), "")
+
+	// Clients that don't accept gzip get everything uncompressed.
+	req := httptest.NewRequest(http.MethodGet, "/text/4096", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentEncoding), "")
+	assert.Equal(t, rec.Body.Len(), 4096)
+}
+
+func TestGzipFlushAfterThreshold(t *testing.T) {
+	e := echo.New()
+	e.Use(Gzip(16, middleware.DefaultSkipper))
+	e.GET("/stream", func(c echo.Context) error {
+		for i := 0; i < 3; i++ {
+			if _, err := c.Response().Write([]byte(strings.Repeat("row,", 8))); err != nil {
+				return err
+			}
+			// Flushing partway through must leave a valid gzip stream.
+			c.Response().Flush()
+		}
+		return nil
+	})
+
+	rec, body := gzipGet(e, "/stream")
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentEncoding), "gzip")
+	assert.Equal(t, body, strings.Repeat("row,", 24))
+}
+
+// BenchmarkGzip compares compressing every response with compressing only those of at least 1KiB,
+// over a mix of small API responses, mid-sized webui assets and large exports.
+func BenchmarkGzip(b *testing.B) {
+	paths := []string{
+		"/text/64", "/text/64", "/text/256", "/text/512", "/text/2048", "/text/16384", "/text/262144",
+	}
+	for name, gz := range map[string]echo.MiddlewareFunc{
+		"all":       middleware.Gzip(),
+		"minLength": Gzip(1024, middleware.DefaultSkipper),
+	} {
+		e := gzipServer(gz)
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				gzipGet(e, paths[i%len(paths)])
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// HeaderIdempotencyKey is the request header clients set to make a request safe to retry.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+type idempotentResult struct {
+	key     string
+	done    chan struct{}
+	result  interface{}
+	err     error
+	expires time.Time
+	// elem is the result's position in IdempotencyCache.succeeded, once it has succeeded.
+	elem *list.Element
+}
+
+// IdempotencyCache remembers the results of requests by idempotency key for a window of time, so
+// that repeated requests return the original result instead of being applied again.
+type IdempotencyCache struct {
+	mu         sync.Mutex
+	window     time.Duration
+	maxEntries int
+	entries    map[string]*idempotentResult
+	// succeeded holds the remembered results, oldest first. Since every result is remembered for
+	// the same window, this is also the order in which they expire.
+	succeeded *list.List
+	now       func() time.Time
+}
+
+// NewIdempotencyCache returns an IdempotencyCache that remembers successful results for window,
+// and at most maxEntries of them; beyond that, the oldest results are forgotten early.
+func NewIdempotencyCache(window time.Duration, maxEntries int) *IdempotencyCache {
+	return &IdempotencyCache{
+		window:     window,
+		maxEntries: maxEntries,
+		entries:    map[string]*idempotentResult{},
+		succeeded:  list.New(),
+		now:        time.Now,
+	}
+}
+
+// Do calls fn unless a request with the same key has succeeded within the window, in which case it
+// returns that request's result. Concurrent calls with the same key wait for the first to finish.
+// Failed calls are not remembered, so they can be retried.
+func (i *IdempotencyCache) Do(
+	key string, fn func() (interface{}, error),
+) (result interface{}, err error) {
+	i.mu.Lock()
+	now := i.now()
+	i.pruneLocked(now)
+	if entry, ok := i.entries[key]; ok && (entry.expires.IsZero() || now.Before(entry.expires)) {
+		i.mu.Unlock()
+		<-entry.done
+		return entry.result, entry.err
+	}
+	entry := &idempotentResult{key: key, done: make(chan struct{})}
+	i.entries[key] = entry
+	i.mu.Unlock()
+
+	succeeded := false
+	defer func() {
+		entry.result, entry.err = result, err
+		i.mu.Lock()
+		if !succeeded {
+			delete(i.entries, key)
+		} else {
+			entry.expires = i.now().Add(i.window)
+			entry.elem = i.succeeded.PushBack(entry)
+			for i.succeeded.Len() > i.maxEntries {
+				i.removeLocked(i.succeeded.Front().Value.(*idempotentResult))
+			}
+		}
+		i.mu.Unlock()
+		close(entry.done)
+	}()
+	result, err = fn()
+	succeeded = err == nil
+	return result, err
+}
+
+// pruneLocked drops expired results.
+func (i *IdempotencyCache) pruneLocked(now time.Time) {
+	for elem := i.succeeded.Front(); elem != nil; elem = i.succeeded.Front() {
+		entry := elem.Value.(*idempotentResult)
+		if !now.After(entry.expires) {
+			return
+		}
+		i.removeLocked(entry)
+	}
+}
+
+func (i *IdempotencyCache) removeLocked(entry *idempotentResult) {
+	i.succeeded.Remove(entry.elem)
+	delete(i.entries, entry.key)
+}
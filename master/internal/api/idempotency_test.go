@@ -0,0 +1,78 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+)
+
+func TestIdempotencyCache(t *testing.T) {
+	now := time.Now()
+	i := NewIdempotencyCache(time.Minute, 10)
+	i.now = func() time.Time { return now }
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	result, err := i.Do("a", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 1)
+
+	// A repeat within the window returns the original result without calling fn.
+	result, err = i.Do("a", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 1)
+	assert.Equal(t, calls, 1)
+
+	result, err = i.Do("b", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 2)
+
+	// Failures aren't remembered, so a retry is applied.
+	_, err = i.Do("c", func() (interface{}, error) { return nil, errors.New("failed") })
+	assert.ErrorContains(t, err, "failed")
+	result, err = i.Do("c", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 3)
+
+	// Once the window has passed, the key is applied again.
+	now = now.Add(2 * time.Minute)
+	result, err = i.Do("a", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 4)
+}
+
+func TestIdempotencyCacheMaxEntries(t *testing.T) {
+	now := time.Now()
+	i := NewIdempotencyCache(time.Minute, 2)
+	i.now = func() time.Time { return now }
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := i.Do(key, fn)
+		assert.NilError(t, err)
+		now = now.Add(time.Second)
+	}
+	assert.Equal(t, len(i.entries), 2)
+
+	// The oldest result was evicted to stay within the limit, and the newer ones are remembered.
+	result, err := i.Do("a", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 4)
+	result, err = i.Do("c", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 3)
+	result, err = i.Do("b", fn)
+	assert.NilError(t, err)
+	assert.Equal(t, result, 5)
+	assert.Equal(t, len(i.entries), 2)
+}
@@ -6,7 +6,8 @@ import (
 )
 
 // CORSWithTargetedOrigin builds on labstack/echo CORS by dynamically setting the origin header to
-// the request's origin.
+// the request's origin. Preflight requests are answered directly with a 204 and never reach the
+// next handler.
 func CORSWithTargetedOrigin(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		origin := c.Request().Header.Get(echo.HeaderOrigin)
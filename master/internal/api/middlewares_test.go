@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/assert"
+)
+
+func TestCORSWithTargetedOriginPreflight(t *testing.T) {
+	e := echo.New()
+	e.Pre(CORSWithTargetedOrigin)
+	// Stand in for authentication, which preflight requests don't carry credentials for.
+	experiments := e.Group("/experiments", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return echo.NewHTTPError(http.StatusUnauthorized)
+		}
+	})
+	handler := func(c echo.Context) error {
+		t.Errorf("handler called for %s %s", c.Request().Method, c.Request().URL)
+		return nil
+	}
+	experiments.PATCH("/:experiment_id", handler)
+	experiments.POST("", handler)
+
+	for _, tc := range []struct {
+		target string
+		method string
+	}{
+		{"/experiments/1", http.MethodPatch},
+		{"/experiments", http.MethodPost},
+	} {
+		req := httptest.NewRequest(http.MethodOptions, tc.target, nil)
+		req.Header.Set(echo.HeaderOrigin, "https://example.com")
+		req.Header.Set(echo.HeaderAccessControlRequestMethod, tc.method)
+		req.Header.Set(echo.HeaderAccessControlRequestHeaders, "Authorization, Content-Type")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, rec.Code, http.StatusNoContent, tc.target)
+		h := rec.Header()
+		assert.Equal(t, h.Get(echo.HeaderAccessControlAllowOrigin), "https://example.com")
+		assert.Equal(t, h.Get(echo.HeaderAccessControlAllowCredentials), "true")
+		assert.Assert(t, strings.Contains(h.Get(echo.HeaderAccessControlAllowMethods), tc.method))
+		assert.Equal(t,
+			h.Get(echo.HeaderAccessControlAllowHeaders), "Authorization, Content-Type")
+	}
+}
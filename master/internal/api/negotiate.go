@@ -0,0 +1,41 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Response formats that can be selected through content negotiation.
+const (
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// MIMEApplicationNDJSON is the media type of newline-delimited JSON.
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+var mediaTypeFormats = map[string]string{
+	echo.MIMEApplicationJSON: FormatJSON,
+	MIMEApplicationNDJSON:    FormatJSONL,
+	"text/csv":               FormatCSV,
+}
+
+// NegotiateFormat returns the response format requested by the client, or defaultFormat if the
+// client did not ask for a known one. An explicit `format` query parameter takes precedence over
+// the Accept header.
+func NegotiateFormat(c echo.Context, defaultFormat string) string {
+	switch format := strings.ToLower(c.QueryParam("format")); format {
+	case FormatJSON, FormatJSONL, FormatCSV:
+		return format
+	}
+
+	for _, mediaType := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType = strings.TrimSpace(strings.Split(mediaType, ";")[0])
+		if format, ok := mediaTypeFormats[strings.ToLower(mediaType)]; ok {
+			return format
+		}
+	}
+	return defaultFormat
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/assert"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		target   string
+		accept   string
+		expected string
+	}{
+		{"/", "", FormatJSON},
+		{"/", "text/csv", FormatCSV},
+		{"/", "text/csv; charset=utf-8, application/json", FormatCSV},
+		{"/", "application/json, text/csv", FormatJSON},
+		{"/", "text/html, */*", FormatJSON},
+		{"/?format=csv", "application/json", FormatCSV},
+		{"/?format=CSV", "", FormatCSV},
+		{"/?format=xml", "text/csv", FormatCSV},
+		{"/?format=jsonl", "", FormatJSONL},
+		{"/", "application/x-ndjson", FormatJSONL},
+	}
+
+	e := echo.New()
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.accept != "" {
+			req.Header.Set(echo.HeaderAccept, tc.accept)
+		}
+		c := e.NewContext(req, httptest.NewRecorder())
+		assert.Equal(t, NegotiateFormat(c, FormatJSON), tc.expected, "%s %s", tc.target, tc.accept)
+	}
+}
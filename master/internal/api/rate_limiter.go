@@ -0,0 +1,88 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterSweepInterval is how often a RateLimiter forgets clients that have been idle long
+// enough for their buckets to refill.
+const rateLimiterSweepInterval = time.Minute
+
+// RateLimiter limits the rate of requests of each client with a token bucket per client key.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*clientRateLimiter
+	lastSweep time.Time
+}
+
+type clientRateLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that allows each client requestsPerSecond requests per
+// second on average, and up to burst requests at once.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		limit:   rate.Limit(requestsPerSecond),
+		burst:   burst,
+		clients: map[string]*clientRateLimiter{},
+	}
+}
+
+// Allow reports whether the client with the given key may make a request now and, if not, how
+// long it should wait before retrying.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	return l.allowAt(key, time.Now())
+}
+
+func (l *RateLimiter) allowAt(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimiterSweepInterval {
+		l.sweep(now)
+	}
+
+	client, ok := l.clients[key]
+	if !ok {
+		client = &clientRateLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	r := client.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, rateLimiterSweepInterval
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		// The request is rejected rather than delayed, so it must not use up a token.
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep forgets clients whose buckets have refilled since they were last seen, since a new bucket
+// for them would be the same.
+func (l *RateLimiter) sweep(now time.Time) {
+	refill := time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+	for key, client := range l.clients {
+		if now.Sub(client.lastSeen) > refill {
+			delete(l.clients, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Clients returns the number of clients being tracked.
+func (l *RateLimiter) Clients() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.clients)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(1, 2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.allowAt("user:1", now)
+		assert.Assert(t, ok)
+	}
+	ok, retryAfter := l.allowAt("user:1", now)
+	assert.Assert(t, !ok)
+	assert.Equal(t, retryAfter, time.Second)
+
+	// Rejected requests don't use up tokens, so waiting as long as told is enough.
+	ok, _ = l.allowAt("user:1", now.Add(retryAfter))
+	assert.Assert(t, ok)
+
+	// Each client has its own bucket.
+	ok, _ = l.allowAt("ip:10.0.0.1", now)
+	assert.Assert(t, ok)
+	assert.Equal(t, l.Clients(), 2)
+
+	// Clients idle long enough for their buckets to refill are forgotten.
+	ok, _ = l.allowAt("ip:10.0.0.2", now.Add(rateLimiterSweepInterval+time.Second))
+	assert.Assert(t, ok)
+	assert.Equal(t, l.Clients(), 1)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RowWriter writes the rows of a tabular export, the first of which is the header. Like
+// csv.Writer, writes are buffered until Flush, and errors are reported by Error.
+type RowWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+	// Close ends the output and flushes it. No rows may be written after it.
+	Close() error
+}
+
+// NewRowWriter returns a RowWriter to the response in the format negotiated with the client and
+// sets the response content type accordingly. Clients that ask for JSON get an array of objects
+// keyed by the header; all others get a CSV written with csvOptions.
+func NewRowWriter(c echo.Context, csvOptions CSVOptions) RowWriter {
+	if NegotiateFormat(c, FormatCSV) == FormatJSON {
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		return newJSONRowWriter(c.Response())
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	return csvRowWriter{csvOptions.NewWriter(c.Response())}
+}
+
+type csvRowWriter struct {
+	*csv.Writer
+}
+
+func (w csvRowWriter) Close() error {
+	w.Flush()
+	return w.Error()
+}
+
+// jsonRowWriter streams rows as a JSON array of objects keyed by the header row.
+type jsonRowWriter struct {
+	w      *bufio.Writer
+	header []string
+	rows   int
+	closed bool
+	err    error
+}
+
+func newJSONRowWriter(w io.Writer) *jsonRowWriter {
+	return &jsonRowWriter{w: bufio.NewWriter(w)}
+}
+
+func (j *jsonRowWriter) Write(record []string) error {
+	if j.err != nil {
+		return j.err
+	}
+	if j.header == nil {
+		j.header = append([]string{}, record...)
+		j.err = j.writeString("[")
+		return j.err
+	}
+
+	if j.rows > 0 {
+		if j.err = j.writeString(","); j.err != nil {
+			return j.err
+		}
+	}
+	j.rows++
+	// Objects are written field by field to keep the header's column order.
+	if j.err = j.writeString("{"); j.err != nil {
+		return j.err
+	}
+	for i, name := range j.header {
+		var value string
+		if i < len(record) {
+			value = record[i]
+		}
+		if i > 0 {
+			if j.err = j.writeString(","); j.err != nil {
+				return j.err
+			}
+		}
+		if j.err = j.writeJSON(name); j.err != nil {
+			return j.err
+		}
+		if j.err = j.writeString(":"); j.err != nil {
+			return j.err
+		}
+		if j.err = j.writeJSON(value); j.err != nil {
+			return j.err
+		}
+	}
+	j.err = j.writeString("}")
+	return j.err
+}
+
+func (j *jsonRowWriter) Flush() {
+	if j.err == nil {
+		j.err = j.w.Flush()
+	}
+}
+
+func (j *jsonRowWriter) Close() error {
+	if j.err == nil && !j.closed {
+		j.closed = true
+		if j.header == nil {
+			j.err = j.writeString("[")
+		}
+		if j.err == nil {
+			j.err = j.writeString("]")
+		}
+	}
+	j.Flush()
+	return j.err
+}
+
+func (j *jsonRowWriter) Error() error {
+	return j.err
+}
+
+func (j *jsonRowWriter) writeString(s string) error {
+	_, err := j.w.WriteString(s)
+	return err
+}
+
+func (j *jsonRowWriter) writeJSON(v string) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = j.w.Write(encoded)
+	return err
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/assert"
+)
+
+func TestNewRowWriter(t *testing.T) {
+	rows := [][]string{
+		{"task_id", "labels"},
+		{"1.a", `x,"y"`},
+		{"2.b", ""},
+	}
+	write := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			req.Header.Set(echo.HeaderAccept, accept)
+		}
+		rec := httptest.NewRecorder()
+		w := NewRowWriter(echo.New().NewContext(req, rec), CSVOptions{Delimiter: ','})
+		for _, row := range rows {
+			assert.NilError(t, w.Write(row))
+			// Flushing partway through must not end the output early.
+			w.Flush()
+		}
+		assert.NilError(t, w.Close())
+		return rec
+	}
+
+	for _, accept := range []string{"", "text/csv"} {
+		rec := write(accept)
+		assert.Equal(t, rec.Header().Get(echo.HeaderContentType), "text/csv", accept)
+		assert.Equal(t, rec.Body.String(), "task_id,labels\n1.a,\"x,\"\"y\"\"\"\n2.b,\n", accept)
+	}
+
+	rec := write("application/json")
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+	assert.Equal(t, rec.Body.String(),
+		`[{"task_id":"1.a","labels":"x,\"y\""},{"task_id":"2.b","labels":""}]`)
+	var decoded []map[string]string
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &decoded))
+	assert.Equal(t, len(decoded), 2)
+}
+
+func TestJSONRowWriterEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newJSONRowWriter(rec)
+	assert.NilError(t, w.Close())
+	assert.Equal(t, rec.Body.String(), "[]")
+
+	rec = httptest.NewRecorder()
+	w = newJSONRowWriter(rec)
+	assert.NilError(t, w.Write([]string{"a", "b"}))
+	assert.NilError(t, w.Close())
+	assert.Equal(t, rec.Body.String(), "[]")
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTooManyStreams is returned when a stream cannot be opened because a limit has been reached.
+var ErrTooManyStreams = errors.New("too many concurrent streams")
+
+// StreamLimiter bounds the number of concurrently open streams, both in total and per client.
+type StreamLimiter struct {
+	mu       sync.Mutex
+	maxTotal int
+	maxPerIP int
+	total    int
+	byIP     map[string]int
+}
+
+// NewStreamLimiter returns a StreamLimiter enforcing the given limits. A non-positive limit
+// disables the corresponding check.
+func NewStreamLimiter(maxTotal, maxPerIP int) *StreamLimiter {
+	return &StreamLimiter{
+		maxTotal: maxTotal,
+		maxPerIP: maxPerIP,
+		byIP:     map[string]int{},
+	}
+}
+
+// Acquire reserves a stream for the given client. On success, it returns a function that must
+// be called exactly once when the stream ends; subsequent calls are no-ops.
+func (l *StreamLimiter) Acquire(client string) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxTotal > 0 && l.total >= l.maxTotal {
+		return nil, errors.Wrapf(ErrTooManyStreams, "limit of %d streams reached", l.maxTotal)
+	}
+	if l.maxPerIP > 0 && l.byIP[client] >= l.maxPerIP {
+		return nil, errors.Wrapf(ErrTooManyStreams, "limit of %d streams for %s reached",
+			l.maxPerIP, client)
+	}
+	l.total++
+	l.byIP[client]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.total--
+			if l.byIP[client]--; l.byIP[client] <= 0 {
+				delete(l.byIP, client)
+			}
+		})
+	}, nil
+}
+
+// Active returns the number of currently open streams.
+func (l *StreamLimiter) Active() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+)
+
+func TestStreamLimiter(t *testing.T) {
+	l := NewStreamLimiter(3, 2)
+
+	releaseA1, err := l.Acquire("10.0.0.1")
+	assert.NilError(t, err)
+	_, err = l.Acquire("10.0.0.1")
+	assert.NilError(t, err)
+
+	_, err = l.Acquire("10.0.0.1")
+	assert.Assert(t, errors.Is(err, ErrTooManyStreams))
+
+	_, err = l.Acquire("10.0.0.2")
+	assert.NilError(t, err)
+	assert.Equal(t, l.Active(), 3)
+
+	_, err = l.Acquire("10.0.0.3")
+	assert.Assert(t, errors.Is(err, ErrTooManyStreams))
+
+	releaseA1()
+	releaseA1()
+	assert.Equal(t, l.Active(), 2)
+
+	_, err = l.Acquire("10.0.0.3")
+	assert.NilError(t, err)
+}
+
+func TestStreamLimiterUnlimited(t *testing.T) {
+	l := NewStreamLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		_, err := l.Acquire("10.0.0.1")
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, l.Active(), 100)
+}
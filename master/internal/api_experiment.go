@@ -335,7 +335,9 @@ func (a *apiServer) DeleteExperiment(
 	if err := a.m.db.TrySaveExperimentState(e); err != nil {
 		return nil, errors.Wrapf(err, "transitioning to %s", e.State)
 	}
+	a.m.deletions.start(e.ID)
 	go func() {
+		defer a.m.deletions.finish(e.ID)
 		if err := a.deleteExperiment(e, &curUser); err != nil {
 			logrus.WithError(err).Errorf("deleting experiment %d", e.ID)
 			e.State = model.DeleteFailedState
@@ -1192,7 +1194,7 @@ func (a *apiServer) CreateExperiment(
 		}
 	}
 
-	e, launchWarnings, err := newExperiment(a.m, dbExp, activeConfig, taskSpec)
+	e, launchWarnings, err := newExperiment(a.m, dbExp, activeConfig, taskSpec, nil)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create experiment: %s", err)
 	}
@@ -6,6 +6,8 @@ import (
 
 	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/internal/grpcutil"
 	"github.com/determined-ai/determined/master/internal/plugin/sso"
+	"github.com/determined-ai/determined/master/internal/prom"
 	"github.com/determined-ai/determined/master/pkg/logger"
 	"github.com/determined-ai/determined/master/version"
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
@@ -84,6 +87,22 @@ func (a *apiServer) MasterLogs(
 	); err != nil {
 		return err
 	}
+	curUser, _, err := grpcutil.GetUser(resp.Context())
+	if err != nil {
+		return err
+	}
+
+	if req.Follow {
+		release, err := a.m.logStreams.Acquire(grpcutil.ClientIP(resp.Context()))
+		if err != nil {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		prom.ActiveLogStreams.Inc()
+		defer func() {
+			release()
+			prom.ActiveLogStreams.Dec()
+		}()
+	}
 
 	fetch := func(lr api.BatchRequest) (api.Batch, error) {
 		if lr.Follow {
@@ -112,6 +131,9 @@ func (a *apiServer) MasterLogs(
 	return processBatches(res, func(b api.Batch) error {
 		return b.ForEach(func(r interface{}) error {
 			lr := r.(*logger.Entry)
+			if !curUser.Admin {
+				lr = lr.Redacted(a.m.config.Log.APIFields)
+			}
 			return resp.Send(&apiv1.MasterLogsResponse{
 				LogEntry: &logv1.LogEntry{
 					Id:        int32(lr.ID),
@@ -131,15 +153,15 @@ func (a *apiServer) ResourceAllocationRaw(
 	resp := &apiv1.ResourceAllocationRawResponse{}
 
 	if req.TimestampAfter == nil {
-		return nil, errors.New("no start time provided")
+		return nil, allocationRangeError(errCodeInvalidStartTime, "no start time provided", nil)
 	}
 	if req.TimestampBefore == nil {
-		return nil, errors.New("no end time provided")
+		return nil, allocationRangeError(errCodeInvalidEndTime, "no end time provided", nil)
 	}
 	start := time.Unix(req.TimestampAfter.Seconds, int64(req.TimestampAfter.Nanos)).UTC()
 	end := time.Unix(req.TimestampBefore.Seconds, int64(req.TimestampBefore.Nanos)).UTC()
-	if start.After(end) {
-		return nil, errors.New("start time cannot be after end time")
+	if err := a.m.validateAllocationRange(start, end); err != nil {
+		return nil, err
 	}
 
 	if err := a.m.db.QueryProto(
@@ -152,8 +174,8 @@ func (a *apiServer) ResourceAllocationRaw(
 }
 
 func (a *apiServer) ResourceAllocationAggregated(
-	_ context.Context,
+	ctx context.Context,
 	req *apiv1.ResourceAllocationAggregatedRequest,
 ) (*apiv1.ResourceAllocationAggregatedResponse, error) {
-	return a.m.fetchAggregatedResourceAllocation(req)
+	return a.m.fetchAggregatedResourceAllocation(ctx, req, false, time.UTC)
 }
@@ -265,6 +265,7 @@ func (a *apiServer) LaunchNotebook(
 		"DET_TASK_TYPE":      string(model.TaskTypeNotebook),
 	}
 	spec.Port = &port
+	spec.ProxyFlushInterval = a.m.config.Proxy.FlushIntervalFor(model.TaskTypeNotebook)
 	spec.Config.Environment.Ports = map[string]int{"notebook": port}
 
 	spec.Config.Entrypoint = []string{jupyterEntrypoint}
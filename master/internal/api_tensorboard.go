@@ -246,6 +246,7 @@ func (a *apiServer) LaunchTensorboard(
 	// the same port on an agent in host mode.
 	port := getRandomPort(minTensorBoardPort, maxTensorBoardPort)
 	spec.Port = &port
+	spec.ProxyFlushInterval = a.m.config.Proxy.FlushIntervalFor(model.TaskTypeTensorboard)
 	spec.Config.Environment.Ports = map[string]int{"tensorboard": port}
 
 	spec.Metadata.ExperimentIDs = req.ExperimentIds
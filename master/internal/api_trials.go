@@ -1277,6 +1277,18 @@ func (a *apiServer) ReportCheckpoint(
 	if err := a.m.db.AddCheckpointMetadata(ctx, c); err != nil {
 		return nil, err
 	}
+	if c.AllocationID != "" {
+		// Task-level allocation exports measure each workload from the end of the one before it,
+		// so a checkpoint is recorded as ending when it was reported.
+		if err := a.m.db.RecordTaskStats(&model.TaskStats{
+			AllocationID: c.AllocationID,
+			EventType:    "CHECKPOINT",
+			StartTime:    &c.ReportTime,
+			EndTime:      &c.ReportTime,
+		}); err != nil {
+			return nil, errors.Wrap(err, "recording checkpoint task stats")
+		}
+	}
 	return &apiv1.ReportCheckpointResponse{}, nil
 }
 
@@ -3,11 +3,14 @@ package internal
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/connsave"
 	detContext "github.com/determined-ai/determined/master/internal/context"
 	"github.com/determined-ai/determined/master/internal/rbac/audit"
 )
@@ -37,7 +40,8 @@ var unauthorizedStatuses = map[int]bool{
 	http.StatusForbidden:    true,
 }
 
-func auditLogMiddleware() echo.MiddlewareFunc {
+// auditLogMiddleware records each request to the audit sink, or to the master log if sink is nil.
+func auditLogMiddleware(sink auditSink) echo.MiddlewareFunc {
 	return echo.MiddlewareFunc(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) (err error) {
 			req := c.Request()
@@ -68,17 +72,51 @@ func auditLogMiddleware() echo.MiddlewareFunc {
 				"determined_user": c.(*detContext.DetContext).GetUsername(),
 				"unauthorized":    unauthorized,
 			}
+			clientCertSubject := connsave.ClientCertSubject(req.Context())
+			if clientCertSubject != "" {
+				fields["client_cert_subject"] = clientCertSubject
+			}
 
-			var logFn LogrusLogFn
+			export, _ := c.Get(auditExportKey).(*auditExport)
+			if export != nil {
+				fields["export_start"] = export.Start
+				fields["export_end"] = export.End
+				fields["export_rows"] = export.Rows
+			}
+
+			var level log.Level
 			switch method := c.Request().Method; {
-			case infoMethods[method] || unauthorized:
-				logFn = log.WithFields(fields).Infof
+			case infoMethods[method] || unauthorized || export != nil:
+				level = log.InfoLevel
 			case debugMethods[method]:
-				logFn = log.WithFields(fields).Debugf
+				level = log.DebugLevel
 			default:
 				return
 			}
 
+			if sink != nil {
+				sink.Write(auditRecord{
+					Time:              time.Now().UTC(),
+					Type:              "echo_audit_log",
+					Level:             level.String(),
+					Method:            req.Method,
+					Path:              req.URL.Path,
+					Status:            res.Status,
+					RemoteIP:          c.RealIP(),
+					DeterminedUser:    c.(*detContext.DetContext).GetUsername(),
+					Unauthorized:      unauthorized,
+					ClientCertSubject: clientCertSubject,
+					Export:            export,
+				})
+				return
+			}
+
+			var logFn LogrusLogFn
+			if level == log.InfoLevel {
+				logFn = log.WithFields(fields).Infof
+			} else {
+				logFn = log.WithFields(fields).Debugf
+			}
 			logFn("%s %s %d", req.Method, req.URL.Path, res.Status)
 
 			return
@@ -86,6 +124,46 @@ func auditLogMiddleware() echo.MiddlewareFunc {
 	})
 }
 
+// auditExportKey is the echo context key under which handlers that export usage data describe the
+// export for the audit log.
+const auditExportKey = "audit_export"
+
+// auditExport describes an export of usage data: the time window requested and the number of data
+// rows returned.
+type auditExport struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Rows  int    `json:"rows"`
+}
+
+// auditedRowWriter counts the data rows written to an export for its audit record.
+type auditedRowWriter struct {
+	api.RowWriter
+	export *auditExport
+	header bool
+}
+
+func (w *auditedRowWriter) Write(record []string) error {
+	if err := w.RowWriter.Write(record); err != nil {
+		return err
+	}
+	if !w.header {
+		w.header = true
+	} else {
+		w.export.Rows++
+	}
+	return nil
+}
+
+// auditExportRows records that the request exports usage data for the window from start to end,
+// so that the audit log captures who exported it, and returns a writer that counts the rows
+// exported.
+func auditExportRows(c echo.Context, start, end string, w api.RowWriter) api.RowWriter {
+	export := &auditExport{Start: start, End: end}
+	c.Set(auditExportKey, export)
+	return &auditedRowWriter{RowWriter: w, export: export}
+}
+
 func authzAuditLogMiddleware() echo.MiddlewareFunc {
 	return echo.MiddlewareFunc(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) (err error) {
@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/config"
+)
+
+// auditRecord is a structured audit log entry for a single request.
+type auditRecord struct {
+	Time           time.Time `json:"time"`
+	Type           string    `json:"type"`
+	Level          string    `json:"level"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	Status         int       `json:"status"`
+	RemoteIP       string    `json:"remote_ip"`
+	DeterminedUser string    `json:"determined_user"`
+	Unauthorized   bool      `json:"unauthorized"`
+	// ClientCertSubject is the subject of the client's verified certificate when mTLS is used.
+	ClientCertSubject string `json:"client_cert_subject,omitempty"`
+	// Export describes the usage data exported by the request, if any.
+	Export *auditExport `json:"export,omitempty"`
+}
+
+// auditSink receives audit records. Write must not block the request for long.
+type auditSink interface {
+	Write(record auditRecord)
+}
+
+// newAuditSink returns the sink selected by the config, or nil to write audit records to the
+// master log.
+func newAuditSink(ctx context.Context, conf config.AuditLogSinkConfig) (auditSink, error) {
+	switch conf.Type {
+	case config.AuditLogSinkMasterLog:
+		return nil, nil
+	case config.AuditLogSinkStdout:
+		return &writerAuditSink{w: os.Stdout}, nil
+	case config.AuditLogSinkFile:
+		f, err := os.OpenFile(conf.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, errors.Wrapf(err, "opening audit log file %s", conf.Path)
+		}
+		s := &writerAuditSink{w: f}
+		go func() {
+			<-ctx.Done()
+			s.close(f)
+		}()
+		return s, nil
+	case config.AuditLogSinkHTTP:
+		return newHTTPAuditSink(ctx, conf), nil
+	default:
+		return nil, errors.Errorf("unknown audit log sink type %q", conf.Type)
+	}
+}
+
+// writerAuditSink writes audit records as JSON lines.
+type writerAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *writerAuditSink) Write(record auditRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		log.Warnf("audit log sink is closed, dropping record for %s %s", record.Method, record.Path)
+		return
+	}
+	if err := json.NewEncoder(s.w).Encode(record); err != nil {
+		log.WithError(err).Error("failed to write audit record")
+	}
+}
+
+// close closes c, the sink's writer, once writes in progress finish; records written afterwards
+// are dropped.
+func (s *writerAuditSink) close(c io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w = nil
+	if err := c.Close(); err != nil {
+		log.WithError(err).Error("failed to close audit log")
+	}
+}
+
+// httpAuditSink POSTs audit records to an endpoint from a background goroutine, retrying transient
+// failures. Records are dropped, rather than blocking requests, if the queue is full.
+type httpAuditSink struct {
+	url        string
+	maxRetries int
+	backoff    time.Duration
+	client     *http.Client
+	records    chan auditRecord
+}
+
+func newHTTPAuditSink(ctx context.Context, conf config.AuditLogSinkConfig) *httpAuditSink {
+	s := &httpAuditSink{
+		url:        conf.URL,
+		maxRetries: conf.MaxRetries,
+		backoff:    time.Second,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		records:    make(chan auditRecord, conf.BufferSize),
+	}
+	go s.run(ctx)
+	return s
+}
+
+func (s *httpAuditSink) Write(record auditRecord) {
+	select {
+	case s.records <- record:
+	default:
+		log.Warnf("audit log queue is full, dropping record for %s %s", record.Method, record.Path)
+	}
+}
+
+func (s *httpAuditSink) run(ctx context.Context) {
+	for {
+		select {
+		case record := <-s.records:
+			if err := s.send(ctx, record); err != nil {
+				log.WithError(err).Errorf(
+					"failed to send audit record for %s %s", record.Method, record.Path)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// send posts a record, retrying with exponential backoff while failures look transient.
+func (s *httpAuditSink) send(ctx context.Context, record auditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.post(ctx, body)
+		if err == nil || !retryable || attempt >= s.maxRetries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *httpAuditSink) post(ctx context.Context, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("audit log endpoint returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("audit log endpoint returned %s", resp.Status)
+	}
+}
@@ -2,8 +2,13 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,6 +16,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/config"
 	detContext "github.com/determined-ai/determined/master/internal/context"
 	"github.com/determined-ai/determined/master/pkg/model"
 )
@@ -47,7 +54,7 @@ func TestAuditLogMiddleware(t *testing.T) {
 			return h(cc)
 		}
 	})
-	e.Use(auditLogMiddleware())
+	e.Use(auditLogMiddleware(nil))
 	e.Any("/ok", echo.HandlerFunc(func(c echo.Context) error {
 		return nil
 	}))
@@ -123,3 +130,103 @@ func TestAuditLogMiddleware(t *testing.T) {
 	require.Contains(t, logs.inner[2].Message, "/notok")
 	require.Equal(t, logs.inner[2].Data["unauthorized"], true)
 }
+
+func TestHTTPAuditSinkRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	received := make(chan auditRecord, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts++; attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var record auditRecord
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+		received <- record
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink := newHTTPAuditSink(ctx, config.AuditLogSinkConfig{
+		Type: config.AuditLogSinkHTTP, URL: server.URL, BufferSize: 1, MaxRetries: 3,
+	})
+	sink.backoff = time.Millisecond
+
+	sink.Write(auditRecord{Method: http.MethodPost, Path: "/ok", DeterminedUser: "brad"})
+	select {
+	case record := <-received:
+		require.Equal(t, "/ok", record.Path)
+		require.Equal(t, "brad", record.DeterminedUser)
+	case <-time.After(10 * time.Second):
+		t.Fatal("audit record was not delivered")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 3, attempts)
+}
+
+type recordingAuditSink struct {
+	records []auditRecord
+}
+
+func (s *recordingAuditSink) Write(record auditRecord) {
+	s.records = append(s.records, record)
+}
+
+func TestAuditLogMiddlewareExport(t *testing.T) {
+	sink := &recordingAuditSink{}
+	e := echo.New()
+	e.Use(func(h echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cc := &detContext.DetContext{Context: c}
+			cc.SetUser(model.User{Username: "brad"})
+			return h(cc)
+		}
+	})
+	e.Use(auditLogMiddleware(sink))
+	e.GET("/export", func(c echo.Context) error {
+		rows := api.NewRowWriter(c, api.CSVOptions{Delimiter: ','})
+		w := auditExportRows(c, "2023-01-01", "2023-01-31", rows)
+		for _, row := range [][]string{{"key", "seconds"}, {"a", "1"}, {"b", "2"}} {
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		return w.Close()
+	})
+
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/export", nil))
+	require.Len(t, sink.records, 1)
+	record := sink.records[0]
+	require.Equal(t, "brad", record.DeterminedUser)
+	// Exports are recorded at INFO even though they are GET requests.
+	require.Equal(t, logrus.InfoLevel.String(), record.Level)
+	require.Equal(t, &auditExport{Start: "2023-01-01", End: "2023-01-31", Rows: 2}, record.Export)
+}
+
+func TestFileAuditSinkClosedOnShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	ctx, cancel := context.WithCancel(context.Background())
+	sink, err := newAuditSink(ctx, config.AuditLogSinkConfig{
+		Type: config.AuditLogSinkFile, Path: path,
+	})
+	require.NoError(t, err)
+
+	sink.Write(auditRecord{Method: http.MethodGet, Path: "/before"})
+	cancel()
+	require.Eventually(t, func() bool {
+		s := sink.(*writerAuditSink)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.w == nil
+	}, 10*time.Second, 10*time.Millisecond)
+	sink.Write(auditRecord{Method: http.MethodGet, Path: "/after"})
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(contents), "/before")
+	require.NotContains(t, string(contents), "/after")
+}
@@ -22,6 +22,9 @@ import (
 	"github.com/determined-ai/determined/proto/pkg/experimentv1"
 )
 
+// ErrInvalidPath is returned when a requested path could escape an experiment's model definition.
+var ErrInvalidPath = errors.New("path escapes the model definition directory")
+
 type modelDefFolder struct {
 	fileTree   []*experimentv1.FileNode
 	cachedTime time.Time
@@ -137,6 +140,22 @@ func (f *FileCache) genPathWithValidation(expID int, path string) (string, error
 	return p, nil
 }
 
+// validateRequestPath rejects absolute paths and paths with `..` components, so a requested
+// path can never refer to anything outside of the experiment's model definition.
+func validateRequestPath(path string) error {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") {
+		return ErrInvalidPath
+	}
+	for _, component := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == filepath.Separator
+	}) {
+		if component == ".." {
+			return ErrInvalidPath
+		}
+	}
+	return nil
+}
+
 func (f *FileCache) genPath(expID int, path string) string {
 	return filepath.Join(f.rootDir, strconv.Itoa(expID), path)
 }
@@ -162,6 +181,9 @@ func (f *FileCache) FileTreeNested(expID int) ([]*experimentv1.FileNode, error)
 
 // FileContent returns file with given experiment id and path.
 func (f *FileCache) FileContent(expID int, path string) ([]byte, error) {
+	if err := validateRequestPath(path); err != nil {
+		return []byte{}, err
+	}
 	fileTree, folder, err := f.getFileTree(expID)
 	if err != nil {
 		return []byte{}, err
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -196,3 +198,45 @@ func TestGenPathWithValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestFileContentRejectsTraversal(t *testing.T) {
+	testExpID := 1
+	f := NewFileCache(t.TempDir(), 2*time.Hour)
+	require.NoError(t, os.MkdirAll(f.genPath(testExpID, "a"), 0o700))
+	require.NoError(t, os.WriteFile(f.genPath(testExpID, "a/b.py"), []byte("ok"), 0o600))
+
+	// Seed the cache directly, including entries a malicious tarball might have smuggled in,
+	// so the guard is exercised independently of the file tree.
+	maliciousPaths := []string{
+		"..",
+		"../secret",
+		"../../etc/passwd",
+		"a/../../secret",
+		"a/..",
+		"/etc/passwd",
+		"a\\..\\..\\secret",
+	}
+	fileTree := []*experimentv1.FileNode{{Path: "a/b.py"}}
+	for _, p := range maliciousPaths {
+		fileTree = append(fileTree, &experimentv1.FileNode{Path: p})
+	}
+	f.caches[testExpID] = &modelDefFolder{
+		path:       f.genPath(testExpID, ""),
+		fileTree:   fileTree,
+		cachedTime: time.Now(),
+	}
+
+	for _, p := range maliciousPaths {
+		if p == "a\\..\\..\\secret" && filepath.Separator != '\\' {
+			// Backslashes are ordinary filename characters outside of Windows.
+			continue
+		}
+		_, err := f.FileContent(testExpID, p)
+		require.ErrorIsf(t, err, ErrInvalidPath, "path %q", p)
+	}
+
+	content, err := f.FileContent(testExpID, "a/b.py")
+	require.NoError(t, err)
+	require.Equal(t, []byte("ok"), content)
+	require.NoError(t, validateRequestPath("a..b.py"))
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/config"
+)
+
+// clientCertVerifier returns a tls.Config VerifyPeerCertificate callback that rejects client
+// certificates with keys or signature algorithms weaker than the configuration allows. It runs
+// after the usual chain verification, so only the already verified chains are checked.
+func clientCertVerifier(
+	cfg config.ClientCertConfig,
+) (func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error, error) {
+	allowedAlgorithms, err := cfg.SignatureAlgorithms()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			// The root of the chain is trusted as configured, so its self-signature isn't checked.
+			for i, cert := range chain {
+				if i > 0 && i == len(chain)-1 {
+					break
+				}
+				if err := checkClientCertStrength(cert, cfg, allowedAlgorithms); err != nil {
+					log.WithError(err).
+						WithField("subject", cert.Subject.String()).
+						Warn("rejecting client certificate")
+					return err
+				}
+			}
+		}
+		return nil
+	}, nil
+}
+
+// checkClientCertStrength returns an error if the certificate's key is smaller than the minimum for
+// its type or it was signed with a disallowed algorithm.
+func checkClientCertStrength(
+	cert *x509.Certificate,
+	cfg config.ClientCertConfig,
+	allowedAlgorithms map[x509.SignatureAlgorithm]bool,
+) error {
+	if !allowedAlgorithms[cert.SignatureAlgorithm] {
+		return errors.Errorf("signature algorithm %s is not allowed", cert.SignatureAlgorithm)
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if bits := key.N.BitLen(); bits < cfg.MinRSAKeyBits {
+			return errors.Errorf(
+				"RSA key of %d bits is smaller than the minimum of %d", bits, cfg.MinRSAKeyBits)
+		}
+	case *ecdsa.PublicKey:
+		if bits := key.Curve.Params().BitSize; bits < cfg.MinECDSAKeyBits {
+			return errors.Errorf(
+				"ECDSA key of %d bits is smaller than the minimum of %d", bits, cfg.MinECDSAKeyBits)
+		}
+	case ed25519.PublicKey:
+	default:
+		return errors.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+)
+
+func newTestCert(
+	t *testing.T, pub crypto.PublicKey, priv crypto.Signer, alg x509.SignatureAlgorithm,
+) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(1),
+		Subject:            pkix.Name{CommonName: "agent"},
+		NotBefore:          time.Now().Add(-time.Hour),
+		NotAfter:           time.Now().Add(time.Hour),
+		SignatureAlgorithm: alg,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestClientCertVerifier(t *testing.T) {
+	cfg := config.DefaultConfig().Security.TLS.ClientCert
+	verify, err := clientCertVerifier(cfg)
+	require.NoError(t, err)
+
+	rsa2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsa1024, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+
+	strong := newTestCert(t, &rsa2048.PublicKey, rsa2048, x509.SHA256WithRSA)
+	cases := []struct {
+		name  string
+		cert  *x509.Certificate
+		valid bool
+	}{
+		{"rsa 2048", strong, true},
+		{"ecdsa p256", newTestCert(t, &p256.PublicKey, p256, x509.ECDSAWithSHA256), true},
+		{"rsa 1024", newTestCert(t, &rsa1024.PublicKey, rsa1024, x509.SHA256WithRSA), false},
+		{"ecdsa p224", newTestCert(t, &p224.PublicKey, p224, x509.ECDSAWithSHA256), false},
+		{"sha1", newTestCert(t, &rsa2048.PublicKey, rsa2048, x509.SHA1WithRSA), false},
+	}
+	for _, tc := range cases {
+		// The leaf of a longer chain is always checked.
+		err := verify(nil, [][]*x509.Certificate{{tc.cert, strong}})
+		if tc.valid {
+			require.NoError(t, err, tc.name)
+		} else {
+			require.Error(t, err, tc.name)
+		}
+	}
+
+	// Connections without client certificates have no chains to check.
+	require.NoError(t, verify(nil, nil))
+
+	cfg.AllowedSignatureAlgorithms = []string{"SHA256-RSA", "not-an-algorithm"}
+	_, err = clientCertVerifier(cfg)
+	require.Error(t, err)
+}
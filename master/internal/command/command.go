@@ -272,6 +272,7 @@ func (c *command) Receive(ctx *actor.Context) error {
 				Port:            *c.GenericCommandSpec.Port,
 				ProxyTCP:        c.ProxyTCP,
 				Unauthenticated: c.Unauthenticated,
+				FlushInterval:   c.ProxyFlushInterval,
 			}
 		}
 
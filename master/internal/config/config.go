@@ -3,11 +3,17 @@ package config
 import (
 	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -80,8 +86,485 @@ type DBConfig struct {
 
 // WebhooksConfig hosts configuration fields for webhook functionality.
 type WebhooksConfig struct {
-	BaseURL    string `json:"base_url"`
-	SigningKey string `json:"signing_key"`
+	BaseURL    string             `json:"base_url"`
+	SigningKey string             `json:"signing_key"`
+	Retry      WebhookRetryConfig `json:"retry"`
+}
+
+// WebhookRetryConfig hosts configuration fields for retrying failed webhook deliveries with
+// exponential backoff.
+type WebhookRetryConfig struct {
+	MaxRetries      int            `json:"max_retries"`
+	InitialInterval model.Duration `json:"initial_interval"`
+	MaxInterval     model.Duration `json:"max_interval"`
+}
+
+// Validate implements the check.Validatable interface.
+func (w WebhookRetryConfig) Validate() []error {
+	var errs []error
+	if w.MaxRetries < 0 {
+		errs = append(errs, errors.New("max_retries must be non-negative"))
+	}
+	if w.InitialInterval <= 0 {
+		errs = append(errs, errors.New("initial_interval must be positive"))
+	}
+	if w.MaxInterval < w.InitialInterval {
+		errs = append(errs, errors.New("max_interval must be at least initial_interval"))
+	}
+	return errs
+}
+
+// LogStreamConfig hosts configuration fields limiting concurrent master log streams.
+type LogStreamConfig struct {
+	MaxStreams      int `json:"max_streams"`
+	MaxStreamsPerIP int `json:"max_streams_per_ip"`
+}
+
+// Validate implements the check.Validatable interface.
+func (l LogStreamConfig) Validate() []error {
+	var errs []error
+	if l.MaxStreams < 0 {
+		errs = append(errs, errors.New("max_streams must be non-negative"))
+	}
+	if l.MaxStreamsPerIP < 0 {
+		errs = append(errs, errors.New("max_streams_per_ip must be non-negative"))
+	}
+	return errs
+}
+
+// RateLimitConfig limits how often each client may call the master's HTTP API. Clients are
+// authenticated users, or IP addresses for unauthenticated requests.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the average rate of requests allowed; 0 disables the limit.
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	// Burst is how many requests may be made at once.
+	Burst int `json:"burst"`
+	// Routes override the limit for requests whose paths start with their path prefixes.
+	Routes []RouteRateLimitConfig `json:"routes"`
+}
+
+// RouteRateLimitConfig limits the rate of requests to the routes under a path prefix.
+type RouteRateLimitConfig struct {
+	PathPrefix        string  `json:"path_prefix"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
+
+// Enabled returns whether any requests are rate limited.
+func (r RateLimitConfig) Enabled() bool {
+	if r.RequestsPerSecond > 0 {
+		return true
+	}
+	for _, route := range r.Routes {
+		if route.RequestsPerSecond > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate implements the check.Validatable interface.
+func (r RateLimitConfig) Validate() []error {
+	errs := validateRateLimit("", r.RequestsPerSecond, r.Burst)
+	for _, route := range r.Routes {
+		if !strings.HasPrefix(route.PathPrefix, "/") {
+			errs = append(errs, fmt.Errorf("path_prefix %q must start with /", route.PathPrefix))
+		}
+		errs = append(errs, validateRateLimit(
+			fmt.Sprintf("route %s: ", route.PathPrefix), route.RequestsPerSecond, route.Burst)...)
+	}
+	return errs
+}
+
+func validateRateLimit(prefix string, requestsPerSecond float64, burst int) []error {
+	var errs []error
+	if requestsPerSecond < 0 {
+		errs = append(errs, fmt.Errorf("%srequests_per_second must be non-negative", prefix))
+	}
+	if requestsPerSecond > 0 && burst < 1 {
+		errs = append(errs, fmt.Errorf("%sburst must be positive when rate limiting", prefix))
+	}
+	return errs
+}
+
+// ExperimentLabelsConfig hosts configuration fields normalizing experiment labels on creation.
+type ExperimentLabelsConfig struct {
+	TrimWhitespace bool `json:"trim_whitespace"`
+	Lowercase      bool `json:"lowercase"`
+	// DisallowedPattern rejects experiments with any label matching this regular expression.
+	DisallowedPattern string `json:"disallowed_pattern"`
+}
+
+// Validate implements the check.Validatable interface.
+func (e ExperimentLabelsConfig) Validate() []error {
+	if _, err := regexp.Compile(e.DisallowedPattern); err != nil {
+		return []error{errors.Wrap(err, "invalid disallowed_pattern")}
+	}
+	return nil
+}
+
+// ExperimentIdempotencyConfig hosts configuration fields for idempotent experiment creation.
+type ExperimentIdempotencyConfig struct {
+	// Window is how long an Idempotency-Key on experiment creation is remembered. Zero disables
+	// idempotency keys.
+	Window model.Duration `json:"window"`
+}
+
+// Validate implements the check.Validatable interface.
+func (e ExperimentIdempotencyConfig) Validate() []error {
+	if e.Window < 0 {
+		return []error{errors.New("window must be non-negative")}
+	}
+	return nil
+}
+
+// SearcherPreviewConfig hosts configuration fields bounding searcher preview simulations.
+type SearcherPreviewConfig struct {
+	// Timeout bounds how long a preview may simulate for. Zero disables the timeout.
+	Timeout model.Duration `json:"timeout"`
+	// MaxTrials bounds the number of trials a preview simulates. Zero disables the limit.
+	MaxTrials int `json:"max_trials"`
+}
+
+// Validate implements the check.Validatable interface.
+func (s SearcherPreviewConfig) Validate() []error {
+	var errs []error
+	if s.Timeout < 0 {
+		errs = append(errs, errors.New("timeout must be non-negative"))
+	}
+	if s.MaxTrials < 0 {
+		errs = append(errs, errors.New("max_trials must be non-negative"))
+	}
+	return errs
+}
+
+// TaskLogsConfig hosts configuration fields for receiving task logs.
+type TaskLogsConfig struct {
+	// IdempotencyWindow is how long batches posted with an Idempotency-Key header are remembered,
+	// so that retried batches are not stored twice. Zero disables deduplication.
+	IdempotencyWindow model.Duration `json:"idempotency_window"`
+	// IdempotencyMaxKeys caps the number of batches remembered at once; beyond it, the oldest are
+	// forgotten before their window has passed.
+	IdempotencyMaxKeys int `json:"idempotency_max_keys"`
+	// MaxBatchSize caps the number of log entries accepted in a single request.
+	MaxBatchSize int `json:"max_batch_size"`
+	// MaxBodySize caps the size in bytes of a request body as sent, before any decompression.
+	MaxBodySize int64 `json:"max_body_size"`
+}
+
+// Validate implements the check.Validatable interface.
+func (t TaskLogsConfig) Validate() []error {
+	var errs []error
+	if t.IdempotencyWindow < 0 {
+		errs = append(errs, errors.New("idempotency_window must be non-negative"))
+	}
+	if t.IdempotencyMaxKeys < 1 {
+		errs = append(errs, errors.New("idempotency_max_keys must be positive"))
+	}
+	if t.MaxBatchSize < 1 {
+		errs = append(errs, errors.New("max_batch_size must be positive"))
+	}
+	if t.MaxBodySize < 1 {
+		errs = append(errs, errors.New("max_body_size must be positive"))
+	}
+	return errs
+}
+
+// ReadinessConfig hosts configuration fields for reporting the master ready to serve traffic.
+type ReadinessConfig struct {
+	// WarmupDelay is how long to wait after the master starts serving before reporting ready.
+	WarmupDelay model.Duration `json:"warmup_delay"`
+}
+
+// Validate implements the check.Validatable interface.
+func (r ReadinessConfig) Validate() []error {
+	if r.WarmupDelay < 0 {
+		return []error{errors.New("warmup_delay must be non-negative")}
+	}
+	return nil
+}
+
+// ClusterConfig hosts configuration fields for the cluster's bookkeeping in the database.
+type ClusterConfig struct {
+	// HeartbeatInterval is how often the master records that it is alive. After a crash, the
+	// allocations it was running are taken to have ended at the last heartbeat.
+	HeartbeatInterval model.Duration `json:"heartbeat_interval"`
+}
+
+// minRecommendedHeartbeatInterval is the heartbeat interval below which the database writes
+// likely cost more than the accuracy they buy.
+const minRecommendedHeartbeatInterval = 10 * time.Second
+
+// Validate implements the check.Validatable interface.
+func (c ClusterConfig) Validate() []error {
+	if c.HeartbeatInterval <= 0 {
+		return []error{errors.New("heartbeat_interval must be positive")}
+	}
+	return nil
+}
+
+// CompressionConfig hosts configuration fields for compressing HTTP responses.
+type CompressionConfig struct {
+	// MinLength is the size in bytes a response must reach before it is compressed.
+	MinLength int `json:"min_length"`
+}
+
+// Validate implements the check.Validatable interface.
+func (c CompressionConfig) Validate() []error {
+	if c.MinLength < 0 {
+		return []error{errors.New("min_length must be non-negative")}
+	}
+	return nil
+}
+
+// ShutdownConfig hosts configuration fields for how the master stops serving requests.
+type ShutdownConfig struct {
+	// GracePeriod is how long requests in flight are given to finish once the master is stopping.
+	GracePeriod model.Duration `json:"grace_period"`
+}
+
+// Validate implements the check.Validatable interface.
+func (s ShutdownConfig) Validate() []error {
+	if s.GracePeriod < 0 {
+		return []error{errors.New("grace_period must be non-negative")}
+	}
+	return nil
+}
+
+// What the master does when its actor system exits.
+const (
+	// ActorSystemOnExitExit makes the master exit.
+	ActorSystemOnExitExit = "exit"
+	// ActorSystemOnExitRestart makes the master re-initialize itself, up to a limited number of
+	// times.
+	ActorSystemOnExitRestart = "restart"
+)
+
+// ActorSystemConfig hosts configuration fields for recovering from the actor system exiting.
+type ActorSystemConfig struct {
+	OnExit      string `json:"on_exit"`
+	MaxRestarts int    `json:"max_restarts"`
+}
+
+// Validate implements the check.Validatable interface.
+func (a ActorSystemConfig) Validate() []error {
+	var errs []error
+	switch a.OnExit {
+	case ActorSystemOnExitExit, ActorSystemOnExitRestart:
+	default:
+		errs = append(errs, errors.Errorf(
+			"on_exit must be %s or %s, got %q", ActorSystemOnExitExit, ActorSystemOnExitRestart,
+			a.OnExit))
+	}
+	if a.MaxRestarts < 0 {
+		errs = append(errs, errors.New("max_restarts must be non-negative"))
+	}
+	return errs
+}
+
+// ShouldRestart returns whether the master should re-initialize itself after its actor system
+// exited, given how many times it already has.
+func (a ActorSystemConfig) ShouldRestart(restarts int) bool {
+	return a.OnExit == ActorSystemOnExitRestart && restarts < a.MaxRestarts
+}
+
+// Response buffering strategies of the proxy to services running in the cluster.
+const (
+	// ProxyResponseBuffered copies responses through a buffer, flushing it every FlushInterval.
+	ProxyResponseBuffered = "buffered"
+	// ProxyResponseStreaming flushes responses after every write, for streaming services.
+	ProxyResponseStreaming = "streaming"
+)
+
+// ProxyConfig hosts configuration fields for how the master proxies responses from services
+// running in the cluster, such as notebooks and TensorBoards.
+type ProxyConfig struct {
+	ResponseBuffering string `json:"response_buffering"`
+	// FlushInterval is how often buffered responses are flushed to the client. Zero only flushes
+	// when the buffer fills or the response ends.
+	FlushInterval model.Duration `json:"flush_interval"`
+	// TaskTypes overrides ResponseBuffering for the services of specific task types.
+	TaskTypes map[model.TaskType]string `json:"task_types"`
+}
+
+// Validate implements the check.Validatable interface.
+func (p ProxyConfig) Validate() []error {
+	var errs []error
+	validBuffering := func(name, buffering string) {
+		switch buffering {
+		case ProxyResponseBuffered, ProxyResponseStreaming:
+		default:
+			errs = append(errs, errors.Errorf("%s must be %s or %s, got %q",
+				name, ProxyResponseBuffered, ProxyResponseStreaming, buffering))
+		}
+	}
+	validBuffering("response_buffering", p.ResponseBuffering)
+	for taskType, buffering := range p.TaskTypes {
+		switch taskType {
+		case model.TaskTypeNotebook, model.TaskTypeTensorboard:
+		default:
+			errs = append(errs, errors.Errorf("task_types: %q tasks don't proxy HTTP", taskType))
+		}
+		validBuffering(fmt.Sprintf("task_types[%s]", taskType), buffering)
+	}
+	if p.FlushInterval < 0 {
+		errs = append(errs, errors.New("flush_interval must be non-negative"))
+	}
+	return errs
+}
+
+// FlushIntervalFor returns the httputil.ReverseProxy flush interval for services of the task type,
+// which is negative to flush after every write when streaming.
+func (p ProxyConfig) FlushIntervalFor(taskType model.TaskType) time.Duration {
+	buffering, ok := p.TaskTypes[taskType]
+	if !ok {
+		buffering = p.ResponseBuffering
+	}
+	if buffering == ProxyResponseStreaming {
+		return -1
+	}
+	return time.Duration(p.FlushInterval)
+}
+
+// WebUIConfig hosts configuration fields for serving the static web directories.
+type WebUIConfig struct {
+	// TrailingSlashRedirectCode is the status used to redirect static web directory paths to
+	// their trailing-slash form.
+	TrailingSlashRedirectCode int `json:"trailing_slash_redirect_code"`
+}
+
+// Validate implements the check.Validatable interface.
+func (w WebUIConfig) Validate() []error {
+	switch w.TrailingSlashRedirectCode {
+	case http.StatusMovedPermanently, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	default:
+		return []error{errors.Errorf(
+			"trailing_slash_redirect_code must be one of 301, 307 or 308, got %d",
+			w.TrailingSlashRedirectCode)}
+	}
+}
+
+// RestoreConfig hosts configuration fields for restoring experiments on master startup.
+type RestoreConfig struct {
+	// MaxConcurrent bounds the number of experiments restored at once.
+	MaxConcurrent int `json:"max_concurrent"`
+	// MaxConcurrentPerPool further bounds the number of experiments restored at once into each
+	// of the listed resource pools.
+	MaxConcurrentPerPool map[string]int `json:"max_concurrent_per_pool"`
+}
+
+// Validate implements the check.Validatable interface.
+func (r RestoreConfig) Validate() []error {
+	var errs []error
+	if r.MaxConcurrent < 1 {
+		errs = append(errs, errors.New("max_concurrent must be at least 1"))
+	}
+	for pool, limit := range r.MaxConcurrentPerPool {
+		if limit < 1 {
+			errs = append(errs, errors.Errorf("max_concurrent_per_pool.%s must be at least 1", pool))
+		}
+	}
+	return errs
+}
+
+// GRPCConfig hosts configuration fields for the master's gRPC server. Zero values keep the gRPC
+// library defaults.
+type GRPCConfig struct {
+	// Port serves gRPC on its own port instead of multiplexing it with HTTP on the master's port.
+	Port                         int            `json:"port"`
+	MaxConcurrentStreams         uint32         `json:"max_concurrent_streams"`
+	KeepaliveMinTime             model.Duration `json:"keepalive_min_time"`
+	KeepalivePermitWithoutStream bool           `json:"keepalive_permit_without_stream"`
+	// EnableReflection registers the gRPC reflection service, which describes the API's schema.
+	EnableReflection bool `json:"enable_reflection"`
+}
+
+// Validate implements the check.Validatable interface.
+func (g GRPCConfig) Validate() []error {
+	var errs []error
+	if g.Port < 0 || g.Port > 65535 {
+		errs = append(errs, errors.New("port must be between 0 and 65535"))
+	}
+	if g.KeepaliveMinTime < 0 {
+		errs = append(errs, errors.New("keepalive_min_time must be non-negative"))
+	}
+	return errs
+}
+
+// ResourceAllocationConfig hosts configuration fields for the resource allocation endpoints.
+type ResourceAllocationConfig struct {
+	// MaxClockSkew is how far past the master's current time a requested end time may be before
+	// the query is rejected. Zero disables the check.
+	MaxClockSkew model.Duration `json:"max_clock_skew"`
+	// MaxLabelSeries caps the number of distinct labels returned by the by-label endpoint.
+	MaxLabelSeries int `json:"max_label_series"`
+	// MaxExportDuration bounds how long an allocation export may run. Zero disables it.
+	MaxExportDuration model.Duration `json:"max_export_duration"`
+	// ExportTimeoutBehavior is what to do when an export runs past MaxExportDuration.
+	ExportTimeoutBehavior string `json:"export_timeout_behavior"`
+	// RateCard, if set, prices allocations so that exports can include their cost.
+	RateCard *RateCardConfig `json:"rate_card"`
+}
+
+// RateCardConfig prices allocated compute per slot-hour, in whatever currency it is given in.
+type RateCardConfig struct {
+	// Default is the price of a slot-hour in resource pools without a price of their own.
+	Default float64 `json:"default"`
+	// ResourcePools maps resource pool names to the price of a slot-hour in them.
+	ResourcePools map[string]float64 `json:"resource_pools"`
+}
+
+// Validate implements the check.Validatable interface.
+func (r RateCardConfig) Validate() []error {
+	var errs []error
+	if r.Default < 0 {
+		errs = append(errs, errors.New("rate_card.default must be non-negative"))
+	}
+	for pool, price := range r.ResourcePools {
+		if price < 0 {
+			errs = append(errs, errors.Errorf(
+				"rate_card.resource_pools.%s must be non-negative", pool))
+		}
+	}
+	return errs
+}
+
+// SlotHourPrice returns the price of a slot-hour in the given resource pool.
+func (r RateCardConfig) SlotHourPrice(pool string) float64 {
+	if price, ok := r.ResourcePools[pool]; ok {
+		return price
+	}
+	return r.Default
+}
+
+const (
+	// ExportTimeoutTruncate ends an export that runs too long early, marking it as truncated.
+	ExportTimeoutTruncate = "truncate"
+	// ExportTimeoutError fails an export that runs too long with a 504, if possible.
+	ExportTimeoutError = "error"
+)
+
+// Validate implements the check.Validatable interface.
+func (r ResourceAllocationConfig) Validate() []error {
+	var errs []error
+	if r.MaxClockSkew < 0 {
+		errs = append(errs, errors.New("max_clock_skew must be non-negative"))
+	}
+	if r.MaxLabelSeries < 1 {
+		errs = append(errs, errors.New("max_label_series must be at least 1"))
+	}
+	if r.MaxExportDuration < 0 {
+		errs = append(errs, errors.New("max_export_duration must be non-negative"))
+	}
+	switch r.ExportTimeoutBehavior {
+	case ExportTimeoutTruncate, ExportTimeoutError:
+	default:
+		errs = append(errs, errors.Errorf(
+			"export_timeout_behavior must be %q or %q", ExportTimeoutTruncate, ExportTimeoutError,
+		))
+	}
+	return errs
 }
 
 // DefaultConfig returns the default configuration of the master.
@@ -102,7 +585,17 @@ func DefaultConfig() *Config {
 			SSH: SSHConfig{
 				RsaKeySize: 1024,
 			},
-			AuthZ: *DefaultAuthZConfig(),
+			TLS: TLSConfig{
+				ReadAttempts: 3,
+				ReadBackoff:  model.Duration(time.Second),
+				ClientCert: ClientCertConfig{
+					MinRSAKeyBits:              2048,
+					MinECDSAKeyBits:            256,
+					AllowedSignatureAlgorithms: append([]string{}, defaultAllowedSignatureAlgorithms...),
+				},
+			},
+			AuthZ:               *DefaultAuthZConfig(),
+			MaxDecompressedBody: 64 << 20,
 		},
 		// If left unspecified, the port is later filled in with 8080 (no TLS) or 8443 (TLS).
 		Port: 0,
@@ -113,6 +606,11 @@ func DefaultConfig() *Config {
 			OtelExportedOtlpEndpoint: "localhost:4317",
 			SegmentMasterKey:         DefaultSegmentMasterKey,
 			SegmentWebUIKey:          DefaultSegmentWebUIKey,
+			ExperimentStateReports: config.ExperimentStateReportsConfig{
+				BufferSize: 1024,
+				BatchSize:  50,
+				Workers:    2,
+			},
 		},
 		EnableCors:  false,
 		ClusterName: "",
@@ -131,6 +629,67 @@ func DefaultConfig() *Config {
 			MaxTrees:       100,
 		},
 		ResourceConfig: DefaultResourceConfig(),
+		LogStreams: LogStreamConfig{
+			MaxStreams:      1024,
+			MaxStreamsPerIP: 32,
+		},
+		Observability: ObservabilityConfig{
+			DebugEndpointsAuth: DebugEndpointsAuthConfig{
+				RequireAdmin: true,
+			},
+			SavedConnectionMaxLifetime: model.Duration(24 * time.Hour),
+		},
+		ResourceAllocation: ResourceAllocationConfig{
+			MaxLabelSeries:        100,
+			ExportTimeoutBehavior: ExportTimeoutTruncate,
+		},
+		Restore: RestoreConfig{
+			MaxConcurrent: 10,
+		},
+		WebUI: WebUIConfig{
+			TrailingSlashRedirectCode: http.StatusMovedPermanently,
+		},
+		ActorSystem: ActorSystemConfig{
+			OnExit:      ActorSystemOnExitExit,
+			MaxRestarts: 3,
+		},
+		Proxy: ProxyConfig{
+			ResponseBuffering: ProxyResponseBuffered,
+		},
+		Shutdown: ShutdownConfig{
+			GracePeriod: model.Duration(15 * time.Second),
+		},
+		Compression: CompressionConfig{
+			MinLength: 1024,
+		},
+		Cluster: ClusterConfig{
+			HeartbeatInterval: model.Duration(10 * time.Minute),
+		},
+		Webhooks: WebhooksConfig{
+			Retry: WebhookRetryConfig{
+				MaxRetries:      2,
+				InitialInterval: model.Duration(time.Second),
+				MaxInterval:     model.Duration(time.Minute),
+			},
+		},
+		TaskLogs: TaskLogsConfig{
+			IdempotencyMaxKeys: 100000,
+			MaxBatchSize:       10000,
+			MaxBodySize:        16 << 20,
+		},
+		ExperimentIdempotency: ExperimentIdempotencyConfig{
+			Window: model.Duration(24 * time.Hour),
+		},
+		SearcherPreview: SearcherPreviewConfig{
+			Timeout:   model.Duration(30 * time.Second),
+			MaxTrials: 10000,
+		},
+		InternalConfig: InternalConfig{
+			AuditLogSink: AuditLogSinkConfig{
+				BufferSize: 1024,
+				MaxRetries: 3,
+			},
+		},
 	}
 }
 
@@ -158,6 +717,22 @@ type Config struct {
 	Cache                 CacheConfig                       `json:"cache"`
 	Webhooks              WebhooksConfig                    `json:"webhooks"`
 	FeatureSwitches       []string                          `json:"feature_switches"`
+	LogStreams            LogStreamConfig                   `json:"log_streams"`
+	ResourceAllocation    ResourceAllocationConfig          `json:"resource_allocation"`
+	GRPC                  GRPCConfig                        `json:"grpc"`
+	Restore               RestoreConfig                     `json:"restore"`
+	TaskLogs              TaskLogsConfig                    `json:"task_logs"`
+	ExperimentLabels      ExperimentLabelsConfig            `json:"experiment_labels"`
+	ExperimentIdempotency ExperimentIdempotencyConfig       `json:"experiment_idempotency"`
+	SearcherPreview       SearcherPreviewConfig             `json:"searcher_preview"`
+	Readiness             ReadinessConfig                   `json:"readiness"`
+	Shutdown              ShutdownConfig                    `json:"shutdown"`
+	Compression           CompressionConfig                 `json:"compression"`
+	Cluster               ClusterConfig                     `json:"cluster"`
+	RateLimit             RateLimitConfig                   `json:"rate_limit"`
+	WebUI                 WebUIConfig                       `json:"webui"`
+	ActorSystem           ActorSystemConfig                 `json:"actor_system"`
+	Proxy                 ProxyConfig                       `json:"proxy"`
 	*ResourceConfig
 
 	// Internal contains "hidden" useful debugging configurations.
@@ -196,15 +771,10 @@ func (c Config) Printable() ([]byte, error) {
 	if c.Telemetry.SegmentWebUIKey != "" {
 		c.Telemetry.SegmentWebUIKey = hiddenValue
 	}
-	if c.TaskContainerDefaults.RegistryAuth != nil {
-		if c.TaskContainerDefaults.RegistryAuth.Password != "" {
-			// RegistryAuth is a pointer, so if we need to hide the password we need to be very
-			// careful to replace the pointer, not the contents behind the pointer.
-			printable := *c.TaskContainerDefaults.RegistryAuth
-			printable.Password = hiddenValue
-			c.TaskContainerDefaults.RegistryAuth = &printable
-		}
+	if c.Observability.DebugEndpointsAuth.BearerToken != "" {
+		c.Observability.DebugEndpointsAuth.BearerToken = hiddenValue
 	}
+	c.TaskContainerDefaults = c.TaskContainerDefaults.Printable()
 
 	c.CheckpointStorage = c.CheckpointStorage.Printable()
 
@@ -257,6 +827,12 @@ func (c *Config) Resolve() error {
 		log.Warn("_strict_ntsc_enabled option is removed and will not have any effect.")
 	}
 
+	if interval := time.Duration(c.Cluster.HeartbeatInterval); interval > 0 &&
+		interval < minRecommendedHeartbeatInterval {
+		log.Warnf("cluster.heartbeat_interval of %s is very low and adds database load; "+
+			"consider at least %s", interval, minRecommendedHeartbeatInterval)
+	}
+
 	return nil
 }
 
@@ -279,6 +855,28 @@ type SecurityConfig struct {
 	TLS         TLSConfig            `json:"tls"`
 	SSH         SSHConfig            `json:"ssh"`
 	AuthZ       AuthZConfig          `json:"authz"`
+	// DocsRequireAuth requires authentication to view the docs served under /docs.
+	DocsRequireAuth bool `json:"docs_require_auth"`
+	// MaxDecompressedBody caps the size in bytes of compressed request bodies on ingestion
+	// endpoints once decompressed.
+	MaxDecompressedBody int64 `json:"max_decompressed_body"`
+	// TrustedProxies are the CIDR ranges of reverse proxies whose X-Forwarded-For headers are
+	// trusted to give the client IP address. Without any, the address of the peer is used.
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// Validate implements the check.Validatable interface.
+func (s SecurityConfig) Validate() []error {
+	var errs []error
+	if s.MaxDecompressedBody < 1 {
+		errs = append(errs, errors.New("max_decompressed_body must be at least 1"))
+	}
+	for _, cidr := range s.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid trusted_proxies entry %q", cidr))
+		}
+	}
+	return errs
 }
 
 // SSHConfig is the configuration setting for SSH.
@@ -290,6 +888,13 @@ type SSHConfig struct {
 type TLSConfig struct {
 	Cert string `json:"cert"`
 	Key  string `json:"key"`
+
+	// ReadAttempts and ReadBackoff control retries of reading the certificate at startup, which
+	// can transiently fail while the files are being rotated.
+	ReadAttempts int            `json:"read_attempts"`
+	ReadBackoff  model.Duration `json:"read_backoff"`
+
+	ClientCert ClientCertConfig `json:"client_cert"`
 }
 
 // Validate implements the check.Validatable interface.
@@ -300,9 +905,73 @@ func (t *TLSConfig) Validate() []error {
 	} else if t.Key == "" && t.Cert != "" {
 		errs = append(errs, errors.New("TLS cert file provided without a key file"))
 	}
+	if t.ReadAttempts < 1 {
+		errs = append(errs, errors.New("TLS read_attempts must be at least 1"))
+	}
+	if t.ReadBackoff < 0 {
+		errs = append(errs, errors.New("TLS read_backoff must be non-negative"))
+	}
 	return errs
 }
 
+// ClientCertConfig sets the minimum strength of the client certificates the master accepts when
+// agents are required to authenticate.
+type ClientCertConfig struct {
+	MinRSAKeyBits   int `json:"min_rsa_key_bits"`
+	MinECDSAKeyBits int `json:"min_ecdsa_key_bits"`
+	// AllowedSignatureAlgorithms are the names Go gives x509 signature algorithms, such as
+	// SHA256-RSA or ECDSA-SHA384. Certificates signed with any other algorithm are rejected.
+	AllowedSignatureAlgorithms []string `json:"allowed_signature_algorithms"`
+}
+
+// defaultAllowedSignatureAlgorithms are the client certificate signature algorithms accepted by
+// default, which leave out those based on MD5 and SHA-1.
+var defaultAllowedSignatureAlgorithms = []string{
+	x509.SHA256WithRSA.String(),
+	x509.SHA384WithRSA.String(),
+	x509.SHA512WithRSA.String(),
+	x509.SHA256WithRSAPSS.String(),
+	x509.SHA384WithRSAPSS.String(),
+	x509.SHA512WithRSAPSS.String(),
+	x509.ECDSAWithSHA256.String(),
+	x509.ECDSAWithSHA384.String(),
+	x509.ECDSAWithSHA512.String(),
+	x509.PureEd25519.String(),
+}
+
+// Validate implements the check.Validatable interface.
+func (c ClientCertConfig) Validate() []error {
+	var errs []error
+	if c.MinRSAKeyBits < 0 {
+		errs = append(errs, errors.New("client_cert min_rsa_key_bits must be non-negative"))
+	}
+	if c.MinECDSAKeyBits < 0 {
+		errs = append(errs, errors.New("client_cert min_ecdsa_key_bits must be non-negative"))
+	}
+	if _, err := c.SignatureAlgorithms(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// SignatureAlgorithms returns the set of allowed signature algorithms.
+func (c ClientCertConfig) SignatureAlgorithms() (map[x509.SignatureAlgorithm]bool, error) {
+	known := map[string]x509.SignatureAlgorithm{}
+	for alg := x509.MD2WithRSA; alg <= x509.PureEd25519; alg++ {
+		known[strings.ToUpper(alg.String())] = alg
+	}
+
+	allowed := map[x509.SignatureAlgorithm]bool{}
+	for _, name := range c.AllowedSignatureAlgorithms {
+		alg, ok := known[strings.ToUpper(name)]
+		if !ok {
+			return nil, errors.Errorf("client_cert allowed_signature_algorithms: unknown algorithm %q", name)
+		}
+		allowed[alg] = true
+	}
+	return allowed, nil
+}
+
 // Validate implements the check.Validatable interface.
 func (t *SSHConfig) Validate() []error {
 	var errs []error
@@ -332,12 +1001,89 @@ func (t *TLSConfig) ReadCertificate() (*tls.Certificate, error) {
 // InternalConfig is the configuration for internal knobs.
 type InternalConfig struct {
 	AuditLoggingEnabled bool                   `json:"audit_logging_enabled"`
+	AuditLogSink        AuditLogSinkConfig     `json:"audit_log_sink"`
 	ExternalSessions    model.ExternalSessions `json:"external_sessions"`
 }
 
+// Audit log sink types.
+const (
+	AuditLogSinkMasterLog = ""
+	AuditLogSinkStdout    = "stdout"
+	AuditLogSinkFile      = "file"
+	AuditLogSinkHTTP      = "http"
+)
+
+// AuditLogSinkConfig selects where audit records are written. By default, they are written to the
+// master log; other sinks receive one JSON record per request.
+type AuditLogSinkConfig struct {
+	Type string `json:"type"`
+	// Path is the file audit records are appended to, for the file sink.
+	Path string `json:"path"`
+	// URL is the endpoint audit records are POSTed to, for the http sink.
+	URL string `json:"url"`
+	// BufferSize is the number of records the http sink queues before dropping new ones. It must be
+	// positive for the http sink.
+	BufferSize int `json:"buffer_size"`
+	// MaxRetries is the number of times the http sink retries a record after a transient failure.
+	MaxRetries int `json:"max_retries"`
+}
+
+// Validate implements the check.Validatable interface.
+func (a AuditLogSinkConfig) Validate() []error {
+	var errs []error
+	switch a.Type {
+	case AuditLogSinkMasterLog, AuditLogSinkStdout:
+	case AuditLogSinkFile:
+		if a.Path == "" {
+			errs = append(errs, errors.New("path must be set for the file audit log sink"))
+		}
+	case AuditLogSinkHTTP:
+		if a.URL == "" {
+			errs = append(errs, errors.New("url must be set for the http audit log sink"))
+		}
+		if a.BufferSize == 0 {
+			errs = append(errs, errors.New("buffer_size must be positive for the http audit log sink"))
+		}
+	default:
+		errs = append(errs, errors.Errorf("unknown audit log sink type %q", a.Type))
+	}
+	if a.BufferSize < 0 {
+		errs = append(errs, errors.New("buffer_size must be non-negative"))
+	}
+	if a.MaxRetries < 0 {
+		errs = append(errs, errors.New("max_retries must be non-negative"))
+	}
+	return errs
+}
+
 // ObservabilityConfig is the configuration for observability metrics.
 type ObservabilityConfig struct {
 	EnablePrometheus bool `json:"enable_prometheus"`
+	// ProfileCaptureDir enables on-demand capture of runtime profiles into this directory.
+	ProfileCaptureDir string `json:"profile_capture_dir"`
+	// DebugEndpointsAuth restricts access to the metrics and profiling endpoints.
+	DebugEndpointsAuth DebugEndpointsAuthConfig `json:"debug_endpoints_auth"`
+	// SavedConnectionMaxLifetime is how long a connection saved for later retrieval by handlers is
+	// tracked before it is evicted, even if it never reported closing. Zero disables eviction.
+	SavedConnectionMaxLifetime model.Duration `json:"saved_connection_max_lifetime"`
+}
+
+// Validate implements the check.Validatable interface.
+func (o ObservabilityConfig) Validate() []error {
+	if o.SavedConnectionMaxLifetime < 0 {
+		return []error{errors.New("saved_connection_max_lifetime must be non-negative")}
+	}
+	return nil
+}
+
+// DebugEndpointsAuthConfig configures how requests to the metrics and profiling endpoints are
+// authenticated. By default only admins may access them.
+type DebugEndpointsAuthConfig struct {
+	// RequireAdmin restricts the endpoints to admins.
+	RequireAdmin bool `json:"require_admin"`
+	// BearerToken, if set, is accepted in the Authorization header in place of a user session,
+	// for scrapers that can't log in.
+	BearerToken string `json:"bearer_token"`
 }
 
 func readPriorityFromScheduler(conf *SchedulerConfig) *int {
@@ -490,3 +490,125 @@ resource_manager:
 		})
 	}
 }
+
+func TestProxyConfigFlushInterval(t *testing.T) {
+	raw := `
+proxy:
+  flush_interval: 100ms
+  task_types:
+    TENSORBOARD: streaming
+`
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte(raw), unmarshaled, yaml.DisallowUnknownFields)
+	assert.NilError(t, err)
+	assert.Equal(t, len(unmarshaled.Proxy.Validate()), 0)
+	assert.Equal(t,
+		unmarshaled.Proxy.FlushIntervalFor(model.TaskTypeNotebook), 100*time.Millisecond)
+	assert.Equal(t, unmarshaled.Proxy.FlushIntervalFor(model.TaskTypeTensorboard), time.Duration(-1))
+
+	unmarshaled.Proxy.ResponseBuffering = "eager"
+	unmarshaled.Proxy.TaskTypes[model.TaskTypeShell] = ProxyResponseStreaming
+	assert.Equal(t, len(unmarshaled.Proxy.Validate()), 2)
+}
+
+func TestRestoreConfigMaxConcurrent(t *testing.T) {
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte("restore: {}"), unmarshaled, yaml.DisallowUnknownFields)
+	assert.NilError(t, err)
+	assert.Equal(t, unmarshaled.Restore.MaxConcurrent, 10)
+	assert.Equal(t, len(unmarshaled.Restore.Validate()), 0)
+
+	unmarshaled.Restore.MaxConcurrent = 0
+	assert.Equal(t, len(unmarshaled.Restore.Validate()), 1)
+}
+
+func TestClusterConfigHeartbeatInterval(t *testing.T) {
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte("cluster: {}"), unmarshaled, yaml.DisallowUnknownFields)
+	assert.NilError(t, err)
+	assert.Equal(t, time.Duration(unmarshaled.Cluster.HeartbeatInterval), 10*time.Minute)
+	assert.Equal(t, len(unmarshaled.Cluster.Validate()), 0)
+
+	err = yaml.Unmarshal(
+		[]byte("cluster: {heartbeat_interval: 30s}"), unmarshaled, yaml.DisallowUnknownFields)
+	assert.NilError(t, err)
+	assert.Equal(t, time.Duration(unmarshaled.Cluster.HeartbeatInterval), 30*time.Second)
+
+	unmarshaled.Cluster.HeartbeatInterval = 0
+	assert.Equal(t, len(unmarshaled.Cluster.Validate()), 1)
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	var cfg RateLimitConfig
+	assert.Assert(t, !cfg.Enabled())
+	assert.Equal(t, len(cfg.Validate()), 0)
+
+	raw := `
+requests_per_second: 10
+burst: 20
+routes:
+  - path_prefix: /resources/allocation
+    requests_per_second: 0.1
+    burst: 1
+`
+	assert.NilError(t, yaml.Unmarshal([]byte(raw), &cfg, yaml.DisallowUnknownFields))
+	assert.Assert(t, cfg.Enabled())
+	assert.Equal(t, len(cfg.Validate()), 0)
+
+	cfg.Burst = 0
+	cfg.Routes[0].PathPrefix = "resources"
+	assert.Equal(t, len(cfg.Validate()), 2)
+}
+
+func TestTaskLogsConfigLimits(t *testing.T) {
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte("task_logs: {}"), unmarshaled, yaml.DisallowUnknownFields)
+	assert.NilError(t, err)
+	assert.Equal(t, unmarshaled.TaskLogs.MaxBatchSize, 10000)
+	assert.Equal(t, unmarshaled.TaskLogs.MaxBodySize, int64(16<<20))
+	assert.Equal(t, len(unmarshaled.TaskLogs.Validate()), 0)
+
+	unmarshaled.TaskLogs.MaxBatchSize = 0
+	unmarshaled.TaskLogs.MaxBodySize = 0
+	assert.Equal(t, len(unmarshaled.TaskLogs.Validate()), 2)
+}
+
+func TestCompressionConfigMinLength(t *testing.T) {
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte("compression: {}"), unmarshaled, yaml.DisallowUnknownFields)
+	assert.NilError(t, err)
+	assert.Equal(t, unmarshaled.Compression.MinLength, 1024)
+	assert.Equal(t, len(unmarshaled.Compression.Validate()), 0)
+
+	unmarshaled.Compression.MinLength = -1
+	assert.Equal(t, len(unmarshaled.Compression.Validate()), 1)
+}
+
+func TestActorSystemConfigShouldRestart(t *testing.T) {
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte("actor_system: {}"), unmarshaled, yaml.DisallowUnknownFields)
+	assert.NilError(t, err)
+	assert.Equal(t, len(unmarshaled.ActorSystem.Validate()), 0)
+	assert.Assert(t, !unmarshaled.ActorSystem.ShouldRestart(0))
+
+	unmarshaled.ActorSystem.OnExit = ActorSystemOnExitRestart
+	unmarshaled.ActorSystem.MaxRestarts = 2
+	assert.Assert(t, unmarshaled.ActorSystem.ShouldRestart(1))
+	assert.Assert(t, !unmarshaled.ActorSystem.ShouldRestart(2))
+
+	unmarshaled.ActorSystem.OnExit = "reboot"
+	unmarshaled.ActorSystem.MaxRestarts = -1
+	assert.Equal(t, len(unmarshaled.ActorSystem.Validate()), 2)
+}
+
+func TestAuditLogSinkConfigBufferSize(t *testing.T) {
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte(`__internal: {audit_log_sink: {type: http, url: "http://x"}}`),
+		unmarshaled, yaml.DisallowUnknownFields)
+	assert.NilError(t, err)
+	assert.Equal(t, unmarshaled.InternalConfig.AuditLogSink.BufferSize, 1024)
+	assert.Equal(t, len(unmarshaled.InternalConfig.AuditLogSink.Validate()), 0)
+
+	unmarshaled.InternalConfig.AuditLogSink.BufferSize = 0
+	assert.Equal(t, len(unmarshaled.InternalConfig.AuditLogSink.Validate()), 1)
+}
@@ -2,17 +2,102 @@ package connsave
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/soheilhy/cmux"
+
+	"github.com/determined-ai/determined/master/internal/prom"
 )
 
 type connKey struct{}
 
+// sweepInterval is how often saved connections are checked against the max lifetime.
+const sweepInterval = time.Minute
+
+// saved tracks the connections saved by SaveConn until the server is done with them.
+var saved = struct {
+	sync.Mutex
+	// savedAt is when each tracked connection was saved.
+	savedAt     map[net.Conn]time.Time
+	maxLifetime time.Duration
+	lastSweep   time.Time
+}{savedAt: map[net.Conn]time.Time{}}
+
+// SetMaxLifetime sets how long a saved connection is tracked before it is evicted even though the
+// server never reported being done with it. Zero disables eviction.
+func SetMaxLifetime(maxLifetime time.Duration) {
+	saved.Lock()
+	defer saved.Unlock()
+	saved.maxLifetime = maxLifetime
+}
+
 // SaveConn saves net.Conn into the context for future retrieval.
 func SaveConn(ctx context.Context, c net.Conn) context.Context {
+	saved.Lock()
+	defer saved.Unlock()
+	now := time.Now()
+	saved.savedAt[c] = now
+	if saved.maxLifetime > 0 && now.Sub(saved.lastSweep) >= sweepInterval {
+		for conn, savedAt := range saved.savedAt {
+			if now.Sub(savedAt) > saved.maxLifetime {
+				delete(saved.savedAt, conn)
+			}
+		}
+		saved.lastSweep = now
+	}
+	prom.SavedConnections.Set(float64(len(saved.savedAt)))
 	return context.WithValue(ctx, connKey{}, c)
 }
 
+// TrackConnState is an http.Server ConnState hook that stops tracking saved connections once the
+// server is done with them: when they close or, since hijacked connections such as WebSockets
+// never report closing, when they are hijacked.
+func TrackConnState(c net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateClosed, http.StateHijacked:
+	default:
+		return
+	}
+	saved.Lock()
+	defer saved.Unlock()
+	delete(saved.savedAt, c)
+	prom.SavedConnections.Set(float64(len(saved.savedAt)))
+}
+
 // GetConn retrieves net.Conn from the context.
 func GetConn(ctx context.Context) net.Conn {
 	return ctx.Value(connKey{}).(net.Conn)
 }
+
+// VerifiedChains returns the peer's verified certificate chains from the connection saved in the
+// context. It returns nil if no connection was saved, the connection isn't TLS or the peer
+// presented no certificate.
+func VerifiedChains(ctx context.Context) [][]*x509.Certificate {
+	c, ok := ctx.Value(connKey{}).(net.Conn)
+	if !ok {
+		return nil
+	}
+	if muxConn, ok := c.(*cmux.MuxConn); ok {
+		c = muxConn.Conn
+	}
+	tlsConn, ok := c.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	return tlsConn.ConnectionState().VerifiedChains
+}
+
+// ClientCertSubject returns the subject of the peer's verified leaf certificate from the
+// connection saved in the context, or an empty string if there is none.
+func ClientCertSubject(ctx context.Context) string {
+	chains := VerifiedChains(ctx)
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return ""
+	}
+	return chains[0][0].Subject.String()
+}
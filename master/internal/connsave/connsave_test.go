@@ -0,0 +1,63 @@
+package connsave
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func isSaved(c net.Conn) bool {
+	saved.Lock()
+	defer saved.Unlock()
+	_, ok := saved.savedAt[c]
+	return ok
+}
+
+func TestTrackConnState(t *testing.T) {
+	for _, state := range []http.ConnState{http.StateClosed, http.StateHijacked} {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		ctx := SaveConn(context.Background(), server)
+		require.Equal(t, server, GetConn(ctx))
+		require.True(t, isSaved(server))
+
+		TrackConnState(server, http.StateActive)
+		TrackConnState(server, http.StateIdle)
+		require.True(t, isSaved(server), "connection in use untracked")
+
+		TrackConnState(server, state)
+		require.False(t, isSaved(server), "connection still tracked once %s", state)
+	}
+}
+
+func TestSavedConnectionMaxLifetime(t *testing.T) {
+	SetMaxLifetime(time.Millisecond)
+	defer SetMaxLifetime(0)
+
+	old, _ := net.Pipe()
+	defer old.Close()
+	SaveConn(context.Background(), old)
+	require.True(t, isSaved(old))
+
+	// Connections are only swept once per sweepInterval.
+	time.Sleep(2 * time.Millisecond)
+	recent, _ := net.Pipe()
+	defer recent.Close()
+	SaveConn(context.Background(), recent)
+	require.True(t, isSaved(old), "connection evicted before the next sweep")
+
+	saved.Lock()
+	saved.lastSweep = time.Time{}
+	saved.Unlock()
+	another, _ := net.Pipe()
+	defer another.Close()
+	SaveConn(context.Background(), another)
+	require.False(t, isSaved(old), "connection past its max lifetime still tracked")
+	require.True(t, isSaved(another))
+}
@@ -2,10 +2,13 @@ package internal
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,9 +20,11 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coreos/go-systemd/activation"
@@ -33,6 +38,8 @@ import (
 	"github.com/soheilhy/cmux"
 	"github.com/uptrace/bun"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/determined-ai/determined/master/internal/api"
@@ -50,6 +57,7 @@ import (
 	"github.com/determined-ai/determined/master/internal/prom"
 	"github.com/determined-ai/determined/master/internal/proxy"
 	"github.com/determined-ai/determined/master/internal/rm"
+	"github.com/determined-ai/determined/master/internal/rm/agentrm"
 	"github.com/determined-ai/determined/master/internal/rm/allocationmap"
 	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/internal/task"
@@ -61,6 +69,7 @@ import (
 	"github.com/determined-ai/determined/master/pkg/actor"
 	"github.com/determined-ai/determined/master/pkg/actor/actors"
 	"github.com/determined-ai/determined/master/pkg/aproto"
+	"github.com/determined-ai/determined/master/pkg/device"
 	"github.com/determined-ai/determined/master/pkg/etc"
 	"github.com/determined-ai/determined/master/pkg/logger"
 	"github.com/determined-ai/determined/master/pkg/model"
@@ -72,11 +81,18 @@ import (
 )
 
 const (
-	maxConcurrentRestores = 10
-	defaultAskTimeout     = 2 * time.Second
-	webuiBaseRoute        = "/det"
+	defaultAskTimeout = 2 * time.Second
+	webuiBaseRoute    = "/det"
 )
 
+// gzipSkipper skips compressing range requests, since compressing a partial response breaks its
+// byte-range semantics, and proxied requests, whose responses are passed through as the proxied
+// service sent them. Whether other responses are compressed depends on their size.
+func gzipSkipper(c echo.Context) bool {
+	return c.Request().Header.Get("Range") != "" ||
+		strings.HasPrefix(c.Request().URL.Path, proxyPrefix+"/")
+}
+
 // staticWebDirectoryPaths are the locations of static files that comprise the webui.
 var staticWebDirectoryPaths = map[string]bool{
 	"/docs":          true,
@@ -84,6 +100,10 @@ var staticWebDirectoryPaths = map[string]bool{
 	"/docs/rest-api": true,
 }
 
+// ErrActorSystemExited is the cause of the error Run returns when it stopped because the actor
+// system exited.
+var ErrActorSystemExited = errors.New("actor system exited")
+
 // Master manages the Determined master state.
 type Master struct {
 	ClusterID string
@@ -100,6 +120,21 @@ type Master struct {
 	proxy        *actor.Ref
 	taskLogger   *task.Logger
 	hpImportance *actor.Ref
+	logStreams   *api.StreamLimiter
+	restores     restoreProgress
+	deletions    experimentDeletions
+	// taskLogBatches dedupes retried task log batches; nil unless configured.
+	taskLogBatches *api.IdempotencyCache
+	// expStateReports queues telemetry reports for experiments changing state during restore.
+	expStateReports *telemetry.ExperimentStateReporter
+	// ready is set once the master has finished starting up and is serving traffic.
+	ready atomic.Bool
+	// draining is closed once the HTTP server starts shutting down.
+	draining chan struct{}
+	// startTime is when this master process was created.
+	startTime time.Time
+	// forceClosedAllocations are the allocations left open by a crash that were closed on startup.
+	forceClosedAllocations []db.ForceClosedAllocation
 
 	trialLogBackend TrialLogBackend
 	taskLogBackend  task.LogBackend
@@ -108,17 +143,164 @@ type Master struct {
 // New creates an instance of the Determined master.
 func New(logStore *logger.LogBuffer, config *config.Config) *Master {
 	logger.SetLogrus(config.Log)
+	var taskLogBatches *api.IdempotencyCache
+	if window := time.Duration(config.TaskLogs.IdempotencyWindow); window > 0 {
+		taskLogBatches = api.NewIdempotencyCache(window, config.TaskLogs.IdempotencyMaxKeys)
+	}
 	return &Master{
-		MasterID: uuid.New().String(),
-		logs:     logStore,
-		config:   config,
+		MasterID:  uuid.New().String(),
+		logs:      logStore,
+		config:    config,
+		startTime: time.Now(),
+		logStreams: api.NewStreamLimiter(
+			config.LogStreams.MaxStreams, config.LogStreams.MaxStreamsPerIP,
+		),
+		taskLogBatches: taskLogBatches,
 	}
 }
 
+// getRestoreProgress reports how many experiments the most recent restore run has finished.
+func (m *Master) getRestoreProgress(ctx echo.Context) (interface{}, error) {
+	return m.restores.snapshot(), nil
+}
+
 func (m *Master) getConfig(ctx echo.Context) (interface{}, error) {
 	return m.config.Printable()
 }
 
+// getConfigDefaults returns the built-in default master configuration, before any config file,
+// environment variable or flag has been applied.
+func (m *Master) getConfigDefaults(c echo.Context) (interface{}, error) {
+	if !c.(*detContext.DetContext).MustGetUser().Admin {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "only admins may view config defaults")
+	}
+	return config.DefaultConfig().Printable()
+}
+
+// getTaskContainerDefaults returns the task container defaults that tasks inherit, either from the
+// master config or, for a given resource pool, from that pool's config. Registry credentials are
+// hidden, and for non-admins so are environment variable values and pod specs.
+func (m *Master) getTaskContainerDefaults(c echo.Context) (interface{}, error) {
+	args := struct {
+		ResourcePool string `query:"resource_pool"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	defaults := m.taskSpec.TaskContainerDefaults
+	if args.ResourcePool != "" {
+		var err error
+		if defaults, err = m.rm.TaskContainerDefaults(
+			m.system, args.ResourcePool, m.taskSpec.TaskContainerDefaults,
+		); err != nil {
+			return nil, err
+		}
+	}
+	if !c.(*detContext.DetContext).MustGetUser().Admin {
+		return defaults.Redacted(), nil
+	}
+	return defaults.Printable(), nil
+}
+
+// resourceManagerInfo describes the effective resource manager and its resource pools.
+type resourceManagerInfo struct {
+	Type                       string   `json:"type"`
+	ResourcePools              []string `json:"resource_pools"`
+	DefaultComputeResourcePool string   `json:"default_compute_resource_pool"`
+	DefaultAuxResourcePool     string   `json:"default_aux_resource_pool"`
+}
+
+// getResourceManager reports whether the master runs the agent or Kubernetes resource manager,
+// along with its configured resource pools and defaults.
+func (m *Master) getResourceManager(ctx echo.Context) (interface{}, error) {
+	info := resourceManagerInfo{ResourcePools: []string{}}
+	switch rmConfig := m.config.ResourceManager; {
+	case rmConfig.AgentRM != nil:
+		info.Type = "agent"
+		info.DefaultComputeResourcePool = rmConfig.AgentRM.DefaultComputeResourcePool
+		info.DefaultAuxResourcePool = rmConfig.AgentRM.DefaultAuxResourcePool
+	case rmConfig.KubernetesRM != nil:
+		info.Type = "kubernetes"
+		info.DefaultComputeResourcePool = rmConfig.KubernetesRM.DefaultComputeResourcePool
+		info.DefaultAuxResourcePool = rmConfig.KubernetesRM.DefaultAuxResourcePool
+	default:
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "no resource manager configured")
+	}
+	for _, pool := range m.config.ResourcePools {
+		info.ResourcePools = append(info.ResourcePools, pool.PoolName)
+	}
+	return info, nil
+}
+
+// getExpectedContainers reports which containers the agent resource manager expects a restored
+// allocation to have and whether their agents have reconnected, to help debug restores.
+func (m *Master) getExpectedContainers(c echo.Context) (interface{}, error) {
+	args := struct {
+		AllocationID string `path:"allocation_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	if m.config.ResourceManager.AgentRM == nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			"expected containers are only tracked by the agent resource manager")
+	}
+	return agentrm.ExpectedContainers(
+		c.Request().Context(), m.system, model.AllocationID(args.AllocationID),
+	)
+}
+
+// closeAllocationAskTimeout is how long an allocation's actor may take to report its state before
+// it is considered wedged and its allocation may be closed.
+const closeAllocationAskTimeout = 5 * time.Second
+
+// postCloseAllocation forcibly closes an allocation that was orphaned while the master was
+// running, such as when its agent vanished or its actor wedged, releasing its resources and ending
+// its database record. Allocations whose actors still respond are left alone.
+func (m *Master) postCloseAllocation(c echo.Context) (interface{}, error) {
+	args := struct {
+		AllocationID string `path:"allocation_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	id := model.AllocationID(args.AllocationID)
+
+	allocation, err := m.db.AllocationByID(id)
+	switch {
+	case errors.Cause(err) == db.ErrNotFound:
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("allocation not found: %s", id))
+	case err != nil:
+		return nil, err
+	case allocation.EndTime != nil:
+		return nil, echo.NewHTTPError(http.StatusConflict,
+			fmt.Sprintf("allocation %s is already closed", id))
+	}
+
+	if ref := allocationmap.GetAllocation(id); ref != nil {
+		resp, ok := m.system.Ask(ref, task.AllocationState{}).GetOrTimeout(closeAllocationAskTimeout)
+		if ok && resp != nil {
+			return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf(
+				"allocation %s is still active; kill its task instead", id))
+		}
+		log.Warnf("allocation %s did not respond in %s, closing it", id, closeAllocationAskTimeout)
+		allocationmap.UnregisterAllocation(id)
+	}
+
+	// The resource manager may still hold resources for the allocation if its actor wedged.
+	if handler, err := m.rm.GetAllocationHandler(
+		m.system, sproto.GetAllocationHandler{ID: id},
+	); err == nil && handler != nil {
+		m.rm.Release(m.system, sproto.ResourcesReleased{AllocationRef: handler})
+	}
+
+	if err := m.db.CloseAllocation(id); err != nil {
+		return nil, err
+	}
+	log.Infof("closed orphaned allocation %s", id)
+	return nil, nil
+}
+
 // Info returns this master's information.
 func (m *Master) Info() aproto.MasterInfo {
 	telemetryInfo := aproto.TelemetryInfo{}
@@ -130,6 +312,8 @@ func (m *Master) Info() aproto.MasterInfo {
 		// Only advertise a Segment WebUI key if a key has been configured and
 		// telemetry is enabled.
 		telemetryInfo.Enabled = true
+		telemetryInfo.DeploymentLabel = m.config.Telemetry.DeploymentLabel
+		telemetryInfo.Environment = m.config.Telemetry.Environment
 
 		if m.config.Telemetry.OtelEnabled && m.config.Telemetry.OtelExportedOtlpEndpoint != "" {
 			telemetryInfo.OtelEnabled = true
@@ -137,12 +321,22 @@ func (m *Master) Info() aproto.MasterInfo {
 		}
 	}
 
+	capabilities := aproto.CapabilitiesInfo{
+		PrometheusEnabled:   m.config.Observability.EnablePrometheus,
+		AuditLoggingEnabled: m.config.InternalConfig.AuditLoggingEnabled,
+		LogBackend:          aproto.LogBackendPostgres,
+	}
+	if m.config.Logging.ElasticLoggingConfig != nil {
+		capabilities.LogBackend = aproto.LogBackendElastic
+	}
+
 	masterInfo := aproto.MasterInfo{
-		ClusterID:   m.ClusterID,
-		MasterID:    m.MasterID,
-		Version:     version.Version,
-		Telemetry:   telemetryInfo,
-		ClusterName: m.config.ClusterName,
+		ClusterID:    m.ClusterID,
+		MasterID:     m.MasterID,
+		Version:      version.Version,
+		Telemetry:    telemetryInfo,
+		ClusterName:  m.config.ClusterName,
+		Capabilities: capabilities,
 	}
 	sso.AddProviderInfoToMasterInfo(m.config, &masterInfo)
 	return masterInfo
@@ -152,19 +346,155 @@ func (m *Master) getInfo(echo.Context) (interface{}, error) {
 	return m.Info(), nil
 }
 
-func (m *Master) getMasterLogs(c echo.Context) (interface{}, error) {
+// masterUptime reports when the master process started and how long it has been running.
+type masterUptime struct {
+	StartTime     time.Time `json:"start_time"`
+	UptimeSeconds float64   `json:"uptime_seconds"`
+}
+
+// getUptime returns the master's start time and uptime, which makes restart loops easy to spot.
+func (m *Master) getUptime(echo.Context) (interface{}, error) {
+	return masterUptime{
+		StartTime:     m.startTime.UTC(),
+		UptimeSeconds: time.Since(m.startTime).Seconds(),
+	}, nil
+}
+
+// getReady responds successfully once the master is ready to serve traffic, for use as a readiness
+// probe.
+func (m *Master) getReady(c echo.Context) error {
+	if !m.ready.Load() {
+		return c.String(http.StatusServiceUnavailable, "starting")
+	}
+	return c.String(http.StatusOK, "ready")
+}
+
+// getHealth responds with HTTP status 200 while the database answers and the resource manager is
+// responsive, and 503 naming the failing subsystems otherwise, or once the master starts shutting
+// down. Unlike getReady, it keeps checking after startup, so load balancers can use it as a probe.
+func (m *Master) getHealth(c echo.Context) error {
+	select {
+	case <-m.draining:
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{"status": "draining"})
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), defaultAskTimeout)
+	defer cancel()
+	failing := runHealthChecks(ctx, []healthCheck{
+		{name: "database", check: m.db.Ping},
+		{name: "resource_manager", check: func(context.Context) error {
+			return m.pingResourceManager()
+		}},
+	})
+
+	if len(failing) > 0 {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"status":  "unhealthy",
+			"failing": failing,
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{"status": "healthy"})
+}
+
+// healthCheck is a check of one subsystem of the master, named in getHealth responses.
+type healthCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// runHealthChecks runs the checks concurrently and returns the names of those that fail or don't
+// finish before ctx is done, in the order given. The response of getHealth is unauthenticated, so
+// errors are only logged.
+func runHealthChecks(ctx context.Context, checks []healthCheck) []string {
+	results := make([]chan error, len(checks))
+	for i, c := range checks {
+		results[i] = make(chan error, 1)
+		go func(c healthCheck, result chan<- error) { result <- c.check(ctx) }(c, results[i])
+	}
+
+	failing := []string{}
+	for i, c := range checks {
+		var err error
+		select {
+		case err = <-results[i]:
+		case <-ctx.Done():
+			// Prefer the result of a check that finished just as the deadline passed.
+			select {
+			case err = <-results[i]:
+			default:
+				err = ctx.Err()
+			}
+		}
+		if err != nil {
+			log.WithError(err).Warnf("health check: %s is failing", c.name)
+			failing = append(failing, c.name)
+		}
+	}
+	return failing
+}
+
+// pingResourceManager checks the resource manager actor is alive and processing its messages.
+func (m *Master) pingResourceManager() error {
+	if m.rm == nil {
+		return errors.New("resource manager not started")
+	}
+	resp := m.system.AskAt(m.rm.Ref().Address(), actor.Ping{})
+	if resp.Source() == nil {
+		return errors.New("resource manager actor not found")
+	}
+	if _, notTimedOut := resp.GetOrTimeout(defaultAskTimeout); !notTimedOut {
+		return errors.Errorf("resource manager did not respond within %s", defaultAskTimeout)
+	}
+	return nil
+}
+
+// getSSOProviders returns just the configured SSO providers, so that the login page does not
+// need to fetch the full /info payload.
+func (m *Master) getSSOProviders(echo.Context) (interface{}, error) {
+	return sso.ProviderInfo(m.config), nil
+}
+
+// getMasterLogs returns master log entries as a JSON array, or as newline-delimited JSON objects
+// when `format=jsonl` is requested. At most log.api_max_tail entries are returned, even if `tail`
+// asks for more, is -1 for all of them or is omitted; whether that left any out is reported in the
+// logsTruncatedHeader. An empty range of IDs returns no entries.
+func (m *Master) getMasterLogs(c echo.Context) error {
 	args := struct {
-		LessThanID    *int `query:"less_than_id"`
-		GreaterThanID *int `query:"greater_than_id"`
-		Limit         *int `query:"tail"`
+		LessThanID    *int    `query:"less_than_id"`
+		GreaterThanID *int    `query:"greater_than_id"`
+		Limit         *int    `query:"tail"`
+		Component     *string `query:"component"`
+		Source        *string `query:"source"`
+		Level         *string `query:"level"`
+		Follow        bool    `query:"follow"`
 	}{}
 	if err := api.BindArgs(&args, c); err != nil {
-		return nil, err
+		return err
+	}
+	// Entries at or above the minimum level are those at least as severe, which logrus numbers
+	// lower.
+	minLevel := log.TraceLevel
+	if args.Level != nil {
+		level, err := log.ParseLevel(*args.Level)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		minLevel = level
+	}
+	if args.Follow && args.LessThanID != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "less_than_id cannot be used with follow")
 	}
 
-	limit := -1
+	limit, capped := m.config.Log.APIMaxTail, true
 	if args.Limit != nil {
-		limit = *args.Limit
+		switch tail := *args.Limit; {
+		case tail < -1:
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("tail must be -1 or at least 0, got %d", tail))
+		case tail != -1 && tail <= limit:
+			limit, capped = tail, false
+		}
 	}
 
 	startID := -1
@@ -177,58 +507,320 @@ func (m *Master) getMasterLogs(c echo.Context) (interface{}, error) {
 		endID = *args.LessThanID
 	}
 
-	entries := m.logs.Entries(startID, endID, limit)
+	isAdmin := c.(*detContext.DetContext).MustGetUser().Admin
+	matches := func(e *logger.Entry) bool {
+		if e.Level > minLevel {
+			return false
+		}
+		if args.Component != nil && e.Component != *args.Component {
+			return false
+		}
+		return args.Source == nil || strings.Contains(e.Source, *args.Source)
+	}
+	redact := func(entries []*logger.Entry) []*logger.Entry {
+		if filter := m.config.Log.APIFields; !isAdmin {
+			for i, entry := range entries {
+				entries[i] = entry.Redacted(filter)
+			}
+		}
+		return entries
+	}
+	fetch := func(startID, endID, limit int) []*logger.Entry {
+		if args.Component == nil && args.Source == nil && args.Level == nil {
+			return redact(m.logs.Entries(startID, endID, limit))
+		}
+		return redact(m.logs.EntriesMatching(startID, endID, limit, matches))
+	}
+	// When the cap rather than the client's tail limits the entries, fetch one more to tell if any
+	// were left out: the oldest ones for the tail of the logs, or the newest when paging forward.
+	fetchCapped := func() (entries []*logger.Entry, truncated bool) {
+		if !capped {
+			c.Response().Header().Set(logsTruncatedHeader, "false")
+			return fetch(startID, endID, limit), false
+		}
+		entries = fetch(startID, endID, limit+1)
+		truncated = len(entries) > limit
+		c.Response().Header().Set(logsTruncatedHeader, strconv.FormatBool(truncated))
+		switch {
+		case !truncated:
+			return entries, false
+		case startID == -1:
+			return entries[1:], true
+		default:
+			return entries[:limit], true
+		}
+	}
+
+	if args.Follow {
+		// Fix the end of the initial entries so that following picks up exactly where they end.
+		endID = m.logs.Len()
+		entries, truncated := fetchCapped()
+		next := endID
+		if truncated && startID != -1 {
+			// A forward page cut short ends before endID; follow on from its last entry so that the
+			// entries left out are streamed rather than skipped.
+			next = entries[len(entries)-1].ID + 1
+		}
+		return m.followMasterLogs(c, entries, next,
+			func(entries []*logger.Entry) []*logger.Entry {
+				var matching []*logger.Entry
+				for _, entry := range entries {
+					if matches(entry) {
+						matching = append(matching, entry)
+					}
+				}
+				return redact(matching)
+			})
+	}
+
+	entries, _ := fetchCapped()
+	if api.NegotiateFormat(c, api.FormatJSON) == api.FormatJSONL {
+		return writeJSONLines(c, entries)
+	}
 	if len(entries) == 0 {
 		// Return a zero-length array here so the JSON encoding is `[]` rather than `null`.
 		entries = make([]*logger.Entry, 0)
 	}
-	return entries, nil
+	return c.JSON(http.StatusOK, entries)
+}
+
+// getMasterLog returns the master log entry with the given ID, in the same shape as an element of
+// the getMasterLogs array.
+func (m *Master) getMasterLog(c echo.Context) (interface{}, error) {
+	args := struct {
+		LogID int `path:"log_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	entry := m.logs.Entry(args.LogID)
+	if entry == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf(
+			"log entry %d not found; it may have been evicted from the log buffer", args.LogID))
+	}
+	if !c.(*detContext.DetContext).MustGetUser().Admin {
+		entry = entry.Redacted(m.config.Log.APIFields)
+	}
+	return entry, nil
+}
+
+// logsEvictedMarker is written to a followed master log stream in place of entries that were
+// evicted from the log buffer before they could be sent, so that clients know they missed some.
+type logsEvictedMarker struct {
+	// EvictedFromID and EvictedToID are the first and one past the last ID of the missed entries.
+	EvictedFromID int `json:"evicted_from_id"`
+	EvictedToID   int `json:"evicted_to_id"`
+}
+
+// followMasterLogs writes the initial entries as newline-delimited JSON, then keeps writing
+// entries as they are logged, starting from ID next and passed through filter, until the client
+// disconnects. Entries are read from the log buffer as they are written, so nothing is buffered
+// for slow clients beyond what the log buffer already holds; entries evicted before they are read
+// are replaced with a logsEvictedMarker.
+func (m *Master) followMasterLogs(
+	c echo.Context, entries []*logger.Entry, next int,
+	filter func([]*logger.Entry) []*logger.Entry,
+) error {
+	release, err := m.logStreams.Acquire(clientKey(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusTooManyRequests, err.Error())
+	}
+	prom.ActiveLogStreams.Inc()
+	defer func() {
+		release()
+		prom.ActiveLogStreams.Dec()
+	}()
+
+	if err := writeJSONLines(c, entries); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	ticker := time.NewTicker(masterLogsBatchMissWaitTime)
+	defer ticker.Stop()
+	enc := json.NewEncoder(c.Response())
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.draining:
+			// End the response so the client sees the stream finish rather than get cut off.
+			return nil
+		case <-ticker.C:
+		}
+
+		total := m.logs.Len()
+		if total == next {
+			continue
+		}
+		// The buffered entries are consecutive, so any missing from the start were evicted.
+		batch := m.logs.Entries(next, total, -1)
+		if len(batch) > 0 && batch[0].ID > next {
+			marker := logsEvictedMarker{EvictedFromID: next, EvictedToID: batch[0].ID}
+			if err := enc.Encode(marker); err != nil {
+				return err
+			}
+		}
+		for _, entry := range filter(batch) {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		c.Response().Flush()
+		next = total
+	}
+}
+
+// logsTruncatedHeader is set on master log responses to whether log.api_max_tail left out entries
+// that the request asked for.
+const logsTruncatedHeader = "X-Determined-Logs-Truncated"
+
+// logsEvictedHeader is set on master log exports to whether logs from the start of the requested
+// window had already been evicted from the log buffer.
+const logsEvictedHeader = "X-Determined-Logs-Evicted"
+
+// getMasterLogsExport returns the master logs of a time window as a gzipped plaintext file, one
+// entry per line, for attaching to support tickets.
+func (m *Master) getMasterLogsExport(c echo.Context) error {
+	args := struct {
+		Start string `query:"timestamp_after"`
+		End   string `query:"timestamp_before"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return err
+	}
+	start, err := time.Parse("2006-01-02T15:04:05Z", args.Start)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid start time")
+	}
+	end, err := time.Parse("2006-01-02T15:04:05Z", args.End)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid end time")
+	}
+	if start.After(end) {
+		return echo.NewHTTPError(http.StatusBadRequest, "start time cannot be after end time")
+	}
+
+	entries, evicted := m.logs.EntriesBetween(start, end)
+	if filter := m.config.Log.APIFields; !c.(*detContext.DetContext).MustGetUser().Admin {
+		for i, entry := range entries {
+			entries[i] = entry.Redacted(filter)
+		}
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "application/gzip")
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf(
+		`attachment; filename="master-logs-%s-%s.log.gz"`,
+		start.Format("20060102T150405Z"), end.Format("20060102T150405Z"),
+	))
+	header.Set(logsEvictedHeader, strconv.FormatBool(evicted))
+	c.Response().WriteHeader(http.StatusOK)
+
+	gz := gzip.NewWriter(c.Response())
+	for _, entry := range entries {
+		line := fmt.Sprintf("%s [%s]", entry.Time.UTC().Format(time.RFC3339Nano), entry.Level)
+		if entry.Component != "" {
+			line += fmt.Sprintf(" [%s]", entry.Component)
+		}
+		if _, err := fmt.Fprintf(gz, "%s %s\n", line, entry.Message); err != nil {
+			return err
+		}
+	}
+	return gz.Close()
+}
+
+// writeJSONLines streams entries as newline-delimited JSON, flushing after each one so consumers
+// can process them as they arrive.
+func writeJSONLines(c echo.Context, entries []*logger.Entry) error {
+	c.Response().Header().Set(echo.HeaderContentType, api.MIMEApplicationNDJSON)
+	c.Response().WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(c.Response())
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+		c.Response().Flush()
+	}
+	return nil
 }
 
 //	@Summary	Get a detailed view of resource allocation during the given time period (CSV).
 //	@Tags		Cluster
 //	@ID			get-raw-resource-allocation-csv
 //	@Accept		json
-//	@Produce	text/csv
+//	@Produce	text/csv,json
 //	@Param		timestamp_after		query	string	true	"Start time to get allocations for (YYYY-MM-DDTHH:MM:SSZ format)"
 //	@Param		timestamp_before	query	string	true	"End time to get allocations for (YYYY-MM-DDTHH:MM:SSZ format)"
-//	@Success	200					{}		string	"A CSV file containing the fields experiment_id,kind,username,labels,slots,start_time,end_time,seconds"
+//	@Param		include_names		query	bool	false	"Whether to include experiment, project and workspace names"
+//	@Param		include_slot_seconds	query	bool	false	"Whether to include slot_seconds (slots times seconds) and gpu_hours columns"
+//	@Param		delimiter			query	string	false	"Field delimiter, a single character (default ,)"
+//	@Param		quoting				query	string	false	"escape (default) backslash-escapes commas in labels; rfc4180 quotes labels as a nested CSV record instead"
+//	@Success	200					{}		string	"A CSV file containing the fields experiment_id,kind,username,labels,slots,start_time,end_time,seconds and optionally experiment_name,project_name,workspace_name and slot_seconds,gpu_hours"
 //	@Router		/allocation/raw [get]
 //	@Deprecated
 //
 // nolint:lll
 func (m *Master) getRawResourceAllocation(c echo.Context) error {
 	args := struct {
-		Start string `query:"timestamp_after"`
-		End   string `query:"timestamp_before"`
+		Start        string `query:"timestamp_after"`
+		End          string `query:"timestamp_before"`
+		IncludeNames *bool  `query:"include_names"`
+		// IncludeSlotSeconds adds the slot-seconds and GPU hours of each entry, so that consumers
+		// need not compute them from slots and seconds.
+		IncludeSlotSeconds *bool `query:"include_slot_seconds"`
 	}{}
 	if err := api.BindArgs(&args, c); err != nil {
 		return err
 	}
 
-	start, err := time.Parse("2006-01-02T15:04:05Z", args.Start)
+	start, end, err := m.parseAllocationTimeRange(args.Start, args.End)
 	if err != nil {
-		return errors.Wrap(err, "invalid start time")
+		return err
 	}
-	end, err := time.Parse("2006-01-02T15:04:05Z", args.End)
+	csvOptions, err := api.ParseCSVOptions(c)
 	if err != nil {
-		return errors.Wrap(err, "invalid end time")
-	}
-	if start.After(end) {
-		return errors.New("start time cannot be after end time")
+		return err
 	}
 
-	resp := &apiv1.ResourceAllocationRawResponse{}
-	if err := m.db.QueryProto(
-		"get_raw_allocation", &resp.ResourceEntries, start.UTC(), end.UTC(),
-	); err != nil {
+	// Entries are written as they are read so that long histories aren't held in memory.
+	ctx, cancel := m.exportContext(c)
+	defer cancel()
+	rows, err := m.db.QueryProtoRows(ctx, "get_raw_allocation", start.UTC(), end.UTC())
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return echo.NewHTTPError(http.StatusGatewayTimeout, "export exceeded max_export_duration")
+	case err != nil:
 		return errors.Wrap(err, "error fetching allocation data")
 	}
+	defer rows.Close()
 
-	c.Response().Header().Set("Content-Type", "text/csv")
+	includeNames := args.IncludeNames != nil && *args.IncludeNames
+	includeSlotSeconds := args.IncludeSlotSeconds != nil && *args.IncludeSlotSeconds
+	// Names are looked up once per batch of entries for the experiments not seen yet, since the
+	// entries aren't known upfront.
+	names := map[int32]experimentNames{}
+	fetchNames := func(batch []*masterv1.ResourceAllocationRawEntry) error {
+		var ids []int32
+		for _, entry := range batch {
+			if _, ok := names[entry.ExperimentId]; !ok && entry.ExperimentId != 0 {
+				// Deleted experiments have no names; remember them as empty too.
+				names[entry.ExperimentId] = experimentNames{}
+				ids = append(ids, entry.ExperimentId)
+			}
+		}
+		// Names are still needed for the last batch once max_export_duration has passed.
+		fetched, err := fetchExperimentNames(c.Request().Context(), ids)
+		if err != nil {
+			return errors.Wrap(err, "error fetching experiment names")
+		}
+		for id, n := range fetched {
+			names[id] = n
+		}
+		return nil
+	}
 
-	labelEscaper := strings.NewReplacer("\\", "\\\\", ",", "\\,")
-	csvWriter := csv.NewWriter(c.Response())
+	rowWriter := auditExportRows(c, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339),
+		api.NewRowWriter(c, csvOptions))
 	formatTimestamp := func(ts *timestamppb.Timestamp) string {
 		if ts == nil {
 			return ""
@@ -236,141 +828,399 @@ func (m *Master) getRawResourceAllocation(c echo.Context) error {
 		return ts.AsTime().Format(time.RFC3339Nano)
 	}
 
+	c.Response().Header().Set("Trailer", exportTruncatedTrailer)
 	header := []string{
 		"experiment_id", "kind", "username", "labels", "slots", "start_time", "end_time", "seconds",
 	}
-	if err := csvWriter.Write(header); err != nil {
+	if includeNames {
+		header = append(header, "experiment_name", "project_name", "workspace_name")
+	}
+	if includeSlotSeconds {
+		header = append(header, "slot_seconds", "gpu_hours")
+	}
+	if err := rowWriter.Write(header); err != nil {
 		return err
 	}
 
-	for _, entry := range resp.ResourceEntries {
-		var labels []string
-		for _, label := range entry.Labels {
-			labels = append(labels, labelEscaper.Replace(label))
+	writeBatch := func(batch []*masterv1.ResourceAllocationRawEntry) error {
+		if includeNames {
+			if err := fetchNames(batch); err != nil {
+				return err
+			}
+		}
+		for _, entry := range batch {
+			labels, err := joinLabels(entry.Labels, csvOptions.Quoting)
+			if err != nil {
+				return err
+			}
+			fields := []string{
+				strconv.Itoa(int(entry.ExperimentId)), entry.Kind, entry.Username, labels,
+				strconv.Itoa(int(entry.Slots)), formatTimestamp(entry.StartTime),
+				formatTimestamp(entry.EndTime), fmt.Sprintf("%f", entry.Seconds),
+			}
+			if includeNames {
+				// Deleted experiments and non-experiment entries have no names; leave them empty.
+				n := names[entry.ExperimentId]
+				fields = append(fields, n.Name, n.ProjectName, n.WorkspaceName)
+			}
+			if includeSlotSeconds {
+				slotSeconds := float64(entry.Slots) * float64(entry.Seconds)
+				fields = append(fields,
+					fmt.Sprintf("%f", slotSeconds), fmt.Sprintf("%f", slotSeconds/3600))
+			}
+			if err := rowWriter.Write(fields); err != nil {
+				return err
+			}
+		}
+		rowWriter.Flush()
+		c.Response().Flush()
+		return nil
+	}
+
+	batch := make([]*masterv1.ResourceAllocationRawEntry, 0, allocationExportFlushRows)
+	for rows.Next() {
+		entry := &masterv1.ResourceAllocationRawEntry{}
+		if err := db.ScanProto(rows, entry); err != nil {
+			return err
 		}
-		fields := []string{
-			strconv.Itoa(int(entry.ExperimentId)), entry.Kind, entry.Username, strings.Join(labels, ","),
-			strconv.Itoa(int(entry.Slots)), formatTimestamp(entry.StartTime), formatTimestamp(entry.EndTime),
-			fmt.Sprintf("%f", entry.Seconds),
+		if batch = append(batch, entry); len(batch) == allocationExportFlushRows {
+			if err := writeBatch(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
 		}
-		if err := csvWriter.Write(fields); err != nil {
+	}
+	switch err := rows.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		if err := m.exportTimedOut(c); err != nil {
 			return err
 		}
+	case err != nil:
+		return errors.Wrap(err, "error fetching allocation data")
+	}
+	if err := writeBatch(batch); err != nil {
+		return err
+	}
+	return rowWriter.Close()
+}
+
+// joinLabels joins labels into a single CSV field. By default, backslashes and commas within labels
+// are escaped with a backslash; with CSVQuotingRFC4180, the labels are written as a nested CSV
+// record so that standard CSV readers can split them.
+func joinLabels(labels []string, quoting string) (string, error) {
+	if quoting != api.CSVQuotingRFC4180 {
+		escaped := make([]string, 0, len(labels))
+		for _, label := range labels {
+			escaped = append(escaped, labelEscaper.Replace(label))
+		}
+		return strings.Join(escaped, ","), nil
+	}
+	if len(labels) == 0 {
+		return "", nil
+	}
+	var joined strings.Builder
+	labelWriter := csv.NewWriter(&joined)
+	if err := labelWriter.Write(labels); err != nil {
+		return "", err
+	}
+	labelWriter.Flush()
+	if err := labelWriter.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(joined.String(), "\n"), nil
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", ",", "\\,")
+
+// getResourceAllocationByLabel returns, per experiment label, the slot-seconds consumed in each
+// bucket of the requested period.
+func (m *Master) getResourceAllocationByLabel(c echo.Context) (interface{}, error) {
+	args := struct {
+		Start  string  `query:"timestamp_after"`
+		End    string  `query:"timestamp_before"`
+		Bucket string  `query:"bucket"`
+		Labels *string `query:"labels"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+
+	start, end, err := m.parseAllocationTimeRange(args.Start, args.End)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := time.ParseDuration(args.Bucket)
+	if err != nil || bucket <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "bucket must be a positive duration")
+	}
+	if buckets := end.Sub(start) / bucket; buckets > maxLabelAllocationBuckets {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+			"%d buckets requested, at most %d are allowed", buckets, maxLabelAllocationBuckets,
+		))
+	}
+
+	filter := map[string]bool{}
+	if args.Labels != nil {
+		for _, label := range strings.Split(*args.Labels, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				filter[label] = true
+			}
+		}
+	}
+
+	resp := &apiv1.ResourceAllocationRawResponse{}
+	if err := m.db.QueryProto(
+		"get_raw_allocation", &resp.ResourceEntries, start.UTC(), end.UTC(),
+	); err != nil {
+		return nil, errors.Wrap(err, "error fetching allocation data")
+	}
+	return allocationByLabel(
+		resp.ResourceEntries, start.UTC(), end.UTC(), bucket, filter,
+		m.config.ResourceAllocation.MaxLabelSeries,
+	), nil
+}
+
+// validateAllocationRange checks the time range of a raw allocation request, for the CSV and gRPC
+// endpoints alike. Besides the order of the times, it rejects end times too far past the current
+// time, which usually indicate a skewed client clock rather than an intentional query.
+func (m *Master) validateAllocationRange(start, end time.Time) error {
+	if start.After(end) {
+		return allocationRangeError(
+			errCodeInvalidTimeRange, "start time cannot be after end time", nil)
+	}
+	tolerance := time.Duration(m.config.ResourceAllocation.MaxClockSkew)
+	if tolerance <= 0 {
+		return nil
+	}
+	if now := time.Now().UTC(); end.After(now.Add(tolerance)) {
+		return api.NewCodedError(http.StatusBadRequest, errCodeInvalidEndTime,
+			"end time %s is more than %s past the current master time %s; check the client clock",
+			end.Format(time.RFC3339), tolerance, now.Format(time.RFC3339),
+		)
 	}
-	csvWriter.Flush()
 	return nil
 }
 
+// Codes of the errors returned by the allocation endpoints for invalid request periods.
+const (
+	errCodeInvalidStartTime = "INVALID_START_TIME"
+	errCodeInvalidEndTime   = "INVALID_END_TIME"
+	errCodeInvalidTimeRange = "INVALID_TIME_RANGE"
+	errCodeMissingPeriod    = "MISSING_AGGREGATION_PERIOD"
+	errCodeInvalidTimeZone  = "INVALID_TIME_ZONE"
+)
+
+// parseTimeZone parses the IANA name of a time zone, defaulting to UTC. The master's local zone
+// is not allowed, since the database can't resolve it.
+func parseTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err == nil && loc == time.Local {
+		err = errors.New("not an IANA time zone name")
+	}
+	if err != nil {
+		return nil, api.NewCodedError(
+			http.StatusBadRequest, errCodeInvalidTimeZone, "invalid tz %q", name,
+		).WithCause(err)
+	}
+	return loc, nil
+}
+
+// allocationRangeError returns the 400 error for an invalid period of an allocation request.
+func allocationRangeError(code, msg string, cause error) error {
+	return api.NewCodedError(http.StatusBadRequest, code, msg).WithCause(cause)
+}
+
+// parseAllocationTimeRange parses and validates the timestamp_after and timestamp_before arguments
+// of the allocation endpoints.
+func (m *Master) parseAllocationTimeRange(
+	startArg, endArg string,
+) (start, end time.Time, err error) {
+	if start, err = time.Parse("2006-01-02T15:04:05Z", startArg); err != nil {
+		return start, end, allocationRangeError(errCodeInvalidStartTime, "invalid start time", err)
+	}
+	if end, err = time.Parse("2006-01-02T15:04:05Z", endArg); err != nil {
+		return start, end, allocationRangeError(errCodeInvalidEndTime, "invalid end time", err)
+	}
+	return start, end, m.validateAllocationRange(start, end)
+}
+
+// fetchAggregatedResourceAllocation aggregates allocation over the requested dates. Monthly
+// aggregation normally covers whole months; with clamp set, the dates are instead taken as exact
+// days (YYYY-MM-DD) and the first and last months only total the days within the range. Periods
+// start at midnight in loc.
 func (m *Master) fetchAggregatedResourceAllocation(
-	req *apiv1.ResourceAllocationAggregatedRequest,
+	ctx context.Context,
+	req *apiv1.ResourceAllocationAggregatedRequest, clamp bool, loc *time.Location,
 ) (*apiv1.ResourceAllocationAggregatedResponse, error) {
-	resp := &apiv1.ResourceAllocationAggregatedResponse{}
-
+	var (
+		query string
+		// unit is the date_trunc field of the period, and format the to_char pattern of its start.
+		unit, format     string
+		start, end       time.Time
+		startErr, endErr error
+	)
 	switch req.Period {
 	case masterv1.ResourceAllocationAggregationPeriod_RESOURCE_ALLOCATION_AGGREGATION_PERIOD_DAILY:
-		start, err := time.Parse("2006-01-02", req.StartDate)
-		if err != nil {
-			return nil, errors.Wrap(err, "invalid start date")
-		}
-		end, err := time.Parse("2006-01-02", req.EndDate)
-		if err != nil {
-			return nil, errors.Wrap(err, "invalid end date")
+		query, unit, format = "get_aggregated_allocation", "day", "YYYY-MM-DD"
+		start, startErr = time.ParseInLocation("2006-01-02", req.StartDate, loc)
+		end, endErr = time.ParseInLocation("2006-01-02", req.EndDate, loc)
+
+	case masterv1.ResourceAllocationAggregationPeriod_RESOURCE_ALLOCATION_AGGREGATION_PERIOD_MONTHLY:
+		query, unit, format = "get_monthly_aggregated_allocation", "month", "YYYY-MM"
+		layout := "2006-01"
+		if clamp {
+			layout = "2006-01-02"
 		}
-		if start.After(end) {
-			return nil, errors.New("start date cannot be after end date")
+		start, startErr = time.ParseInLocation(layout, req.StartDate, loc)
+		end, endErr = time.ParseInLocation(layout, req.EndDate, loc)
+		if !clamp {
+			end = end.AddDate(0, 1, -1)
 		}
 
-		if err := m.db.QueryProto(
-			"get_aggregated_allocation", &resp.ResourceEntries, start.UTC(), end.UTC(),
-		); err != nil {
-			return nil, errors.Wrap(err, "error fetching aggregated allocation data")
-		}
+	case masterv1.ResourceAllocationAggregationPeriod_RESOURCE_ALLOCATION_AGGREGATION_PERIOD_WEEKLY:
+		query, unit, format = "get_weekly_aggregated_allocation", "week", `IYYY-"W"IW`
+		start, startErr = parseISOWeek(req.StartDate)
+		end, endErr = parseISOWeek(req.EndDate)
+		start, end = inLocation(start, loc), inLocation(end, loc).AddDate(0, 0, 6)
 
-		return resp, nil
+	case masterv1.ResourceAllocationAggregationPeriod_RESOURCE_ALLOCATION_AGGREGATION_PERIOD_QUARTERLY:
+		query, unit, format = "get_quarterly_aggregated_allocation", "quarter", `YYYY-"Q"Q`
+		start, startErr = parseQuarter(req.StartDate)
+		end, endErr = parseQuarter(req.EndDate)
+		start, end = inLocation(start, loc), inLocation(end, loc).AddDate(0, 3, -1)
 
-	case masterv1.ResourceAllocationAggregationPeriod_RESOURCE_ALLOCATION_AGGREGATION_PERIOD_MONTHLY:
-		start, err := time.Parse("2006-01", req.StartDate)
-		if err != nil {
-			return nil, errors.Wrap(err, "invalid start date")
-		}
-		end, err := time.Parse("2006-01", req.EndDate)
-		if err != nil {
-			return nil, errors.Wrap(err, "invalid end date")
+	default:
+		return nil, api.NewCodedError(
+			http.StatusBadRequest, errCodeMissingPeriod, "no aggregation period specified")
+	}
+	if startErr != nil {
+		return nil, allocationRangeError(errCodeInvalidStartTime, "invalid start date", startErr)
+	}
+	if endErr != nil {
+		return nil, allocationRangeError(errCodeInvalidEndTime, "invalid end date", endErr)
+	}
+	if start.After(end) {
+		return nil, allocationRangeError(
+			errCodeInvalidTimeRange, "start date cannot be after end date", nil)
+	}
+
+	resp := &apiv1.ResourceAllocationAggregatedResponse{
+		ResourceEntries: []*masterv1.ResourceAllocationAggregatedEntry{},
+	}
+	args := []interface{}{start.UTC(), end.UTC()}
+	if loc != time.UTC {
+		// The daily aggregates that the other queries sum are of UTC days, so periods in other
+		// time zones are aggregated from the allocations themselves.
+		query = "get_zoned_aggregated_allocation"
+		args = []interface{}{
+			start.UTC(), end.AddDate(0, 0, 1).UTC(), unit, loc.String(), format, req.Period.String(),
 		}
-		end = end.AddDate(0, 1, -1)
-		if start.After(end) {
-			return nil, errors.New("start date cannot be after end date")
+	}
+	rows, err := m.db.QueryProtoRows(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching aggregated allocation data")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry := &masterv1.ResourceAllocationAggregatedEntry{}
+		if err := db.ScanProto(rows, entry); err != nil {
+			return nil, err
 		}
+		resp.ResourceEntries = append(resp.ResourceEntries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "error fetching aggregated allocation data")
+	}
+	return resp, nil
+}
 
-		if err := m.db.QueryProto(
-			"get_monthly_aggregated_allocation", &resp.ResourceEntries, start.UTC(), end.UTC(),
-		); err != nil {
-			return nil, errors.Wrap(err, "error fetching aggregated allocation data")
-		}
+// inLocation returns midnight in loc of the date of t.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
 
-		return resp, nil
+// parseISOWeek parses an ISO 8601 week (YYYY-Www) and returns the Monday it starts on.
+func parseISOWeek(s string) (time.Time, error) {
+	var year, week int
+	if _, err := fmt.Sscanf(s, "%4d-W%2d", &year, &week); err != nil || len(s) != len("2006-W01") {
+		return time.Time{}, errors.Errorf("%q is not a week in YYYY-Www format", s)
+	}
+	// January 4th is always in the first ISO week of its year.
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7+(week-1)*7)
+	if y, w := monday.ISOWeek(); y != year || w != week {
+		return time.Time{}, errors.Errorf("%d has no week %d", year, week)
+	}
+	return monday, nil
+}
 
-	default:
-		return nil, errors.New("no aggregation period specified")
+// parseQuarter parses a calendar quarter (YYYY-Qn) and returns the first day of the quarter.
+func parseQuarter(s string) (time.Time, error) {
+	var year, quarter int
+	if _, err := fmt.Sscanf(s, "%4d-Q%1d", &year, &quarter); err != nil || len(s) != len("2006-Q1") {
+		return time.Time{}, errors.Errorf("%q is not a quarter in YYYY-Qn format", s)
 	}
+	if quarter < 1 || quarter > 4 {
+		return time.Time{}, errors.Errorf("quarter must be between 1 and 4, got %d", quarter)
+	}
+	return time.Date(year, time.Month(3*(quarter-1)+1), 1, 0, 0, 0, 0, time.UTC), nil
 }
 
 // TaskMetadata captures the historic allocation information for a given task.
 type TaskMetadata struct {
-	bun.BaseModel    `bun:"table:tasks"`
-	TaskID           model.TaskID   `bun:"task_id"`
-	TaskType         model.TaskType `bun:"task_type"`
-	Username         string
-	WorkspaceName    string
-	ExperimentID     int
-	Slots            int
-	StartTime        time.Time
-	EndTime          time.Time
-	TrainingTime     float64
-	ValidationTime   float64
-	ImagepullingTime float64
+	bun.BaseModel     `bun:"table:tasks"`
+	TaskID            model.TaskID   `bun:"task_id"`
+	TaskType          model.TaskType `bun:"task_type"`
+	Username          string
+	WorkspaceName     string
+	ProjectName       string
+	ExperimentID      int
+	Slots             int
+	SlotType          string
+	StartTime         time.Time
+	EndTime           time.Time
+	TrainingTime      float64
+	ValidationTime    float64
+	CheckpointingTime float64
+	ImagepullingTime  float64
+	// Cost is only computed when a rate card is configured.
+	Cost float64
 }
 
 //	@Summary	Get a detailed view of resource allocation at a task-level during the given time period (CSV).
 //	@Tags		Cluster
 //	@ID			get-raw-resource-task-allocation-csv
 //	@Accept		json
-//	@Produce	text/csv
-//
-// nolint:lll
-//
+//	@Produce	text/csv,json
 //	@Param		timestamp_after		query	string	true	"Start time to get allocations for (YYYY-MM-DDTHH:MM:SSZ format)"
-//
-// nolint:lll
-//
 //	@Param		timestamp_before	query	string	true	"End time to get allocations for (YYYY-MM-DDTHH:MM:SSZ format)"
+//	@Param		columns				query	string	false	"Comma-separated list of columns to include, in order (defaults to all columns)"
+//	@Param		slot_type			query	string	false	"Only include tasks whose slots were of this device type (cuda, rocm or cpu), or mixed for tasks backed by more than one"
+//	@Param		workspace			query	string	false	"Only include tasks of experiments in the workspace with this name"
+//	@Param		project				query	string	false	"Only include tasks of experiments in the project with this name in workspace, which is then required"
+//	@Param		null_value			query	string	false	"Value of the workspace_name, project_name and experiment_id of tasks without an experiment (defaults to empty, or 0 for experiment_id)"
+//	@Param		delimiter			query	string	false	"Field delimiter, a single character (default ,)"
+//	@Param		limit				query	int		false	"Maximum number of tasks to return"
+//	@Param		after_start_time	query	string	false	"Only return tasks after the one with this start_time and after_task_id, such as the last task of the previous page"
+//	@Param		after_task_id		query	string	false	"Only return tasks after the one with this task_id and after_start_time"
+//	@Success	200					{}		string	"A CSV file containing the fields task_id, task_type, username, workspace_name, experiment_id, slots, start_time, end_time, training_time, validation_time, checkpointing_time, imagepulling_time, slot_type, project_name and, if a rate card is configured, cost"
+//	@Router		/allocations/tasks-raw [get]
 //
 // nolint:lll
-//
-//	@Success	200					{}		string	"A CSV file containing the fields task_id, task_type, username, workspace_name, experiment_id, slots, start_time, end_time, training_time, validation_time, checkpointing_time, imagepulling_time"
-//	@Router		/allocations/tasks-raw [get]
 func (m *Master) getRawResourceAllocationTasks(c echo.Context) error {
-	// Get start and end times from context
-	args := struct {
-		Start string `query:"timestamp_after"`
-		End   string `query:"timestamp_before"`
-	}{}
-	if err := api.BindArgs(&args, c); err != nil {
-		return err
-	}
-
-	// Parse Start and End Times
-	start, err := time.Parse("2006-01-02T15:04:05Z", args.Start)
+	args, err := m.parseTaskAllocationArgs(c)
 	if err != nil {
-		return errors.Wrap(err, "invalid start time")
+		return err
 	}
-	end, err := time.Parse("2006-01-02T15:04:05Z", args.End)
+	start, end, columns := args.start, args.end, args.columns
+	csvOptions, err := api.ParseCSVOptions(c)
 	if err != nil {
-		return errors.Wrap(err, "invalid end time")
-	}
-	if start.After(end) {
-		return errors.New("start time cannot be after end time")
+		return err
 	}
+
 	timeRangeCTE := db.Bun().NewSelect().
 		ColumnExpr("tstzrange(? :: timestamptz, ? :: timestamptz) AS period", start, end)
 
@@ -416,12 +1266,22 @@ func (m *Master) getRawResourceAllocationTasks(c echo.Context) error {
 		Join("INNER JOIN task_stats ts ON a.allocation_id = ts.allocation_id").
 		Where("ts.event_type = 'IMAGEPULL'")
 
+	// Build Query for identifing all TaskID's associated with checkpointing
+	checkpointQuery := db.Bun().NewSelect().
+		ColumnExpr("a.task_id").
+		ColumnExpr("'checkpoint' as kind").
+		ColumnExpr("ts.end_time").
+		TableExpr("allocations a").
+		Join("INNER JOIN task_stats ts ON a.allocation_id = ts.allocation_id").
+		Where("ts.event_type = 'CHECKPOINT'")
+
 	// Union each kind query into a single query
 	metricReports := trialStartQuery.
 		UnionAll(allocationStartQuery).
 		UnionAll(trainingQuery).
 		UnionAll(validationQuery).
-		UnionAll(imagePullQuery)
+		UnionAll(imagePullQuery).
+		UnionAll(checkpointQuery)
 
 	// Identify start & end times for each task according to the workload kind
 	// ** Implicit assumption that one workload started when the previous ended
@@ -461,31 +1321,26 @@ func (m *Master) getRawResourceAllocationTasks(c echo.Context) error {
 		Join("INNER JOIN jobs j ON t.job_id = j.job_id").
 		Join("INNER JOIN users u ON j.owner_id = u.id")
 
-	// Get the number of slots request for a given task
-	taskSlotsCTE := db.Bun().NewSelect().
-		ColumnExpr("t.task_id").
-		ColumnExpr("(array_agg(a.slots) FILTER (WHERE a.slots IS NOT NULL))[1] as slots").
-		TableExpr("tasks t").
-		Join("INNER JOIN allocations a ON t.task_id = a.task_id").
-		Group("t.task_id")
-
 	// Pull metadata row-by-row for all Task ID's and aggregate workload times based on workload kinds for all tasks
 	taskMetaData := TaskMetadata{}
-	rows, err := db.Bun().NewSelect().Model(&taskMetaData).
+	query := db.Bun().NewSelect().Model(&taskMetaData).
 		ColumnExpr("task_metadata.task_id AS task_id").
 		ColumnExpr("task_metadata.task_type AS task_type").
 		ColumnExpr("task_owners.username AS username").
 		ColumnExpr("workspaces.name AS workspace_name").
+		ColumnExpr("COALESCE(projects.name, '') AS project_name").
 		ColumnExpr("experiments.id as experiment_id").
 		ColumnExpr("task_slots.slots as slots").
+		ColumnExpr("COALESCE(task_slots.slot_type, '') as slot_type").
 		ColumnExpr("task_metadata.start_time AS start_time").
 		ColumnExpr("task_metadata.end_time AS end_time").
 		ColumnExpr("SUM(workloads.seconds) FILTER (WHERE workloads.kind = 'training') as training_time").
 		ColumnExpr("SUM(workloads.seconds) FILTER (WHERE workloads.kind = 'validation') as validation_time").
+		ColumnExpr("SUM(workloads.seconds) FILTER (WHERE workloads.kind = 'checkpoint') as checkpointing_time").
 		ColumnExpr("SUM(workloads.seconds) FILTER (WHERE workloads.kind = 'imagepull') as imagepulling_time").
 		With("const", timeRangeCTE).
 		With("workloads", workloads).
-		With("task_slots", taskSlotsCTE).
+		With("task_slots", taskSlotsQuery()).
 		With("task_owners", taskOwnersCTE).
 		Join("LEFT JOIN task_slots ON task_slots.task_id = task_metadata.task_id").
 		Join("LEFT JOIN task_owners ON task_owners.task_id = task_metadata.task_id").
@@ -500,89 +1355,468 @@ func (m *Master) getRawResourceAllocationTasks(c echo.Context) error {
 			"task_metadata.task_type",
 			"task_owners.username",
 			"workspaces.name",
+			"projects.name",
 			"experiments.id",
 			"task_slots.slots",
+			"task_slots.slot_type",
 			"task_metadata.start_time",
 			"task_metadata.end_time").
-		Order("start_time").
-		Rows(c.Request().Context())
-	if err != nil && rows.Err() != nil {
+		Order("start_time", "task_id")
+	query = whereTaskSlotType(query, args.slotType)
+	if args.after != nil {
+		query = query.Where("(task_metadata.start_time, task_metadata.task_id) > (?, ?)",
+			args.after.startTime, args.after.taskID)
+	}
+	if args.limit > 0 {
+		query = query.Limit(args.limit)
+	}
+	// Tasks that don't belong to an experiment have no workspace or project and are left out.
+	if args.workspace != "" {
+		query = query.Where("workspaces.name = ?", args.workspace)
+	}
+	if args.project != "" {
+		query = query.Where("projects.name = ?", args.project)
+	}
+	if rateCard := m.config.ResourceAllocation.RateCard; rateCard != nil {
+		query = query.
+			With("task_costs", taskCostsQuery(rateCard)).
+			ColumnExpr("COALESCE(task_costs.cost, 0) AS cost").
+			Join("LEFT JOIN task_costs ON task_costs.task_id = task_metadata.task_id").
+			Group("task_costs.cost")
+	}
+	ctx, cancel := m.exportContext(c)
+	defer cancel()
+	rows, err := query.Rows(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return echo.NewHTTPError(http.StatusGatewayTimeout, "export exceeded max_export_duration")
+	case err != nil:
 		return err
 	}
 	defer rows.Close()
 
-	c.Response().Header().Set("Content-Type", "text/csv")
-	header := []string{
-		"task_id",
-		"task_type",
-		"username",
-		"workspace_name",
-		"experiment_id",
-		"slots",
-		"start_time",
-		"end_time",
-		"training_time",
-		"validation_time",
-		"imagepulling_time",
-	}
-
-	formatTimestamp := func(t time.Time) string {
-		if t.IsZero() {
-			return ""
-		}
-		return t.Format(time.RFC3339Nano)
-	}
-
-	formatDuration := func(duration float64) string {
-		if duration == 0 {
-			return "0.0"
-		}
-		return fmt.Sprintf("%f", duration)
+	c.Response().Header().Set("Trailer", exportTruncatedTrailer)
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.name)
 	}
 
-	csvWriter := csv.NewWriter(c.Response())
-	if err = csvWriter.Write(header); err != nil {
+	rowWriter := auditExportRows(c, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339),
+		api.NewRowWriter(c, csvOptions))
+	if err = rowWriter.Write(header); err != nil {
 		return err
 	}
 
-	// Write each entry to the output CSV
-	for rows.Next() {
+	// Write each entry to the output
+	for written := 1; rows.Next(); written++ {
 		taskMetadata := new(TaskMetadata)
-		if err := db.Bun().ScanRow(c.Request().Context(), rows, taskMetadata); err != nil {
+		if err := db.Bun().ScanRow(ctx, rows, taskMetadata); err != nil {
 			return err
 		}
-		fields := []string{
-			taskMetadata.TaskID.String(),
-			string(taskMetadata.TaskType),
-			taskMetadata.Username,
-			taskMetadata.WorkspaceName,
-			strconv.Itoa(taskMetadata.ExperimentID),
-			strconv.Itoa(taskMetadata.Slots),
-			formatTimestamp(taskMetadata.StartTime),
-			formatTimestamp(taskMetadata.EndTime),
-			formatDuration(taskMetadata.TrainingTime),
-			formatDuration(taskMetadata.ValidationTime),
-			formatDuration(taskMetadata.ImagepullingTime),
-		}
-		if err := csvWriter.Write(fields); err != nil {
+		fields := make([]string, 0, len(columns))
+		for _, column := range columns {
+			if args.nullValue != nil && taskMetadata.ExperimentID == 0 &&
+				experimentTaskAllocationColumns[column.name] {
+				fields = append(fields, *args.nullValue)
+				continue
+			}
+			fields = append(fields, column.value(taskMetadata))
+		}
+		if err := rowWriter.Write(fields); err != nil {
+			return err
+		}
+		if written%allocationExportFlushRows == 0 {
+			// Flush through any compression too, so that clients see long exports progress.
+			rowWriter.Flush()
+			c.Response().Flush()
+		}
+	}
+	switch err := rows.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		if err := m.exportTimedOut(c); err != nil {
 			return err
 		}
+	case err != nil:
+		return err
+	}
+	return rowWriter.Close()
+}
+
+// exportContext returns the context of an allocation export's database queries, which bounds how
+// long the export may hold a database connection by resource_allocation.max_export_duration.
+func (m *Master) exportContext(c echo.Context) (context.Context, context.CancelFunc) {
+	ctx := c.Request().Context()
+	if d := m.config.ResourceAllocation.MaxExportDuration; d > 0 {
+		return context.WithTimeout(ctx, time.Duration(d))
+	}
+	return context.WithCancel(ctx)
+}
+
+// exportTimedOut handles an allocation export whose rows ran past max_export_duration. It fails
+// with a 504 if export_timeout_behavior is error and nothing was sent yet. Otherwise part of the
+// export may already be sent, so it notes in a trailer that the export was cut short and returns
+// nil for the caller to end the export.
+func (m *Master) exportTimedOut(c echo.Context) error {
+	if m.config.ResourceAllocation.ExportTimeoutBehavior == config.ExportTimeoutError &&
+		!c.Response().Committed {
+		return echo.NewHTTPError(http.StatusGatewayTimeout, "export exceeded max_export_duration")
 	}
-	csvWriter.Flush()
+	c.Response().Header().Set(exportTruncatedTrailer, "true")
 	return nil
 }
 
+// allocationExportFlushRows is how many rows of an allocation export are written between flushes of
+// the response.
+const allocationExportFlushRows = 1000
+
+// exportTruncatedTrailer is the HTTP trailer set on allocation exports that were cut short by
+// resource_allocation.max_export_duration.
+const exportTruncatedTrailer = "X-Determined-Export-Truncated"
+
+// taskAllocationArgs are the parsed arguments shared by the task-level allocation endpoints.
+type taskAllocationArgs struct {
+	start   time.Time
+	end     time.Time
+	columns []taskAllocationColumn
+	// slotType restricts the tasks to those whose slots were backed by this type of device.
+	slotType string
+	// workspace restricts the tasks to those of experiments in the workspace with this name.
+	workspace string
+	// project restricts the tasks to those of experiments in the project with this name in
+	// workspace; project names are only unique within a workspace.
+	project string
+	// nullValue, if set, replaces the experiment columns of tasks that have no experiment.
+	nullValue *string
+	// limit, if positive, is the most tasks to return.
+	limit int
+	// after, if set, restricts the tasks to those ordered after it.
+	after *taskAllocationCursor
+}
+
+// taskAllocationCursor is a position in the task-level allocation export, which is ordered by
+// start time and then task ID. Paging by it rather than by an offset keeps pages stable and lets
+// the database skip the earlier tasks.
+type taskAllocationCursor struct {
+	startTime time.Time
+	taskID    model.TaskID
+}
+
+func (m *Master) parseTaskAllocationArgs(c echo.Context) (*taskAllocationArgs, error) {
+	// Get start and end times from context
+	args := struct {
+		Start     string  `query:"timestamp_after"`
+		End       string  `query:"timestamp_before"`
+		Columns   *string `query:"columns"`
+		SlotType  *string `query:"slot_type"`
+		Workspace string  `query:"workspace"`
+		Project   string  `query:"project"`
+		NullValue *string `query:"null_value"`
+		Limit     *int    `query:"limit"`
+		// AfterStartTime and AfterTaskID are the start_time and task_id of the last task of the
+		// previous page.
+		AfterStartTime *string `query:"after_start_time"`
+		AfterTaskID    *string `query:"after_task_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	slotType, err := parseTaskSlotType(args.SlotType)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if args.Project != "" && args.Workspace == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			"project requires workspace, since projects in different workspaces may share a name")
+	}
+
+	columns := availableTaskAllocationColumns(m.config.ResourceAllocation.RateCard)
+	if args.Columns != nil {
+		var err error
+		if columns, err = selectTaskAllocationColumns(*args.Columns, columns); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	start, end, err := m.parseAllocationTimeRange(args.Start, args.End)
+	if err != nil {
+		return nil, err
+	}
+	var limit int
+	if args.Limit != nil {
+		if limit = *args.Limit; limit < 1 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "limit must be positive")
+		}
+	}
+	after, err := parseTaskAllocationCursor(args.AfterStartTime, args.AfterTaskID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return &taskAllocationArgs{
+		start: start, end: end, columns: columns, slotType: slotType,
+		workspace: args.Workspace, project: args.Project,
+		nullValue: args.NullValue, limit: limit, after: after,
+	}, nil
+}
+
+// parseTaskSlotType parses the slot_type argument, which is either the type of device backing the
+// slots of the tasks to select or sproto.SlotTypeMixed for tasks backed by more than one.
+func parseTaskSlotType(slotType *string) (string, error) {
+	if slotType == nil {
+		return "", nil
+	}
+	switch t := *slotType; t {
+	case string(device.CPU), string(device.CUDA), string(device.ROCM), sproto.SlotTypeMixed:
+		return t, nil
+	default:
+		return "", errors.Errorf("invalid slot_type %q, must be one of: %s, %s, %s, %s", t,
+			device.CUDA, device.ROCM, device.CPU, sproto.SlotTypeMixed)
+	}
+}
+
+// taskSlotsQuery selects the number of slots requested for each task and the type of device they
+// were backed by, sproto.SlotTypeMixed if its allocations were backed by different types.
+func taskSlotsQuery() *bun.SelectQuery {
+	return db.Bun().NewSelect().
+		ColumnExpr("t.task_id").
+		ColumnExpr("(array_agg(a.slots) FILTER (WHERE a.slots IS NOT NULL))[1] as slots").
+		ColumnExpr(
+			"CASE WHEN count(DISTINCT a.slot_type) > 1 THEN ? ELSE max(a.slot_type) END AS slot_type",
+			sproto.SlotTypeMixed,
+		).
+		TableExpr("tasks t").
+		Join("INNER JOIN allocations a ON t.task_id = a.task_id").
+		Group("t.task_id")
+}
+
+// whereTaskSlotType restricts a query joined with taskSlotsQuery as task_slots to the tasks whose
+// slots were backed by slotType, unless it is empty.
+func whereTaskSlotType(query *bun.SelectQuery, slotType string) *bun.SelectQuery {
+	if slotType == "" {
+		return query
+	}
+	return query.Where("task_slots.slot_type = ?", slotType)
+}
+
+// parseTaskAllocationCursor parses the after_start_time and after_task_id arguments, which must be
+// given together.
+func parseTaskAllocationCursor(startTime, taskID *string) (*taskAllocationCursor, error) {
+	switch {
+	case startTime == nil && taskID == nil:
+		return nil, nil
+	case startTime == nil || taskID == nil:
+		return nil, errors.New("after_start_time and after_task_id must be given together")
+	}
+	t, err := time.Parse(time.RFC3339Nano, *startTime)
+	if err != nil {
+		return nil, errors.Errorf("invalid after_start_time %q", *startTime)
+	}
+	return &taskAllocationCursor{startTime: t, taskID: model.TaskID(*taskID)}, nil
+}
+
+// taskAllocationEstimate is the approximate size of a task-level allocation CSV export.
+type taskAllocationEstimate struct {
+	Rows  int `json:"rows"`
+	Bytes int `json:"bytes"`
+}
+
+// getRawResourceAllocationTasksEstimate estimates the size of the task-level allocation CSV for the
+// same arguments, by counting the tasks in the period rather than computing their workloads.
+func (m *Master) getRawResourceAllocationTasksEstimate(c echo.Context) (interface{}, error) {
+	args, err := m.parseTaskAllocationArgs(c)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Bun().NewSelect().
+		TableExpr("tasks").
+		Where("tstzrange(tasks.start_time, tasks.end_time) && "+
+			"tstzrange(? :: timestamptz, ? :: timestamptz)", args.start, args.end)
+	if args.slotType != "" {
+		// Filter on the same aggregated slot type as the export, so mixed tasks are counted alike.
+		query = whereTaskSlotType(query.
+			With("task_slots", taskSlotsQuery()).
+			Join("JOIN task_slots ON task_slots.task_id = tasks.task_id"), args.slotType)
+	}
+	if args.workspace != "" {
+		// Project is only set along with workspace.
+		query = query.Where(`EXISTS (
+SELECT 1 FROM experiments e
+JOIN projects p ON e.project_id = p.id
+JOIN workspaces w ON p.workspace_id = w.id
+WHERE e.job_id = tasks.job_id AND w.name = ? AND (? = '' OR p.name = ?))`,
+			args.workspace, args.project, args.project)
+	}
+	if args.after != nil {
+		query = query.Where("(tasks.start_time, tasks.task_id) > (?, ?)",
+			args.after.startTime, args.after.taskID)
+	}
+	rows, err := query.Count(c.Request().Context())
+	if err != nil {
+		return nil, errors.Wrap(err, "error counting tasks")
+	}
+	if args.limit > 0 && rows > args.limit {
+		rows = args.limit
+	}
+
+	headerBytes, rowBytes := 0, 0
+	for _, column := range args.columns {
+		// Each field is followed by a comma, or by the newline ending the record.
+		headerBytes += len(column.name) + 1
+		rowBytes += column.approxBytes + 1
+	}
+	return taskAllocationEstimate{Rows: rows, Bytes: headerBytes + rows*rowBytes}, nil
+}
+
+// taskAllocationColumn is a column of the task-level allocation CSV.
+type taskAllocationColumn struct {
+	name string
+	// approxBytes is the typical width of a value in this column, used to estimate export sizes.
+	approxBytes int
+	value       func(*TaskMetadata) string
+}
+
+func formatTaskTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func formatTaskDuration(duration float64) string {
+	if duration == 0 {
+		return "0.0"
+	}
+	return fmt.Sprintf("%f", duration)
+}
+
+// taskAllocationColumns lists every column of the task-level allocation CSV in the default order.
+var taskAllocationColumns = []taskAllocationColumn{
+	{"task_id", 40, func(t *TaskMetadata) string { return t.TaskID.String() }},
+	{"task_type", 10, func(t *TaskMetadata) string { return string(t.TaskType) }},
+	{"username", 12, func(t *TaskMetadata) string { return t.Username }},
+	{"workspace_name", 16, func(t *TaskMetadata) string { return t.WorkspaceName }},
+	{"experiment_id", 5, func(t *TaskMetadata) string { return strconv.Itoa(t.ExperimentID) }},
+	{"slots", 1, func(t *TaskMetadata) string { return strconv.Itoa(t.Slots) }},
+	{"start_time", 30, func(t *TaskMetadata) string { return formatTaskTimestamp(t.StartTime) }},
+	{"end_time", 30, func(t *TaskMetadata) string { return formatTaskTimestamp(t.EndTime) }},
+	{"training_time", 12, func(t *TaskMetadata) string {
+		return formatTaskDuration(t.TrainingTime)
+	}},
+	{"validation_time", 12, func(t *TaskMetadata) string {
+		return formatTaskDuration(t.ValidationTime)
+	}},
+	{"checkpointing_time", 12, func(t *TaskMetadata) string {
+		return formatTaskDuration(t.CheckpointingTime)
+	}},
+	{"imagepulling_time", 12, func(t *TaskMetadata) string {
+		return formatTaskDuration(t.ImagepullingTime)
+	}},
+	{"slot_type", 4, func(t *TaskMetadata) string { return t.SlotType }},
+	{"project_name", 16, func(t *TaskMetadata) string { return t.ProjectName }},
+}
+
+// experimentTaskAllocationColumns are the columns that describe a task's experiment, which tasks
+// such as notebooks and commands do not have.
+var experimentTaskAllocationColumns = map[string]bool{
+	"workspace_name": true,
+	"project_name":   true,
+	"experiment_id":  true,
+}
+
+// costTaskAllocationColumn is the price of a task's allocations, which is only available when a
+// rate card is configured.
+var costTaskAllocationColumn = taskAllocationColumn{
+	"cost", 10, func(t *TaskMetadata) string { return fmt.Sprintf("%f", t.Cost) },
+}
+
+// availableTaskAllocationColumns returns every column of the task-level allocation CSV in the
+// default order, including the cost if a rate card is configured.
+func availableTaskAllocationColumns(rateCard *config.RateCardConfig) []taskAllocationColumn {
+	if rateCard == nil {
+		return taskAllocationColumns
+	}
+	columns := make([]taskAllocationColumn, 0, len(taskAllocationColumns)+1)
+	columns = append(columns, taskAllocationColumns...)
+	return append(columns, costTaskAllocationColumn)
+}
+
+// taskCostsQuery prices the slot-hours that each task's allocations spent within the requested
+// period, which the query it is used in must define as the `const` CTE.
+func taskCostsQuery(rateCard *config.RateCardConfig) *bun.SelectQuery {
+	pools := make([]string, 0, len(rateCard.ResourcePools))
+	for pool := range rateCard.ResourcePools {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+	price := "CASE a.resource_pool"
+	var priceArgs []interface{}
+	for _, pool := range pools {
+		price += " WHEN ? THEN ?::float8"
+		priceArgs = append(priceArgs, pool, rateCard.ResourcePools[pool])
+	}
+	price += " ELSE ?::float8 END"
+	priceArgs = append(priceArgs, rateCard.Default)
+
+	allocationRanges := db.Bun().NewSelect().
+		ColumnExpr("task_id").
+		ColumnExpr("slots").
+		ColumnExpr("resource_pool").
+		ColumnExpr("tstzrange(start_time, COALESCE(end_time, now())) AS range").
+		TableExpr("allocations").
+		Where("start_time IS NOT NULL")
+
+	return db.Bun().NewSelect().
+		ColumnExpr("a.task_id").
+		ColumnExpr("SUM(a.slots * extract(epoch FROM upper(const.period * a.range) - "+
+			"lower(const.period * a.range)) / 3600 * ("+price+")) AS cost", priceArgs...).
+		TableExpr("(?) AS a", allocationRanges).
+		Table("const").
+		Where("const.period && a.range").
+		Group("a.task_id")
+}
+
+// selectTaskAllocationColumns parses a comma-separated list of column names into the columns to
+// emit, in the requested order, from those available.
+func selectTaskAllocationColumns(
+	names string, available []taskAllocationColumn,
+) ([]taskAllocationColumn, error) {
+	byName := make(map[string]taskAllocationColumn, len(available))
+	var known []string
+	for _, column := range available {
+		byName[column.name] = column
+		known = append(known, column.name)
+	}
+
+	var columns []taskAllocationColumn
+	for _, name := range strings.Split(names, ",") {
+		column, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, errors.Errorf(
+				"unknown column %q, must be one of: %s", name, strings.Join(known, ", "),
+			)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
 //	@Summary	Get an aggregated view of resource allocation during the given time period (CSV).
 //	@Tags		Cluster
 //	@ID			get-aggregated-resource-allocation-csv
-//	@Produce	text/csv
-//	@Param		start_date	query	string	true	"Start time to get allocations for (YYYY-MM-DD format for daily, YYYY-MM format for monthly)"
-//	@Param		end_date	query	string	true	"End time to get allocations for (YYYY-MM-DD format for daily, YYYY-MM format for monthly)"
+//	@Produce	text/csv,json
+//	@Param		start_date	query	string	true	"Start time to get allocations for (YYYY-MM-DD format for daily, YYYY-Www for weekly, YYYY-MM for monthly, YYYY-Qn for quarterly)"
+//	@Param		end_date	query	string	true	"End time to get allocations for (YYYY-MM-DD format for daily, YYYY-Www for weekly, YYYY-MM for monthly, YYYY-Qn for quarterly)"
+//
+// nolint:lll
+//
+//	@Param		period		query	string	true	"Period to aggregate over (RESOURCE_ALLOCATION_AGGREGATION_PERIOD_DAILY, _WEEKLY, _MONTHLY or _QUARTERLY)"
 //
 // nolint:lll
 //
-//	@Param		period		query	string	true	"Period to aggregate over (RESOURCE_ALLOCATION_AGGREGATION_PERIOD_DAILY or RESOURCE_ALLOCATION_AGGREGATION_PERIOD_MONTHLY)"
-//	@Success	200			{}		string	"aggregation_type,aggregation_key,date,seconds"
+//	@Param		clamp		query	bool	false	"For monthly aggregation, take start_date and end_date as YYYY-MM-DD and only count the days within them"
+//	@Param		tz			query	string	false	"IANA time zone whose midnights start the periods (default UTC)"
+//	@Param		delimiter	query	string	false	"Field delimiter, a single character (default ,)"
+//	@Success	200			{}		string	"aggregation_type,aggregation_key,date,seconds and, if a rate card is configured, cost"
 //	@Router		/allocation/aggregated [get]
 //
 // nolint:lll
@@ -590,62 +1824,125 @@ func (m *Master) getRawResourceAllocationTasks(c echo.Context) error {
 // comment indented with tabs. https://github.com/swaggo/swag/pull/1386#issuecomment-1359242144
 func (m *Master) getAggregatedResourceAllocation(c echo.Context) error {
 	args := struct {
-		Start  string `query:"start_date"`
-		End    string `query:"end_date"`
-		Period string `query:"period"`
+		Start           string `query:"start_date"`
+		End             string `query:"end_date"`
+		Period          string `query:"period"`
+		Pivot           bool   `query:"pivot"`
+		AggregationType string `query:"aggregation_type"`
+		Clamp           bool   `query:"clamp"`
+		TZ              string `query:"tz"`
 	}{}
 	if err := api.BindArgs(&args, c); err != nil {
 		return err
 	}
+	loc, err := parseTimeZone(args.TZ)
+	if err != nil {
+		return err
+	}
+	if args.Pivot && !slices.Contains(aggregationTypes, args.AggregationType) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+			"pivot requires aggregation_type to be one of %s", strings.Join(aggregationTypes, ", "),
+		))
+	}
+	csvOptions, err := api.ParseCSVOptions(c)
+	if err != nil {
+		return err
+	}
 
-	resp, err := m.fetchAggregatedResourceAllocation(&apiv1.ResourceAllocationAggregatedRequest{
+	ctx, cancel := m.exportContext(c)
+	defer cancel()
+	resp, err := m.fetchAggregatedResourceAllocation(ctx, &apiv1.ResourceAllocationAggregatedRequest{
 		StartDate: args.Start,
 		EndDate:   args.End,
 		Period: masterv1.ResourceAllocationAggregationPeriod(
 			masterv1.ResourceAllocationAggregationPeriod_value[args.Period],
 		),
-	})
-	if err != nil {
+	}, args.Clamp, loc)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		// The aggregates are read in full before any are written, so none were sent yet.
+		return echo.NewHTTPError(http.StatusGatewayTimeout, "export exceeded max_export_duration")
+	case err != nil:
 		return err
 	}
 
-	c.Response().Header().Set("Content-Type", "text/csv")
+	rowWriter := auditExportRows(c, args.Start, args.End, api.NewRowWriter(c, csvOptions))
 
-	csvWriter := csv.NewWriter(c.Response())
+	if args.Pivot {
+		for _, row := range pivotAggregatedAllocation(resp.ResourceEntries, args.AggregationType) {
+			if err = rowWriter.Write(row); err != nil {
+				return err
+			}
+		}
+		return rowWriter.Close()
+	}
 
+	rateCard := m.config.ResourceAllocation.RateCard
 	header := []string{"aggregation_type", "aggregation_key", "date", "seconds"}
-	if err = csvWriter.Write(header); err != nil {
-		return err
+	if rateCard != nil {
+		header = append(header, "cost")
 	}
-
-	write := func(aggType, aggKey, start string, seconds float32) error {
-		return csvWriter.Write([]string{aggType, aggKey, start, fmt.Sprintf("%f", seconds)})
+	if err = rowWriter.Write(header); err != nil {
+		return err
 	}
 
 	for _, entry := range resp.ResourceEntries {
-		writeAggType := func(agg string, vals map[string]float32) error {
-			for key, seconds := range vals {
-				if err = write(agg, key, entry.PeriodStart, seconds); err != nil {
+		for _, aggType := range aggregationTypes {
+			for key, seconds := range aggregatedValues(entry, aggType) {
+				fields := []string{aggType, key, entry.PeriodStart, fmt.Sprintf("%f", seconds)}
+				if rateCard != nil {
+					// Keys that can't be priced are left without a cost.
+					cost := ""
+					if value, ok := aggregatedCost(entry, aggType, key, seconds, rateCard); ok {
+						cost = fmt.Sprintf("%f", value)
+					}
+					fields = append(fields, cost)
+				}
+				if err = rowWriter.Write(fields); err != nil {
 					return err
 				}
 			}
-			return nil
-		}
-		if err = writeAggType("experiment_label", entry.ByExperimentLabel); err != nil {
-			return err
-		}
-		if err = writeAggType("username", entry.ByUsername); err != nil {
-			return err
-		}
-		if err = writeAggType("resource_pool", entry.ByResourcePool); err != nil {
-			return err
 		}
-		if err = writeAggType("total", map[string]float32{"total": entry.Seconds}); err != nil {
-			return err
+	}
+	return rowWriter.Close()
+}
+
+// getResourceAllocationComparison compares the aggregated allocation of a base and a target period
+// (days or months, in the format the period uses), reporting the change for each aggregation key.
+func (m *Master) getResourceAllocationComparison(c echo.Context) (interface{}, error) {
+	args := struct {
+		Period string `query:"period"`
+		Base   string `query:"base"`
+		Target string `query:"target"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	period := masterv1.ResourceAllocationAggregationPeriod(
+		masterv1.ResourceAllocationAggregationPeriod_value[args.Period],
+	)
+
+	fetch := func(date string) ([]*masterv1.ResourceAllocationAggregatedEntry, error) {
+		resp, err := m.fetchAggregatedResourceAllocation(
+			c.Request().Context(), &apiv1.ResourceAllocationAggregatedRequest{
+				StartDate: date,
+				EndDate:   date,
+				Period:    period,
+			}, false, time.UTC)
+		if err != nil {
+			return nil, err
 		}
+		return resp.ResourceEntries, nil
 	}
-	csvWriter.Flush()
-	return nil
+	base, err := fetch(args.Base)
+	if err != nil {
+		return nil, err
+	}
+	target, err := fetch(args.Target)
+	if err != nil {
+		return nil, err
+	}
+	return compareAggregatedAllocation(base, target), nil
 }
 
 func (m *Master) getSystemdListener() (net.Listener, error) {
@@ -725,9 +2022,21 @@ func (m *Master) startServers(ctx context.Context, cert *tls.Certificate) error
 	}
 	defer closeWithErrCheck("base", baseListener)
 
+	// gRPC is served on its own port if one is configured, and otherwise multiplexed with HTTP.
+	var grpcBaseListener net.Listener
+	separateGRPC := m.config.GRPC.Port != 0 && m.config.GRPC.Port != m.config.Port
+	if separateGRPC {
+		grpcBaseListener, err = net.Listen("tcp", fmt.Sprintf(":%d", m.config.GRPC.Port))
+		if err != nil {
+			return err
+		}
+		defer closeWithErrCheck("grpc", grpcBaseListener)
+	}
+
 	// If configured, set up TLS wrapping.
 	if cert != nil {
 		var clientCAs *x509.CertPool
+		var verifyClientCert func([][]byte, [][]*x509.Certificate) error
 		clientAuthMode := tls.NoClientCert
 
 		if agentRM := m.config.ResourceManager.AgentRM; agentRM != nil && agentRM.RequireAuthentication {
@@ -744,38 +2053,59 @@ func (m *Master) startServers(ctx context.Context, cert *tls.Certificate) error
 				}
 				clientCAs.AppendCertsFromPEM(clientRootCA)
 			}
+
+			verifyClientCert, err = clientCertVerifier(m.config.Security.TLS.ClientCert)
+			if err != nil {
+				return errors.Wrap(err, "invalid client certificate requirements")
+			}
 		}
 
-		baseListener = tls.NewListener(baseListener, &tls.Config{
+		tlsConfig := &tls.Config{
 			Certificates:             []tls.Certificate{*cert},
 			MinVersion:               tls.VersionTLS12,
 			PreferServerCipherSuites: true,
 			ClientCAs:                clientCAs,
 			ClientAuth:               clientAuthMode,
-		})
+			VerifyPeerCertificate:    verifyClientCert,
+		}
+		baseListener = tls.NewListener(baseListener, tlsConfig)
+		if grpcBaseListener != nil {
+			grpcBaseListener = tls.NewListener(grpcBaseListener, grpcTLSConfig(tlsConfig))
+		}
 	}
 
 	// This must be before grpcutil.RegisterHTTPProxy is called since it may use stuff set up by the
 	// gRPC server (logger initialization, maybe more). Found by --race.
 	gRPCServer := grpcutil.NewGRPCServer(m.db, &apiServer{m: m},
 		m.config.Observability.EnablePrometheus,
-		&m.config.InternalConfig.ExternalSessions)
+		&m.config.InternalConfig.ExternalSessions,
+		m.config.GRPC)
 
-	err = grpcutil.RegisterHTTPProxy(ctx, m.echo, m.config.Port, cert)
+	grpcPort := m.config.Port
+	if separateGRPC {
+		grpcPort = m.config.GRPC.Port
+	}
+	err = grpcutil.RegisterHTTPProxy(ctx, m.echo, grpcPort, cert)
 	if err != nil {
 		return errors.Wrap(err, "failed to register gRPC gateway")
 	}
 
 	// Initialize listeners and multiplexing.
-	mux := cmux.New(baseListener)
+	var mux cmux.CMux
+	var grpcListener, httpListener net.Listener
+	if separateGRPC {
+		grpcListener, httpListener = grpcBaseListener, baseListener
+	} else {
+		mux = cmux.New(baseListener)
 
-	grpcListener := mux.MatchWithWriters(
-		cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"),
-	)
-	defer closeWithErrCheck("grpc", grpcListener)
+		grpcListener = mux.MatchWithWriters(
+			cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"),
+		)
+		defer closeWithErrCheck("grpc", grpcListener)
 
-	httpListener := mux.Match(cmux.HTTP1(), cmux.HTTP2())
-	defer closeWithErrCheck("http", httpListener)
+		httpListener = mux.Match(cmux.HTTP1(), cmux.HTTP2())
+		defer closeWithErrCheck("http", httpListener)
+	}
 
 	// Start all servers and return the first error. This leaks a channel, but the complexity of
 	// perfectly handling cleanup and all the error cases doesn't seem worth it for a function that is
@@ -793,28 +2123,118 @@ func (m *Master) startServers(ctx context.Context, cert *tls.Certificate) error
 		// To be fixed by https://github.com/soheilhy/cmux/pull/69 which makes cmux an io.Closer.
 		return gRPCServer.Serve(grpcListener)
 	})
+	// Followed log streams never finish on their own, so they are told to end once draining starts.
+	m.draining = make(chan struct{})
+	m.echo.Server.RegisterOnShutdown(func() { close(m.draining) })
 	start("HTTP server", func() error {
 		m.echo.Listener = httpListener
 		m.echo.HidePort = true
 		m.echo.Server.ConnContext = connsave.SaveConn
-		defer closeWithErrCheck("echo", m.echo)
-		return m.echo.StartServer(m.echo.Server)
+		m.echo.Server.ConnState = connsave.TrackConnState
+		connsave.SetMaxLifetime(time.Duration(m.config.Observability.SavedConnectionMaxLifetime))
+		err := m.echo.StartServer(m.echo.Server)
+		// Once shutting down, closing would cut off the requests being drained.
+		if !errors.Is(err, http.ErrServerClosed) {
+			closeWithErrCheck("echo", m.echo)
+		}
+		return err
 	})
-	start("cmux listener", mux.Serve)
+	if mux != nil {
+		start("cmux listener", mux.Serve)
+	}
+
+	// Routes are all registered by now, but give the servers a moment to settle before telling load
+	// balancers to send traffic our way.
+	go func() {
+		select {
+		case <-time.After(time.Duration(m.config.Readiness.WarmupDelay)):
+			m.ready.Store(true)
+		case <-ctx.Done():
+		}
+	}()
 
 	if systemdListener != nil {
 		log.Infof("accepting incoming connections on a socket inherited from systemd")
 	} else {
 		log.Infof("accepting incoming connections on port %d", m.config.Port)
 	}
+	if separateGRPC {
+		log.Infof("accepting incoming gRPC connections on port %d", m.config.GRPC.Port)
+	}
 	select {
 	case err := <-errs:
 		return err
 	case <-ctx.Done():
+		m.shutdownServers(gRPCServer)
 		return ctx.Err()
 	}
 }
 
+// grpcTLSConfig returns a copy of tlsConfig for a listener that serves only gRPC. Without cmux in
+// front of it, nothing else negotiates HTTP/2, and clients that require ALPN refuse the connection.
+func grpcTLSConfig(tlsConfig *tls.Config) *tls.Config {
+	grpcConfig := tlsConfig.Clone()
+	grpcConfig.NextProtos = []string{"h2"}
+	return grpcConfig
+}
+
+// shutdownServers stops accepting requests and gives those in flight, such as allocation and task
+// log POSTs, up to the configured grace period to finish.
+func (m *Master) shutdownServers(gRPCServer *grpc.Server) {
+	m.ready.Store(false)
+	gracePeriod := time.Duration(m.config.Shutdown.GracePeriod)
+	log.Infof("draining requests in flight for up to %s", gracePeriod)
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	drainServers(ctx, m.echo.Shutdown, gRPCServer.GracefulStop)
+}
+
+// drainServers shuts down the HTTP server and only then the gRPC server, both within ctx. The gRPC
+// gateway serves REST requests through the gRPC server, so stopping it first would fail the HTTP
+// requests still draining.
+func drainServers(
+	ctx context.Context, shutdownHTTP func(context.Context) error, stopGRPC func(),
+) {
+	if err := shutdownHTTP(ctx); err != nil {
+		log.WithError(err).Warn("HTTP server did not drain within the grace period")
+	}
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		defer close(grpcStopped)
+		stopGRPC()
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		// Like Stop, GracefulStop may never return when serving through cmux (see startServers), so
+		// whatever is left is abandoned rather than holding up the shutdown.
+		log.Warn("gRPC server did not drain within the grace period")
+	}
+}
+
+// readCertificate reads the configured TLS certificate, retrying with exponential backoff so that
+// a transient failure during certificate rotation does not abort startup.
+func (m *Master) readCertificate() (*tls.Certificate, error) {
+	tlsConfig := m.config.Security.TLS
+	backoff := time.Duration(tlsConfig.ReadBackoff)
+	for attempt := 1; ; attempt++ {
+		cert, err := tlsConfig.ReadCertificate()
+		if err == nil {
+			return cert, nil
+		}
+		if attempt >= tlsConfig.ReadAttempts {
+			return nil, errors.Wrapf(err, "giving up after %d attempts", attempt)
+		}
+		log.WithError(err).Warnf(
+			"failed to read TLS certificate (attempt %d/%d), retrying in %s",
+			attempt, tlsConfig.ReadAttempts, backoff,
+		)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
 func closeWithErrCheck(name string, closer io.Closer) {
 	err := closer.Close()
 	if err != nil {
@@ -822,19 +2242,56 @@ func closeWithErrCheck(name string, closer io.Closer) {
 	}
 }
 
-func (m *Master) tryRestoreExperiment(sema chan struct{}, wg *sync.WaitGroup, e *model.Experiment) {
+// closeCriticalWithErrCheck closes a closer for which a failure to close cleanly means an unclean
+// shutdown. Besides being logged, the failure is counted and, unless the caller is already failing,
+// returned through errp so that it affects the process exit code.
+func closeCriticalWithErrCheck(name string, closer io.Closer, errp *error) {
+	err := closer.Close()
+	if err == nil {
+		return
+	}
+	log.WithError(err).Errorf("error closing critical closer %s, shutdown was unclean", name)
+	prom.UncleanCloses.WithLabelValues(name).Inc()
+	if *errp == nil {
+		*errp = errors.Wrapf(err, "closing %s", name)
+	}
+}
+
+func (m *Master) tryRestoreExperiment(
+	sema chan struct{}, poolSemas map[string]chan struct{}, wg *sync.WaitGroup,
+	e *model.Experiment,
+) {
+	// Wait for a slot in the experiment's pool before taking a global one, so that restores queued
+	// on a busy pool don't hold up restores into other pools. Looking up the pool queries the
+	// database, so it is done under a global slot too, given back while waiting on the pool.
+	if len(poolSemas) > 0 {
+		sema <- struct{}{}
+		poolSema := poolSemas[m.restorePool(e)]
+		<-sema
+		if poolSema != nil {
+			poolSema <- struct{}{}
+			defer func() { <-poolSema }()
+		}
+	}
 	sema <- struct{}{}
 	defer func() { <-sema }()
 	defer func() { wg.Done() }()
+	defer m.restores.done()
 
 	// restoreExperiments waits for experiment allocations to be initialized.
-	if err := m.restoreExperiment(e); err != nil {
+	start := time.Now()
+	err := m.restoreExperiment(e)
+	prom.ExperimentRestoreDuration.Observe(time.Since(start).Seconds())
+	if err == nil {
+		prom.ExperimentRestoreSuccesses.Inc()
+	} else {
+		prom.ExperimentRestoreFailures.Inc()
 		log.WithError(err).Errorf("failed to restore experiment: %d", e.ID)
 		e.State = model.ErrorState
 		if err := m.db.TerminateExperimentInRestart(e.ID, e.State); err != nil {
 			log.WithError(err).Error("failed to mark experiment as errored")
 		}
-		telemetry.ReportExperimentStateChanged(m.system, m.db, *e)
+		m.expStateReports.Report(*e)
 	}
 }
 
@@ -856,19 +2313,27 @@ func (m *Master) tryRestoreExperiment(sema chan struct{}, wg *sync.WaitGroup, e
 // This would potentially speed up the startup when there're lots of these.
 func (m *Master) restoreNonTerminalExperiments() error {
 	// Restore non-terminal experiments from the database.
-	// Limit the number of concurrent restores at any time within the system to maxConcurrentRestores.
-	// This has avoided resource exhaustion in the past (on the db connection pool) and probably is
-	// good still to avoid overwhelming us on restart after a crash.
-	sema := make(chan struct{}, maxConcurrentRestores)
+	// Limit the number of concurrent restores at any time within the system to
+	// restore.max_concurrent. This has avoided resource exhaustion in the past (on the db
+	// connection pool) and probably is good still to avoid overwhelming us on restart after a crash.
+	sema := make(chan struct{}, m.config.Restore.MaxConcurrent)
 	toRestore, err := m.db.NonTerminalExperiments()
 	if err != nil {
 		return errors.Wrap(err, "couldn't retrieve experiments to restore")
 	}
+	m.restores.reset(len(toRestore))
+
+	// Restores into pools with restore.max_concurrent_per_pool set are also limited per pool, so
+	// that autoscaling pools aren't asked to scale up for all of their experiments at once.
+	poolSemas := make(map[string]chan struct{}, len(m.config.Restore.MaxConcurrentPerPool))
+	for pool, limit := range m.config.Restore.MaxConcurrentPerPool {
+		poolSemas[pool] = make(chan struct{}, limit)
+	}
 
 	wg := sync.WaitGroup{}
 	for _, exp := range toRestore {
 		wg.Add(1)
-		go m.tryRestoreExperiment(sema, &wg, exp)
+		go m.tryRestoreExperiment(sema, poolSemas, &wg, exp)
 	}
 
 	wg.Wait()
@@ -876,14 +2341,256 @@ func (m *Master) restoreNonTerminalExperiments() error {
 	return nil
 }
 
+// restorePool returns the resource pool an experiment will be restored into, or an empty string if
+// it can't be determined, in which case restoring it fails later on anyway.
+func (m *Master) restorePool(e *model.Experiment) string {
+	activeConfig, err := m.db.ActiveExperimentConfig(e.ID)
+	if err != nil {
+		return ""
+	}
+	pool, err := m.rm.ResolveResourcePool(
+		m.system,
+		activeConfig.Resources().ResourcePool(),
+		activeConfig.Resources().SlotsPerTrial(),
+	)
+	if err != nil {
+		return ""
+	}
+	return pool
+}
+
 func (m *Master) closeOpenAllocations() error {
 	allocationIds := allocationmap.GetAllAllocationIds()
-	if err := m.db.CloseOpenAllocations(allocationIds); err != nil {
+	closed, err := m.db.CloseOpenAllocations(allocationIds)
+	if err != nil {
 		return err
 	}
+	if len(closed) > 0 {
+		log.Warnf("closed %d allocations left open when the master last stopped", len(closed))
+	}
+	m.forceClosedAllocations = closed
 	return nil
 }
 
+// getForceClosedAllocations lists the allocations closed on startup because they were still open
+// when the master last stopped. Their end times are only as precise as the last cluster heartbeat,
+// so billing may want to flag them.
+func (m *Master) getForceClosedAllocations(echo.Context) (interface{}, error) {
+	closed := m.forceClosedAllocations
+	if closed == nil {
+		closed = []db.ForceClosedAllocation{}
+	}
+	return map[string]interface{}{"allocations": closed}, nil
+}
+
+// getWebhooks lists the webhooks and their triggers, optionally only those with triggers of a given
+// type, to audit which webhooks fire on which events.
+func (m *Master) getWebhooks(c echo.Context) (interface{}, error) {
+	args := struct {
+		TriggerType *string `query:"trigger_type"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	if err := webhooks.AuthZProvider.Get().CanEditWebhooks(ctx, &user); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+
+	var ws webhooks.Webhooks
+	var err error
+	if args.TriggerType == nil {
+		ws, err = webhooks.GetWebhooks(ctx)
+	} else {
+		triggerType := webhooks.TriggerType(strings.ToUpper(*args.TriggerType))
+		switch triggerType {
+		case webhooks.TriggerTypeStateChange, webhooks.TriggerTypeMetricThresholdExceeded:
+		default:
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+				"trigger_type must be %s or %s, got %q", webhooks.TriggerTypeStateChange,
+				webhooks.TriggerTypeMetricThresholdExceeded, *args.TriggerType))
+		}
+		ws, err = webhooks.GetWebhooksWithTrigger(ctx, triggerType)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching webhooks")
+	}
+
+	results := make([]map[string]interface{}, 0, len(ws))
+	for _, w := range ws {
+		triggers := make([]map[string]interface{}, 0, len(w.Triggers))
+		for _, t := range w.Triggers {
+			triggers = append(triggers, map[string]interface{}{
+				"id":           t.ID,
+				"trigger_type": t.TriggerType,
+				"condition":    t.Condition,
+			})
+		}
+		results = append(results, map[string]interface{}{
+			"id":           w.ID,
+			"url":          w.URL,
+			"webhook_type": w.WebhookType,
+			"signed":       w.SigningSecret != "",
+			"triggers":     triggers,
+		})
+	}
+	return map[string]interface{}{"webhooks": results}, nil
+}
+
+// postReloadWebhooks restarts webhook delivery without restarting the master and reports how many
+// webhooks are configured afterward. Deliveries in flight finish in the background, so it doesn't
+// wait on them.
+func (m *Master) postReloadWebhooks(c echo.Context) error {
+	webhooks.Reload()
+	ws, err := webhooks.GetWebhooks(c.Request().Context())
+	if err != nil {
+		return errors.Wrap(err, "error fetching webhooks")
+	}
+	return c.JSON(http.StatusAccepted, map[string]interface{}{"webhooks": len(ws)})
+}
+
+// postTestWebhook sends a test event to a webhook and reports the response of its endpoint, to
+// check the master can reach it without waiting for a real event.
+func (m *Master) postTestWebhook(c echo.Context) (interface{}, error) {
+	args := struct {
+		WebhookID int `path:"webhook_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+
+	delivery, err := webhooks.SendTestEvent(c.Request().Context(), args.WebhookID)
+	if errors.Is(db.MatchSentinelError(err), db.ErrNotFound) {
+		return nil, echo.NewHTTPError(
+			http.StatusNotFound, fmt.Sprintf("webhook not found: %d", args.WebhookID),
+		)
+	} else if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// getWebhookDeadLetters lists the webhook events whose delivery failed for good.
+func (m *Master) getWebhookDeadLetters(c echo.Context) (interface{}, error) {
+	deadLetters, err := webhooks.GetDeadLetters(c.Request().Context())
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching webhook dead letters")
+	}
+
+	results := make([]map[string]interface{}, 0, len(deadLetters))
+	for _, d := range deadLetters {
+		results = append(results, map[string]interface{}{
+			"id":        d.ID,
+			"url":       d.URL,
+			"payload":   json.RawMessage(d.Payload),
+			"error":     d.Error,
+			"attempts":  d.Attempts,
+			"failed_at": d.FailedAt,
+		})
+	}
+	return map[string]interface{}{"dead_letters": results}, nil
+}
+
+// postRedeliverWebhookDeadLetter queues a dead-lettered webhook event to be delivered again.
+func (m *Master) postRedeliverWebhookDeadLetter(c echo.Context) (interface{}, error) {
+	args := struct {
+		DeadLetterID int `path:"dead_letter_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+
+	err := webhooks.RedeliverDeadLetter(
+		c.Request().Context(), webhooks.DeadLetterID(args.DeadLetterID),
+	)
+	if errors.Is(db.MatchSentinelError(err), db.ErrNotFound) {
+		return nil, echo.NewHTTPError(
+			http.StatusNotFound, fmt.Sprintf("dead letter not found: %d", args.DeadLetterID),
+		)
+	} else if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// defaultWebhookSecretTransition is how long the signing secret a webhook's rotated secret replaces
+// keeps signing payloads, unless a transition period is given.
+const defaultWebhookSecretTransition = 24 * time.Hour
+
+// minWebhookSigningSecretLength is the shortest signing secret that may be given for a webhook,
+// long enough that its signatures can't be forged by guessing it.
+const minWebhookSigningSecretLength = 32
+
+// maxWebhookSecretBodySize bounds the body of a request to rotate a webhook's signing secret.
+const maxWebhookSecretBodySize = 64 << 10
+
+// postRotateWebhookSecret sets a new signing secret for a webhook, generated unless one is given,
+// and returns it. The secret it replaces keeps signing payloads during the transition period.
+func (m *Master) postRotateWebhookSecret(c echo.Context) (interface{}, error) {
+	args := struct {
+		WebhookID int `path:"webhook_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	if err := webhooks.AuthZProvider.Get().CanEditWebhooks(ctx, &user); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+
+	var params struct {
+		Secret           string          `json:"secret"`
+		TransitionPeriod *model.Duration `json:"transition_period"`
+	}
+	if req := c.Request(); req.ContentLength != 0 {
+		req.Body = http.MaxBytesReader(c.Response(), req.Body, maxWebhookSecretBodySize)
+		if err := api.DecodeJSONBody(c, maxWebhookSecretBodySize, &params); err != nil {
+			return nil, err
+		}
+	}
+	if params.Secret != "" && len(params.Secret) < minWebhookSigningSecretLength {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+			"secret must be at least %d characters", minWebhookSigningSecretLength))
+	}
+
+	transition := defaultWebhookSecretTransition
+	if params.TransitionPeriod != nil {
+		if *params.TransitionPeriod < 0 {
+			return nil, echo.NewHTTPError(
+				http.StatusBadRequest, "transition_period must be non-negative",
+			)
+		}
+		transition = time.Duration(*params.TransitionPeriod)
+	}
+	secret := params.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.Wrap(err, "error generating signing secret")
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	webhook, err := webhooks.RotateSigningSecret(
+		ctx, webhooks.WebhookID(args.WebhookID), secret, transition,
+	)
+	if errors.Is(db.MatchSentinelError(err), db.ErrNotFound) {
+		return nil, echo.NewHTTPError(
+			http.StatusNotFound, fmt.Sprintf("webhook not found: %d", args.WebhookID),
+		)
+	} else if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{"webhook_id": webhook.ID, "secret": webhook.SigningSecret}
+	if webhook.PreviousSigningSecret != "" {
+		result["previous_secret_expires_at"] = webhook.PreviousSigningSecretExpiresAt
+	}
+	return result, nil
+}
+
 // convertDBErrorsToNotFound helps reduce boilerplate in our handlers, by
 // classifying database "not found" errors as HTTP "not found" errors.
 func convertDBErrorsToNotFound(next echo.HandlerFunc) echo.HandlerFunc {
@@ -896,15 +2603,43 @@ func convertDBErrorsToNotFound(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
-func updateClusterHeartbeat(ctx context.Context, db *db.PgDB) {
-	t := time.NewTicker(10 * time.Minute)
+// handleStreamedExportErrors wraps handlers that stream exports, such as CSV files, so that errors
+// after the response has started aren't reported as a server error on top of a successful status.
+// Clients disconnecting mid-export are expected and only logged at debug level; other errors are
+// logged, since the client can only tell from the export being cut short.
+func handleStreamedExportErrors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		switch {
+		case err == nil:
+			return nil
+		case c.Request().Context().Err() != nil:
+			log.WithError(err).Debugf("client disconnected during export %s", c.Request().URL.Path)
+			return nil
+		case c.Response().Committed:
+			log.WithError(err).Errorf("export %s failed partway", c.Request().URL.Path)
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+func updateClusterHeartbeat(ctx context.Context, db *db.PgDB, interval time.Duration) {
+	t := time.NewTicker(interval)
 	defer t.Stop()
+	failures := 0
 	for {
 		currentTime := time.Now().UTC().Truncate(time.Millisecond)
 		err := db.UpdateClusterHeartBeat(currentTime)
 		if err != nil {
 			log.Error(err.Error())
+			failures++
+		} else {
+			failures = 0
+			prom.SetLastHeartbeat(time.Now())
 		}
+		prom.HeartbeatConsecutiveFailures.Set(float64(failures))
 		select {
 		case <-t.C:
 		case <-ctx.Done():
@@ -913,23 +2648,132 @@ func updateClusterHeartbeat(ctx context.Context, db *db.PgDB) {
 	}
 }
 
+// decodeTaskLogsBody decodes a task logs request body into v, responding with 413 if the body as
+// sent exceeds the configured limit.
+func (m *Master) decodeTaskLogsBody(c echo.Context, v interface{}) error {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, m.config.TaskLogs.MaxBodySize)
+	return api.DecodeJSONBody(c, m.config.Security.MaxDecompressedBody, v)
+}
+
+// checkTaskLogsBatchSize responds with 413 if a request holds more log entries than allowed.
+func (m *Master) checkTaskLogsBatchSize(n int) error {
+	if limit := m.config.TaskLogs.MaxBatchSize; n > limit {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("batch of %d task logs exceeds the limit of %d", n, limit))
+	}
+	return nil
+}
+
 func (m *Master) postTaskLogs(c echo.Context) (interface{}, error) {
 	var logs []*model.TaskLog
-	if err := json.NewDecoder(c.Request().Body).Decode(&logs); err != nil {
+	if err := m.decodeTaskLogsBody(c, &logs); err != nil {
+		return "", err
+	}
+	if err := m.checkTaskLogsBatchSize(len(logs)); err != nil {
 		return "", err
 	}
+	for i, l := range logs {
+		switch {
+		case l == nil:
+			return "", echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("task log %d must not be null", i))
+		case l.TaskID == "":
+			return "", echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("task log %d is missing a task ID", i))
+		}
+	}
+	addLogs := func() (interface{}, error) {
+		if err := m.taskLogBackend.AddTaskLogs(logs); err != nil {
+			return "", errors.Wrap(err, "receiving task logs")
+		}
+		return taskLogsAdded{Added: len(logs)}, nil
+	}
+
+	// Agents may resend a batch after a network failure; with an idempotency key, only the first
+	// delivery is stored.
+	key := c.Request().Header.Get(api.HeaderIdempotencyKey)
+	if m.taskLogBatches == nil || key == "" {
+		return addLogs()
+	}
+	return m.taskLogBatches.Do(taskLogBatchKey(logs, key), addLogs)
+}
+
+// taskLogBatchKey scopes the idempotency key of a task log batch to the tasks it holds logs for, so
+// that a key reused by the sender of another task's logs can't suppress their batch.
+func taskLogBatchKey(logs []*model.TaskLog, key string) string {
+	seen := map[string]bool{}
+	var taskIDs []string
+	for _, l := range logs {
+		if !seen[l.TaskID] {
+			seen[l.TaskID] = true
+			taskIDs = append(taskIDs, l.TaskID)
+		}
+	}
+	sort.Strings(taskIDs)
+	// Task IDs can't contain newlines, so the scope can't run into the key.
+	return strings.Join(taskIDs, ",") + "\n" + key
+}
+
+// taskLogsAdded reports how many task logs were persisted from a batch.
+type taskLogsAdded struct {
+	Added int `json:"added"`
+}
+
+// taskLogsAck reports whether one task's logs in a bulk upload were persisted.
+type taskLogsAck struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// postBulkTaskLogs accepts logs for several tasks, keyed by task ID, and persists each task's
+// logs independently so that callers can retry only the tasks that failed.
+func (m *Master) postBulkTaskLogs(c echo.Context) (interface{}, error) {
+	var logsByTask map[string][]*model.TaskLog
+	if err := m.decodeTaskLogsBody(c, &logsByTask); err != nil {
+		return nil, err
+	}
+	var total int
+	for _, logs := range logsByTask {
+		total += len(logs)
+	}
+	if err := m.checkTaskLogsBatchSize(total); err != nil {
+		return nil, err
+	}
+
+	acks := make(map[string]taskLogsAck, len(logsByTask))
+	for taskID, logs := range logsByTask {
+		if err := m.addBulkTaskLogs(taskID, logs); err != nil {
+			log.WithError(err).Warnf("failed to receive task logs for task %s", taskID)
+			acks[taskID] = taskLogsAck{Error: err.Error()}
+			continue
+		}
+		acks[taskID] = taskLogsAck{Success: true}
+	}
+	return acks, nil
+}
+
+func (m *Master) addBulkTaskLogs(taskID string, logs []*model.TaskLog) error {
+	for _, l := range logs {
+		switch {
+		case l == nil:
+			return errors.New("task log must not be null")
+		case l.TaskID == "":
+			l.TaskID = taskID
+		case l.TaskID != taskID:
+			return errors.Errorf("task log for task %s submitted under task %s", l.TaskID, taskID)
+		}
+	}
 	if err := m.taskLogBackend.AddTaskLogs(logs); err != nil {
-		return "", errors.Wrap(err, "receiving task logs")
+		return errors.Wrap(err, "receiving task logs")
 	}
-	return "", nil
+	return nil
 }
 
 // Run causes the Determined master to connect the database and begin listening for HTTP requests.
-func (m *Master) Run(ctx context.Context) error {
+func (m *Master) Run(ctx context.Context) (err error) {
 	log.Infof("Determined master %s (built with %s)", version.Version, runtime.Version())
 
-	var err error
-
 	if err = etc.SetRootPath(filepath.Join(m.config.Root, "static/srv")); err != nil {
 		return errors.Wrap(err, "could not set static root")
 	}
@@ -938,7 +2782,7 @@ func (m *Master) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	defer closeWithErrCheck("db", m.db)
+	defer closeCriticalWithErrCheck("db", m.db, &err)
 
 	m.ClusterID, err = m.db.GetOrCreateClusterID()
 	if err != nil {
@@ -948,7 +2792,7 @@ func (m *Master) Run(ctx context.Context) error {
 	// Must happen before recovery. If tasks can't recover their allocations, they need an end time.
 	cluster.InitTheLastBootClusterHeartbeat()
 
-	cert, err := m.config.Security.TLS.ReadCertificate()
+	cert, err := m.readCertificate()
 	if err != nil {
 		return errors.Wrap(err, "failed to read TLS certificate")
 	}
@@ -985,11 +2829,22 @@ func (m *Master) Run(ctx context.Context) error {
 	m.system = actor.NewSystemWithRoot("master", actor.ActorFunc(root))
 
 	ctx, cancel := context.WithCancel(ctx)
+	var systemExited atomic.Bool
 	go func() {
 		sErr := m.system.Ref.AwaitTermination()
 		log.WithError(sErr).Error("actor system exited")
+		systemExited.Store(true)
 		cancel()
 	}()
+	defer func() {
+		switch {
+		case !systemExited.Load():
+		case err != nil:
+			err = errors.Wrapf(ErrActorSystemExited, "%v", err)
+		default:
+			err = ErrActorSystemExited
+		}
+	}()
 
 	switch {
 	case m.config.Logging.DefaultLoggingConfig != nil:
@@ -1025,26 +2880,23 @@ func (m *Master) Run(ctx context.Context) error {
 
 	// Initialize the HTTP server and listen for incoming requests.
 	m.echo = echo.New()
+	m.echo.IPExtractor = ipExtractor(m.config.Security.TrustedProxies)
 	m.echo.Use(middleware.Recover())
 
-	gzipConfig := middleware.GzipConfig{
-		Skipper: func(c echo.Context) bool {
-			webuiStaticAssets := regexp.MustCompile(`\/det\/(themes|static|determined)\/`)
-			return !webuiStaticAssets.MatchString(c.Request().URL.Path)
-		},
-	}
-	m.echo.Use(middleware.GzipWithConfig(gzipConfig))
+	m.echo.Use(api.Gzip(m.config.Compression.MinLength, gzipSkipper))
 
 	m.echo.Use(middleware.AddTrailingSlashWithConfig(middleware.TrailingSlashConfig{
 		Skipper: func(c echo.Context) bool {
 			return !staticWebDirectoryPaths[c.Path()]
 		},
-		RedirectCode: http.StatusMovedPermanently,
+		RedirectCode: m.config.WebUI.TrailingSlashRedirectCode,
 	}))
 	setupEchoRedirects(m)
 
 	if m.config.EnableCors {
-		m.echo.Use(api.CORSWithTargetedOrigin)
+		// Run before routing so that preflight requests are answered for every route, without
+		// reaching redirects, group middleware or handlers.
+		m.echo.Pre(api.CORSWithTargetedOrigin)
 	}
 
 	// Add resistance to common HTTP attacks.
@@ -1067,7 +2919,11 @@ func (m *Master) Run(ctx context.Context) error {
 	m.echo.Use(convertDBErrorsToNotFound)
 
 	if m.config.InternalConfig.AuditLoggingEnabled {
-		m.echo.Use(auditLogMiddleware())
+		sink, err := newAuditSink(ctx, m.config.InternalConfig.AuditLogSink)
+		if err != nil {
+			return err
+		}
+		m.echo.Use(auditLogMiddleware(sink))
 	}
 
 	if m.config.Telemetry.OtelEnabled {
@@ -1077,6 +2933,9 @@ func (m *Master) Run(ctx context.Context) error {
 
 	m.echo.Use(authzAuditLogMiddleware())
 	m.echo.Use(userService.ProcessAuthentication)
+	if m.config.RateLimit.Enabled() {
+		m.echo.Use(rateLimitMiddleware(m.config.RateLimit))
+	}
 
 	m.echo.Logger = logger.New()
 	m.echo.HideBanner = true
@@ -1104,10 +2963,14 @@ func (m *Master) Run(ctx context.Context) error {
 	)
 	tasksGroup := m.echo.Group("/tasks")
 	tasksGroup.GET("", api.Route(m.getTasks))
+	tasksGroup.GET("/summary", api.Route(m.getTaskSummary))
 
 	m.system.ActorOf(actor.Addr("experiments"), &actors.Group{})
 	m.system.ActorOf(sproto.JobsActorAddr, job.NewJobs(m.rm))
 
+	m.expStateReports = telemetry.NewExperimentStateReporter(
+		ctx, m.system, m.db, m.config.Telemetry,
+	)
 	if err = m.restoreNonTerminalExperiments(); err != nil {
 		return err
 	}
@@ -1139,7 +3002,7 @@ func (m *Master) Run(ctx context.Context) error {
 	// The below function call is intentionally made after the call to CloseOpenAllocations.
 	// This ensures that in the scenario where a cluster fails all open allocations are
 	// set to the last cluster heartbeat when the cluster was running.
-	go updateClusterHeartbeat(ctx, m.db)
+	go updateClusterHeartbeat(ctx, m.db, time.Duration(m.config.Cluster.HeartbeatInterval))
 
 	// Docs and WebUI.
 	webuiRoot := filepath.Join(m.config.Root, "webui")
@@ -1151,8 +3014,14 @@ func (m *Master) Run(ctx context.Context) error {
 	reactIndex := filepath.Join(reactRoot, "index.html")
 
 	// Docs.
-	m.echo.Static("/docs/rest-api", filepath.Join(webuiRoot, "docs", "rest-api"))
-	m.echo.Static("/docs", filepath.Join(webuiRoot, "docs"))
+	var docsMiddleware []echo.MiddlewareFunc
+	if m.config.Security.DocsRequireAuth {
+		docsMiddleware = append(docsMiddleware, userService.RequireAuthentication)
+	}
+	for _, prefix := range []string{"/docs/rest-api", "/docs"} {
+		docsRoot := echo.MustSubFS(m.echo.Filesystem, filepath.Join(webuiRoot, prefix))
+		m.echo.GET(prefix+"*", echo.StaticDirectoryHandler(docsRoot, false), docsMiddleware...)
+	}
 
 	webuiGroup := m.echo.Group(webuiBaseRoute)
 	webuiGroup.File("", reactIndex)
@@ -1188,16 +3057,21 @@ func (m *Master) Run(ctx context.Context) error {
 		cacheFileLongTerm := regexp.MustCompile(`.(chunk\.(css|js)|woff2|woff)$`)
 		cacheFileShortTerm := regexp.MustCompile(`.(antd.\S+(.css)|ico|png|jpe*g|gif|svg)$`)
 
+		cacheCategory := "none"
 		if cacheFileLongTerm.MatchString(requestedFile) {
 			c.Response().Header().Set("cache-control", "public, max-age=31536000")
+			cacheCategory = "long_term"
 		} else if cacheFileShortTerm.MatchString(requestedFile) {
 			c.Response().Header().Set("cache-control", "public, max-age=600")
+			cacheCategory = "short_term"
 		}
 
 		if hasMatchingFile {
+			prom.WebUIAssetsServed.WithLabelValues(cacheCategory, "file").Inc()
 			return c.File(requestedFile)
 		}
 
+		prom.WebUIAssetsServed.WithLabelValues(cacheCategory, "index").Inc()
 		return c.File(reactIndex)
 	})
 
@@ -1205,16 +3079,53 @@ func (m *Master) Run(ctx context.Context) error {
 		filepath.Join(m.config.Root, "swagger/determined/api/v1/api.swagger.json"))
 
 	m.echo.GET("/config", api.Route(m.getConfig))
+	m.echo.GET("/config/defaults", api.Route(m.getConfigDefaults),
+		userService.RequireAdminAuthentication)
+	m.echo.GET("/config/task-container-defaults", api.Route(m.getTaskContainerDefaults))
 	m.echo.GET("/info", api.Route(m.getInfo))
-	m.echo.GET("/logs", api.Route(m.getMasterLogs))
+	m.echo.GET("/info/uptime", api.Route(m.getUptime))
+	m.echo.GET("/cluster/force-closed-allocations", api.Route(m.getForceClosedAllocations))
+	m.echo.GET("/allocations/:allocation_id/expected-containers",
+		api.Route(m.getExpectedContainers), userService.RequireAdminAuthentication)
+	m.echo.POST("/allocations/:allocation_id/close", api.Route(m.postCloseAllocation),
+		userService.RequireAdminAuthentication)
+	m.echo.GET("/ready", m.getReady)
+	m.echo.GET("/health", m.getHealth)
+	m.echo.GET("/sso/providers", api.Route(m.getSSOProviders))
+	m.echo.GET("/webhooks", api.Route(m.getWebhooks))
+	m.echo.POST("/webhooks/reload", m.postReloadWebhooks, userService.RequireAdminAuthentication)
+	m.echo.POST("/webhooks/:webhook_id/test", api.Route(m.postTestWebhook),
+		userService.RequireAdminAuthentication)
+	m.echo.POST("/webhooks/:webhook_id/rotate-secret", api.Route(m.postRotateWebhookSecret))
+	m.echo.GET("/webhooks/dead-letters", api.Route(m.getWebhookDeadLetters),
+		userService.RequireAdminAuthentication)
+	m.echo.POST("/webhooks/dead-letters/:dead_letter_id/redeliver",
+		api.Route(m.postRedeliverWebhookDeadLetter), userService.RequireAdminAuthentication)
+	m.echo.GET("/logs", m.getMasterLogs)
+	m.echo.GET("/logs/export", m.getMasterLogsExport)
+	m.echo.GET("/logs/:log_id", api.Route(m.getMasterLog))
 
 	experimentsGroup := m.echo.Group("/experiments")
 	experimentsGroup.GET("/:experiment_id/model_def", m.getExperimentModelDefinition)
 	experimentsGroup.GET("/:experiment_id/file/download", m.getExperimentModelFile)
-	experimentsGroup.GET("/:experiment_id/preview_gc", api.Route(m.getExperimentCheckpointsToGC))
+	experimentsGroup.GET("/:experiment_id/preview_gc", func(c echo.Context) error {
+		if api.NegotiateFormat(c, api.FormatJSON) == api.FormatCSV {
+			return m.getExperimentCheckpointsToGCCSV(c)
+		}
+		return api.Route(m.getExperimentCheckpointsToGC)(c)
+	})
+	experimentsGroup.GET("/:experiment_id/state-history", api.Route(m.getExperimentStateHistory))
 	experimentsGroup.PATCH("/:experiment_id", api.Route(m.patchExperiment))
+	experimentsGroup.GET("/restore-progress", api.Route(m.getRestoreProgress))
+	experimentsGroup.POST("/fail-delete", api.Route(m.failDeletingExperiments),
+		userService.RequireAdminAuthentication)
+	experimentsGroup.POST("/:experiment_id/fail-delete", api.Route(m.failDeletingExperiments),
+		userService.RequireAdminAuthentication)
 	experimentsGroup.POST("", api.Route(m.postExperiment))
 
+	workspacesGroup := m.echo.Group("/workspaces")
+	workspacesGroup.GET("/:workspace_id/preview_gc", api.Route(m.getWorkspaceCheckpointsToGC))
+
 	checkpointsGroup := m.echo.Group("/checkpoints")
 	checkpointsGroup.GET("/:checkpoint_uuid", m.getCheckpoint)
 
@@ -1223,29 +3134,51 @@ func (m *Master) Run(ctx context.Context) error {
 
 	trialsGroup := m.echo.Group("/trials")
 	trialsGroup.GET("/:trial_id", api.Route(m.getTrial))
-	trialsGroup.GET("/:trial_id/metrics", api.Route(m.getTrialMetrics))
+	trialsGroup.GET("/:trial_id/metrics", m.getTrialMetrics)
+	trialsGroup.GET("/:trial_id/logs", api.Route(m.getTrialLogs))
 
 	resourcesGroup := m.echo.Group("/resources")
-	resourcesGroup.GET("/allocation/raw", m.getRawResourceAllocation)
-	resourcesGroup.GET("/allocation/tasks-raw", m.getRawResourceAllocationTasks)
-	resourcesGroup.GET("/allocation/aggregated", m.getAggregatedResourceAllocation)
+	resourcesGroup.GET("/allocation/raw", handleStreamedExportErrors(m.getRawResourceAllocation))
+	resourcesGroup.GET(
+		"/allocation/tasks-raw", handleStreamedExportErrors(m.getRawResourceAllocationTasks),
+	)
+	resourcesGroup.GET(
+		"/allocation/tasks-raw/estimate", api.Route(m.getRawResourceAllocationTasksEstimate),
+	)
+	resourcesGroup.GET(
+		"/allocation/aggregated", handleStreamedExportErrors(m.getAggregatedResourceAllocation),
+	)
+	resourcesGroup.GET("/allocation/by-label", api.Route(m.getResourceAllocationByLabel))
+	resourcesGroup.GET("/allocation/compare", api.Route(m.getResourceAllocationComparison))
+	resourcesGroup.GET("/manager", api.Route(m.getResourceManager))
 
 	m.echo.POST("/task-logs", api.Route(m.postTaskLogs))
+	m.echo.POST("/task-logs/bulk", api.Route(m.postBulkTaskLogs))
 
-	m.echo.Any("/debug/pprof/*", echo.WrapHandler(http.HandlerFunc(pprof.Index)))
+	debugAuth := debugEndpointsAuthMiddleware(
+		userService, m.config.Observability.DebugEndpointsAuth,
+	)
+	m.echo.Any("/debug/pprof/*", echo.WrapHandler(http.HandlerFunc(pprof.Index)), debugAuth)
 	m.echo.Any(
 		"/debug/pprof/cmdline",
 		echo.WrapHandler(http.HandlerFunc(pprof.Cmdline)),
+		debugAuth,
 	)
 	m.echo.Any(
 		"/debug/pprof/profile",
 		echo.WrapHandler(http.HandlerFunc(pprof.Profile)),
+		debugAuth,
 	)
 	m.echo.Any(
 		"/debug/pprof/symbol",
 		echo.WrapHandler(http.HandlerFunc(pprof.Symbol)),
+		debugAuth,
 	)
-	m.echo.Any("/debug/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)))
+	m.echo.Any("/debug/pprof/trace", echo.WrapHandler(http.HandlerFunc(pprof.Trace)), debugAuth)
+	if m.config.Observability.ProfileCaptureDir != "" {
+		m.echo.POST("/debug/capture-profiles", api.Route(m.captureProfiles),
+			userService.RequireAdminAuthentication)
+	}
 
 	if m.config.Observability.EnablePrometheus {
 		p := prometheus.NewPrometheus("echo", nil)
@@ -1261,11 +3194,12 @@ func (m *Master) Run(ctx context.Context) error {
 			return c.Path()
 		}
 		p.Use(m.echo)
-		m.echo.Any("/debug/prom/metrics", echo.WrapHandler(promhttp.Handler()))
+		m.echo.Any("/debug/prom/metrics", echo.WrapHandler(promhttp.Handler()), debugAuth)
 		m.echo.Any("/prom/det-state-metrics",
-			echo.WrapHandler(promhttp.HandlerFor(prom.DetStateMetrics, promhttp.HandlerOpts{})))
+			echo.WrapHandler(promhttp.HandlerFor(prom.DetStateMetrics, promhttp.HandlerOpts{})),
+			debugAuth)
 		m.echo.Any("/prom/det-http-sd-config",
-			api.Route(m.getPrometheusTargets))
+			api.Route(m.getPrometheusTargets), debugAuth)
 	}
 
 	handler := m.system.AskAt(actor.Addr("proxy"), proxy.NewProxyHandler{ServiceID: "service"})
@@ -0,0 +1,343 @@
+//go:build integration
+// +build integration
+
+package internal
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/rm/allocationmap"
+	"github.com/determined-ai/determined/master/internal/task"
+	"github.com/determined-ai/determined/master/pkg/actor"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+	"github.com/determined-ai/determined/proto/pkg/checkpointv1"
+	"github.com/determined-ai/determined/proto/pkg/commonv1"
+	"github.com/determined-ai/determined/proto/pkg/masterv1"
+	"github.com/determined-ai/determined/proto/pkg/trialv1"
+)
+
+// requireTaskWithSlotTypes adds a task that ran at start for an hour, with an allocation backed by
+// each of the slot types.
+func requireTaskWithSlotTypes(
+	t *testing.T, pgDB *db.PgDB, start time.Time, slotTypes ...string,
+) *model.Task {
+	ctx := context.Background()
+	task := db.RequireMockTask(t, pgDB, nil)
+	task.StartTime, task.EndTime = start, ptrs.Ptr(start.Add(time.Hour))
+	_, err := db.Bun().NewUpdate().Model(task).
+		Column("start_time", "end_time").WherePK().Exec(ctx)
+	require.NoError(t, err)
+	for i, slotType := range slotTypes {
+		_, err := db.Bun().NewInsert().Model(&model.Allocation{
+			AllocationID: model.AllocationID(fmt.Sprintf("%s.%d", task.TaskID, i)),
+			TaskID:       task.TaskID,
+			Slots:        1,
+			StartTime:    ptrs.Ptr(start),
+			EndTime:      ptrs.Ptr(start.Add(time.Hour)),
+			State:        ptrs.Ptr(model.AllocationStateTerminated),
+			SlotType:     ptrs.Ptr(slotType),
+		}).Exec(ctx)
+		require.NoError(t, err)
+	}
+	return task
+}
+
+func TestGetRawResourceAllocationTasksEstimateSlotType(t *testing.T) {
+	api, _, _ := setupAPITest(t, nil)
+
+	// Tasks in a period of their own, so other tests' tasks aren't counted.
+	start := time.Date(2001, time.March, 1, 0, 0, 0, 0, time.UTC).
+		Add(time.Duration(time.Now().UnixNano()%1e6) * time.Minute)
+	requireTaskWithSlotTypes(t, api.m.db, start, "cuda")
+	requireTaskWithSlotTypes(t, api.m.db, start, "cuda", "cpu")
+	requireTaskWithSlotTypes(t, api.m.db, start, "cpu", "cpu")
+
+	estimate := func(slotType string) (int, error) {
+		target := fmt.Sprintf("/resources/allocation/tasks-raw/estimate?"+
+			"timestamp_after=%s&timestamp_before=%s&slot_type=%s",
+			start.Format("2006-01-02T15:04:05Z"),
+			start.Add(2*time.Hour).Format("2006-01-02T15:04:05Z"), slotType)
+		c := echo.New().NewContext(
+			httptest.NewRequest(http.MethodGet, target, nil), httptest.NewRecorder())
+		res, err := api.m.getRawResourceAllocationTasksEstimate(c)
+		if err != nil {
+			return 0, err
+		}
+		return res.(taskAllocationEstimate).Rows, nil
+	}
+
+	// Tasks backed by several types of devices only match the mixed slot type, as in the export.
+	for slotType, rows := range map[string]int{"cuda": 1, "cpu": 1, "mixed": 1, "rocm": 0} {
+		actual, err := estimate(slotType)
+		require.NoError(t, err, slotType)
+		require.Equal(t, rows, actual, slotType)
+	}
+
+	_, err := estimate("")
+	require.ErrorContains(t, err, "invalid slot_type")
+}
+
+func TestGetRawResourceAllocationTasksCheckpointingTime(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	trial := createTestTrial(t, api, curUser)
+	start := trial.StartTime.UTC()
+	allocationID := model.AllocationID(string(trial.TaskID) + ".0")
+	require.NoError(t, api.m.db.AddAllocation(&model.Allocation{
+		AllocationID: allocationID,
+		TaskID:       trial.TaskID,
+		Slots:        1,
+		ResourcePool: "default",
+		StartTime:    ptrs.Ptr(start),
+	}))
+
+	metadata, err := structpb.NewStruct(map[string]any{"steps_completed": 1})
+	require.NoError(t, err)
+	_, err = api.ReportCheckpoint(ctx, &apiv1.ReportCheckpointRequest{
+		Checkpoint: &checkpointv1.Checkpoint{
+			Uuid:         uuid.New().String(),
+			TaskId:       string(trial.TaskID),
+			AllocationId: string(allocationID),
+			ReportTime:   timestamppb.New(start.Add(30 * time.Minute)),
+			Metadata:     metadata,
+			State:        checkpointv1.State_STATE_COMPLETED,
+		},
+	})
+	require.NoError(t, err)
+
+	target := fmt.Sprintf("/resources/allocation/tasks-raw?timestamp_after=%s&timestamp_before=%s",
+		start.Add(-time.Hour).Format("2006-01-02T15:04:05Z"),
+		start.Add(time.Hour).Format("2006-01-02T15:04:05Z"))
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)
+	require.NoError(t, api.m.getRawResourceAllocationTasks(c))
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	column := map[string]int{}
+	for i, name := range records[0] {
+		column[name] = i
+	}
+	var found bool
+	for _, record := range records[1:] {
+		if record[column["task_id"]] == string(trial.TaskID) {
+			found = true
+			// The checkpoint took from the start of the trial until it was reported.
+			require.Equal(t, "1800.000000", record[column["checkpointing_time"]])
+		}
+	}
+	require.True(t, found)
+}
+
+func TestAllocationExportsMaxExportDuration(t *testing.T) {
+	api, _, _ := setupAPITest(t, nil)
+	// Every query runs past a limit this short.
+	api.m.config.ResourceAllocation.MaxExportDuration = model.Duration(time.Nanosecond)
+
+	raw := "/resources/allocation/raw?" +
+		"timestamp_after=2023-01-01T00:00:00Z&timestamp_before=2023-01-02T00:00:00Z"
+	aggregated := "/resources/allocation/aggregated?start_date=2023-01-01&end_date=2023-01-02&" +
+		"period=RESOURCE_ALLOCATION_AGGREGATION_PERIOD_DAILY"
+	for target, handler := range map[string]echo.HandlerFunc{
+		raw:        api.m.getRawResourceAllocation,
+		aggregated: api.m.getAggregatedResourceAllocation,
+		// Aggregates in other time zones are computed by a query of their own.
+		aggregated + "&tz=America/New_York": api.m.getAggregatedResourceAllocation,
+	} {
+		c := echo.New().NewContext(
+			httptest.NewRequest(http.MethodGet, target, nil), httptest.NewRecorder())
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, handler(c), &httpErr, target)
+		require.Equal(t, http.StatusGatewayTimeout, httpErr.Code, target)
+	}
+}
+
+func TestGetRawResourceAllocationNames(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	start := time.Now().UTC().Add(-time.Hour)
+
+	metrics, err := structpb.NewStruct(map[string]any{"loss": 1})
+	require.NoError(t, err)
+	expIDs := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		trial := createTestTrial(t, api, curUser)
+		require.NoError(t, api.m.db.AddTrainingMetrics(ctx, &trialv1.TrialMetrics{
+			TrialId:        int32(trial.ID),
+			StepsCompleted: 10,
+			Metrics:        &commonv1.Metrics{AvgMetrics: metrics},
+		}))
+		expIDs[fmt.Sprint(trial.ExperimentID)] = true
+	}
+
+	target := fmt.Sprintf("/resources/allocation/raw?"+
+		"timestamp_after=%s&timestamp_before=%s&include_names=true",
+		start.Format("2006-01-02T15:04:05Z"),
+		start.Add(2*time.Hour).Format("2006-01-02T15:04:05Z"))
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)
+	require.NoError(t, api.m.getRawResourceAllocation(c))
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"experiment_id", "kind", "username", "labels", "slots", "start_time", "end_time", "seconds",
+		"experiment_name", "project_name", "workspace_name",
+	}, records[0])
+	found := map[string]bool{}
+	for _, record := range records[1:] {
+		if expIDs[record[0]] {
+			found[record[0]] = true
+			require.Equal(t, "training", record[1])
+			require.Equal(t, curUser.Username, record[2])
+			require.Equal(t, []string{"name", "Uncategorized", "Uncategorized"}, record[8:])
+		}
+	}
+	require.Equal(t, expIDs, found)
+}
+
+func TestGetZonedAggregatedAllocation(t *testing.T) {
+	api, curUser, _ := setupAPITest(t, nil)
+	ctx := context.Background()
+
+	// Allocations are aggregated regardless of their tasks' times, so they are removed afterwards
+	// to keep reruns from counting them twice.
+	task := db.RequireMockTask(t, api.m.db, &curUser.ID)
+	t.Cleanup(func() {
+		_, err := db.Bun().NewDelete().Table("allocations").
+			Where("task_id = ?", task.TaskID).Exec(ctx)
+		require.NoError(t, err)
+	})
+	allocate := func(start, end string, slots int) {
+		startTime, err := time.Parse(time.RFC3339, start)
+		require.NoError(t, err)
+		endTime, err := time.Parse(time.RFC3339, end)
+		require.NoError(t, err)
+		_, err = db.Bun().NewInsert().Model(&model.Allocation{
+			AllocationID: model.AllocationID(fmt.Sprintf("%s.%s", task.TaskID, start)),
+			TaskID:       task.TaskID,
+			Slots:        slots,
+			ResourcePool: "zoned",
+			StartTime:    ptrs.Ptr(startTime),
+			EndTime:      ptrs.Ptr(endTime),
+		}).Exec(ctx)
+		require.NoError(t, err)
+	}
+
+	// New York's clocks went forward on April 2nd 1995 and back on October 29th, making those
+	// local days 23 and 25 hours long.
+	allocate("1995-04-01T23:00:00-05:00", "1995-04-02T01:00:00-05:00", 2)
+	allocate("1995-04-02T00:00:00-05:00", "1995-04-03T00:00:00-04:00", 1)
+	allocate("1995-10-29T00:00:00-04:00", "1995-10-30T00:00:00-05:00", 1)
+	// Kolkata is five and a half hours ahead of UTC, so this spans its local midnight on June 1st.
+	allocate("1995-05-31T18:00:00Z", "1995-05-31T19:00:00Z", 1)
+
+	aggregate := func(
+		period masterv1.ResourceAllocationAggregationPeriod, start, end, zone string,
+	) map[string]float32 {
+		loc, err := time.LoadLocation(zone)
+		require.NoError(t, err)
+		resp, err := api.m.fetchAggregatedResourceAllocation(context.Background(),
+			&apiv1.ResourceAllocationAggregatedRequest{
+				StartDate: start, EndDate: end, Period: period,
+			}, false, loc)
+		require.NoError(t, err)
+		seconds := map[string]float32{}
+		for _, entry := range resp.ResourceEntries {
+			seconds[entry.PeriodStart] = entry.Seconds
+			require.Equal(t, entry.Seconds, entry.ByUsername[curUser.Username])
+			require.Equal(t, entry.Seconds, entry.ByResourcePool["zoned"])
+		}
+		return seconds
+	}
+
+	daily := masterv1.
+		ResourceAllocationAggregationPeriod_RESOURCE_ALLOCATION_AGGREGATION_PERIOD_DAILY
+	require.Equal(t, map[string]float32{
+		"1995-04-01": 2 * 3600,
+		"1995-04-02": 2*3600 + 23*3600,
+	}, aggregate(daily, "1995-04-01", "1995-04-03", "America/New_York"))
+	require.Equal(t, map[string]float32{
+		"1995-10-29": 25 * 3600,
+	}, aggregate(daily, "1995-10-28", "1995-10-30", "America/New_York"))
+
+	monthly := masterv1.
+		ResourceAllocationAggregationPeriod_RESOURCE_ALLOCATION_AGGREGATION_PERIOD_MONTHLY
+	require.Equal(t, map[string]float32{
+		"1995-05": 1800,
+		"1995-06": 1800,
+	}, aggregate(monthly, "1995-05", "1995-06", "Asia/Kolkata"))
+}
+
+func TestPostCloseAllocation(t *testing.T) {
+	api, curUser, _ := setupAPITest(t, nil)
+	allocationmap.InitAllocationMap()
+	trial := createTestTrial(t, api, curUser)
+
+	closeAllocation := func(id model.AllocationID) error {
+		c := echo.New().NewContext(
+			httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		c.SetParamNames("allocation_id")
+		c.SetParamValues(string(id))
+		_, err := api.m.postCloseAllocation(c)
+		return err
+	}
+	requireStatus := func(err error, code int) {
+		var httpErr *echo.HTTPError
+		require.ErrorAs(t, err, &httpErr)
+		require.Equal(t, code, httpErr.Code)
+	}
+	addAllocation := func(id model.AllocationID) {
+		require.NoError(t, api.m.db.AddAllocation(&model.Allocation{
+			AllocationID: id,
+			TaskID:       trial.TaskID,
+			Slots:        1,
+			ResourcePool: "default",
+			StartTime:    ptrs.Ptr(time.Now().UTC()),
+		}))
+	}
+
+	requireStatus(closeAllocation("missing"), http.StatusNotFound)
+
+	// An allocation whose actor still responds is left for its task to be killed instead.
+	active := model.AllocationID(string(trial.TaskID) + ".active")
+	addAllocation(active)
+	ref, _ := system.ActorOf(actor.Addr("allocations", active), actor.ActorFunc(
+		func(ctx *actor.Context) error {
+			if _, ok := ctx.Message().(task.AllocationState); ok {
+				ctx.Respond(task.AllocationState{})
+			}
+			return nil
+		}))
+	allocationmap.RegisterAllocation(active, ref)
+	defer func() {
+		allocationmap.UnregisterAllocation(active)
+		ref.Stop()
+	}()
+	requireStatus(closeAllocation(active), http.StatusConflict)
+	allocation, err := api.m.db.AllocationByID(active)
+	require.NoError(t, err)
+	require.Nil(t, allocation.EndTime)
+
+	// An allocation without an actor is closed, once.
+	orphaned := model.AllocationID(string(trial.TaskID) + ".orphaned")
+	addAllocation(orphaned)
+	require.NoError(t, closeAllocation(orphaned))
+	allocation, err = api.m.db.AllocationByID(orphaned)
+	require.NoError(t, err)
+	require.NotNil(t, allocation.EndTime)
+	requireStatus(closeAllocation(orphaned), http.StatusConflict)
+}
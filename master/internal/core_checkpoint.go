@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -19,6 +20,7 @@ import (
 	"github.com/determined-ai/determined/master/internal/api"
 	detContext "github.com/determined-ai/determined/master/internal/context"
 	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/ptrs"
 	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
 )
@@ -71,7 +73,12 @@ func (m *Master) getCheckpointStorageConfig(id uuid.UUID) (
 	if err != nil || checkpoint == nil {
 		return nil, err
 	}
+	return checkpointStorageConfig(checkpoint)
+}
 
+func checkpointStorageConfig(checkpoint *model.Checkpoint) (
+	*expconf.CheckpointStorageConfig, error,
+) {
 	bytes, err := json.Marshal(checkpoint.CheckpointTrainingMetadata.ExperimentConfig)
 	if err != nil {
 		return nil, err
@@ -127,31 +134,88 @@ func (m *Master) getCheckpointImpl(
 	return nil
 }
 
-//	@Summary	Get a checkpoint's contents in a tgz or zip file.
+// checkpointMetadataFields lists the fields of a checkpoint's JSON description, which callers may
+// select from with the `fields` query parameter.
+var checkpointMetadataFields = []string{
+	"uuid", "state", "report_time", "experiment_id", "trial_id",
+	"storage_type", "storage_location", "resources", "metadata",
+}
+
+// checkpointMetadata describes where a checkpoint is stored and what it contains, projected to the
+// given comma-separated fields, or all fields if none are given.
+func (m *Master) checkpointMetadata(id uuid.UUID, fields string) (map[string]interface{}, error) {
+	selected := checkpointMetadataFields
+	if fields != "" {
+		known := map[string]bool{}
+		for _, field := range checkpointMetadataFields {
+			known[field] = true
+		}
+		selected = nil
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if !known[field] {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+					"unknown field %q, must be one of: %s",
+					field, strings.Join(checkpointMetadataFields, ", ")))
+			}
+			selected = append(selected, field)
+		}
+	}
+
+	checkpoint, err := m.db.CheckpointByUUID(id)
+	switch {
+	case err != nil:
+		return nil, err
+	case checkpoint == nil:
+		return nil, echo.NewHTTPError(http.StatusNotFound,
+			fmt.Sprintf("checkpoint not found: %s", id.String()))
+	}
+	storageConfig, err := checkpointStorageConfig(checkpoint)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("unable to retrieve experiment config for checkpoint %s: %s",
+				id.String(), err.Error()))
+	}
+	storageType, storageLocation := checkpoints.StorageLocation(id.String(), storageConfig)
+
+	all := map[string]interface{}{
+		"uuid":             id.String(),
+		"state":            checkpoint.State,
+		"report_time":      checkpoint.ReportTime,
+		"experiment_id":    checkpoint.ExperimentID,
+		"trial_id":         checkpoint.TrialID,
+		"storage_type":     storageType,
+		"storage_location": storageLocation,
+		"resources":        checkpoint.Resources,
+		"metadata":         checkpoint.Metadata,
+	}
+	projected := make(map[string]interface{}, len(selected))
+	for _, field := range selected {
+		projected[field] = all[field]
+	}
+	return projected, nil
+}
+
+//	@Summary	Get a checkpoint's contents in a tgz or zip file, or its metadata as JSON.
 //	@Tags		Checkpoints
 //	@ID			get-checkpoint
 //	@Accept		json
-//	@Produce	application/gzip,application/zip
+//	@Produce	application/gzip,application/zip,application/json
 //	@Param		checkpoint_uuid	path	string	true	"Checkpoint UUID"
+//	@Param		fields			query	string	false	"Comma-separated metadata fields to return as JSON"
 //	@Success	200				{}		string	""
 //	@Router		/checkpoints/{checkpoint_uuid} [get]
 //
 // Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
 func (m *Master) getCheckpoint(c echo.Context) error {
-	// Get the MIME type. Only a single type is accepted.
-	mimeType := c.Request().Header.Get("Accept")
-	if mimeType != MIMEApplicationGZip &&
-		mimeType != MIMEApplicationZip {
-		return echo.NewHTTPError(http.StatusUnsupportedMediaType,
-			fmt.Sprintf("unsupported media type to download a checkpoint: '%s'", mimeType))
-	}
-
 	args := struct {
-		CheckpointUUID string `path:"checkpoint_uuid"`
+		CheckpointUUID string  `path:"checkpoint_uuid"`
+		Fields         *string `query:"fields"`
 	}{}
 	if err := api.BindArgs(&args, c); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid checkpoint_uuid: "+err.Error())
 	}
+	// Reject malformed UUIDs before touching the database.
 	id, err := uuid.Parse(args.CheckpointUUID)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest,
@@ -159,6 +223,15 @@ func (m *Master) getCheckpoint(c echo.Context) error {
 				args.CheckpointUUID, err))
 	}
 
+	// Get the MIME type. Only a single archive type is accepted; JSON returns metadata instead.
+	mimeType := c.Request().Header.Get("Accept")
+	asJSON := api.NegotiateFormat(c, "") == api.FormatJSON
+	if !asJSON && mimeType != MIMEApplicationGZip &&
+		mimeType != MIMEApplicationZip {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("unsupported media type to download a checkpoint: '%s'", mimeType))
+	}
+
 	curUser := c.(*detContext.DetContext).MustGetUser()
 	if err := m.canDoActionOnCheckpoint(c.Request().Context(), curUser, args.CheckpointUUID,
 		expauth.AuthZProvider.Get().CanGetExperimentArtifacts); err != nil {
@@ -176,6 +249,33 @@ func (m *Master) getCheckpoint(c echo.Context) error {
 		}
 	}
 
+	if asJSON {
+		fields := ""
+		if args.Fields != nil {
+			fields = *args.Fields
+		}
+		metadata, err := m.checkpointMetadata(id, fields)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, metadata)
+	}
+
+	// The archive type is negotiated, so caches must key on it too.
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	checkpoint, err := m.db.CheckpointByUUID(id)
+	if err != nil {
+		return err
+	}
+	// Completed checkpoints never change, so their archives can be cached for good, identified by
+	// the UUID. Others may still be uploading.
+	if checkpoint != nil && checkpoint.State == model.CompletedState {
+		etag := fmt.Sprintf("%s.%s", id, mimeToArchiveType(mimeType))
+		if api.CheckETag(c, etag, api.CacheControlImmutable) {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+
 	c.Response().Header().Set(echo.HeaderContentType, mimeType)
 	return m.getCheckpointImpl(c.Request().Context(), id, mimeType, c.Response())
 }
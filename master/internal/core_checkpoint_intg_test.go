@@ -8,6 +8,7 @@ import (
 	"archive/zip"
 	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -248,6 +249,33 @@ func TestGetCheckpointEchoExpErr(t *testing.T) {
 	}
 }
 
+func TestGetCheckpointMetadataEcho(t *testing.T) {
+	api, ctx, rec := setupCheckpointTestEcho(t)
+	id := uuid.New()
+	addMockCheckpointDB(t, api.m.db, id)
+
+	ctx.SetParamNames("checkpoint_uuid")
+	ctx.SetParamValues(id.String())
+	ctx.SetRequest(httptest.NewRequest(
+		http.MethodGet, "/?fields=storage_type,storage_location,resources", nil))
+	ctx.Request().Header.Set("Accept", echo.MIMEApplicationJSON)
+	require.NoError(t, api.m.getCheckpoint(ctx))
+
+	var metadata map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &metadata))
+	require.Len(t, metadata, 3)
+	require.Equal(t, "s3", metadata["storage_type"])
+	require.Equal(t,
+		fmt.Sprintf("s3://%s/%s/%s", S3TestBucket, S3TestPrefix, id), metadata["storage_location"])
+	require.Contains(t, metadata, "resources")
+
+	ctx.SetRequest(httptest.NewRequest(http.MethodGet, "/?fields=uuid,bogus", nil))
+	ctx.Request().Header.Set("Accept", echo.MIMEApplicationJSON)
+	err := api.m.getCheckpoint(ctx)
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+}
+
 func TestAuthZCheckpointsEcho(t *testing.T) {
 	api, authZExp, _, curUser, _ := setupExpAuthTest(t, nil)
 	ctx := newTestEchoContext(curUser)
@@ -7,6 +7,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
@@ -16,8 +19,11 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
 
 	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/cache"
+	"github.com/determined-ai/determined/master/internal/config"
 	detContext "github.com/determined-ai/determined/master/internal/context"
 	"github.com/determined-ai/determined/master/internal/db"
 	expauth "github.com/determined-ai/determined/master/internal/experiment"
@@ -26,6 +32,8 @@ import (
 	"github.com/determined-ai/determined/master/internal/user"
 	"github.com/determined-ai/determined/master/pkg/actor"
 	"github.com/determined-ai/determined/master/pkg/archive"
+	"github.com/determined-ai/determined/master/pkg/checkpoints"
+	"github.com/determined-ai/determined/master/pkg/command"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/schemas"
 	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
@@ -105,7 +113,11 @@ func echoGetExperimentAndCheckCanDoActions(ctx context.Context, c echo.Context,
 	return e, user, nil
 }
 
-func (m *Master) getExperimentCheckpointsToGC(c echo.Context) (interface{}, error) {
+// experimentCheckpointsToGC returns the experiment and the checkpoints that garbage collection
+// would delete from it with the requested retention policy.
+func (m *Master) experimentCheckpointsToGC(
+	c echo.Context,
+) (*model.Experiment, []model.Checkpoint, error) {
 	args := struct {
 		ExperimentID   int `path:"experiment_id"`
 		ExperimentBest int `query:"save_experiment_best"`
@@ -113,22 +125,39 @@ func (m *Master) getExperimentCheckpointsToGC(c echo.Context) (interface{}, erro
 		TrialLatest    int `query:"save_trial_latest"`
 	}{}
 	if err := api.BindArgs(&args, c); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	exp, _, err := echoGetExperimentAndCheckCanDoActions(
 		c.Request().Context(), c, m, args.ExperimentID,
 		expauth.AuthZProvider.Get().CanGetExperimentArtifacts,
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	checkpointUUIDs, err := m.db.ExperimentCheckpointsToGCRaw(
+	checkpointsDB, err := m.checkpointsToGC(
 		args.ExperimentID, args.ExperimentBest, args.TrialBest, args.TrialLatest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return exp, checkpointsDB, nil
+}
+
+// checkpointsToGC returns the checkpoints of an experiment that garbage collection would delete
+// with the given retention policy.
+func (m *Master) checkpointsToGC(
+	expID, experimentBest, trialBest, trialLatest int,
+) ([]model.Checkpoint, error) {
+	checkpointUUIDs, err := m.db.ExperimentCheckpointsToGCRaw(
+		expID, experimentBest, trialBest, trialLatest)
 	if err != nil {
 		return nil, err
 	}
-	checkpointsDB, err := m.db.CheckpointByUUIDs(checkpointUUIDs)
+	return m.db.CheckpointByUUIDs(checkpointUUIDs)
+}
+
+func (m *Master) getExperimentCheckpointsToGC(c echo.Context) (interface{}, error) {
+	exp, checkpointsDB, err := m.experimentCheckpointsToGC(c)
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +169,91 @@ func (m *Master) getExperimentCheckpointsToGC(c echo.Context) (interface{}, erro
 	return checkpointsWithMetric, nil
 }
 
+// getExperimentCheckpointsToGCCSV previews garbage collection like getExperimentCheckpointsToGC,
+// but as a CSV with a row per checkpoint giving its size and location, followed by a row totaling
+// their sizes.
+func (m *Master) getExperimentCheckpointsToGCCSV(c echo.Context) error {
+	exp, candidates, err := m.experimentCheckpointsToGC(c)
+	if err != nil {
+		return err
+	}
+	csvOptions, err := api.ParseCSVOptions(c)
+	if err != nil {
+		return err
+	}
+	storageConfig := &exp.Config.CheckpointStorage
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	csvWriter := csvOptions.NewWriter(c.Response())
+	if err := csvWriter.Write([]string{
+		"uuid", "trial_id", "steps_completed", "size_bytes", "storage_type", "storage_location",
+	}); err != nil {
+		return err
+	}
+
+	var total int64
+	for _, ckpt := range candidates {
+		if ckpt.UUID == nil {
+			continue
+		}
+		size := checkpointSize(ckpt.Resources)
+		total += size
+		storageType, storageLocation := checkpoints.StorageLocation(ckpt.UUID.String(), storageConfig)
+		if err := csvWriter.Write([]string{
+			ckpt.UUID.String(),
+			strconv.Itoa(ckpt.TrialID),
+			strconv.Itoa(ckpt.StepsCompleted),
+			strconv.FormatInt(size, 10),
+			storageType,
+			storageLocation,
+		}); err != nil {
+			return err
+		}
+	}
+	if err := csvWriter.Write([]string{
+		"total", "", "", strconv.FormatInt(total, 10), "", "",
+	}); err != nil {
+		return err
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// checkpointSize returns the total size in bytes of the files in a checkpoint's resources.
+func checkpointSize(resources model.JSONObj) int64 {
+	var size int64
+	for _, fileSize := range resources {
+		if bytes, ok := fileSize.(float64); ok {
+			size += int64(bytes)
+		}
+	}
+	return size
+}
+
+// getExperimentStateHistory returns the state transitions recorded for an experiment, oldest
+// first. Transitions are recorded by the database whenever an experiment's state changes,
+// including those made while restoring experiments on master startup.
+func (m *Master) getExperimentStateHistory(c echo.Context) (interface{}, error) {
+	args := struct {
+		ExperimentID int `path:"experiment_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	if _, _, err := echoGetExperimentAndCheckCanDoActions(
+		ctx, c, m, args.ExperimentID, expauth.AuthZProvider.Get().CanGetExperimentArtifacts,
+	); err != nil {
+		return nil, err
+	}
+
+	history, err := m.db.ExperimentStateHistory(ctx, args.ExperimentID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"state_history": history}, nil
+}
+
 //	@Summary	Get individual file from modal definitions for download.
 //	@Tags		Experiments
 //	@ID			get-experiment-model-file
@@ -168,9 +282,15 @@ func (m *Master) getExperimentModelFile(c echo.Context) error {
 
 	modelDefCache := GetModelDefCache()
 	file, err := modelDefCache.FileContent(args.ExperimentID, args.Path)
-	if err != nil {
+	if errors.Is(err, cache.ErrInvalidPath) {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	} else if err != nil {
 		return err
 	}
+	// Experiment files are revalidated on each use, since they aren't guaranteed never to change.
+	if api.CheckETag(c, api.ContentETag(file), api.CacheControlRevalidate) {
+		return c.NoContent(http.StatusNotModified)
+	}
 	c.Response().Header().Set(
 		"Content-Disposition",
 		fmt.Sprintf(
@@ -198,6 +318,9 @@ func (m *Master) getExperimentModelDefinition(c echo.Context) error {
 	if err != nil {
 		return err
 	}
+	if api.CheckETag(c, api.ContentETag(modelDef), api.CacheControlRevalidate) {
+		return c.NoContent(http.StatusNotModified)
+	}
 
 	var cleanName string
 
@@ -226,6 +349,74 @@ func (m *Master) getExperimentModelDefinition(c echo.Context) error {
 	return c.Blob(http.StatusOK, "application/x-gtar", modelDef)
 }
 
+// experimentDeletions tracks the experiments this master is deleting right now.
+type experimentDeletions struct {
+	mu  sync.Mutex
+	ids map[int]bool
+}
+
+func (d *experimentDeletions) start(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ids == nil {
+		d.ids = map[int]bool{}
+	}
+	d.ids[id] = true
+}
+
+func (d *experimentDeletions) finish(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.ids, id)
+}
+
+func (d *experimentDeletions) inProgress() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make([]int, 0, len(d.ids))
+	for id := range d.ids {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// failDeletingExperiments runs the startup cleanup for experiments stuck in the DELETING state on
+// demand, either for a single experiment or, if no experiment ID is given, for all of them.
+// Experiments this master is still deleting are left alone, since only deletions that were cut
+// short, such as by a crash, are stuck.
+func (m *Master) failDeletingExperiments(c echo.Context) (interface{}, error) {
+	args := struct {
+		ExperimentID *int `path:"experiment_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	inProgress := m.deletions.inProgress()
+	if args.ExperimentID != nil {
+		if slices.Contains(inProgress, *args.ExperimentID) {
+			return nil, echo.NewHTTPError(http.StatusConflict,
+				fmt.Sprintf("experiment %d is still being deleted", *args.ExperimentID))
+		}
+		ids = append(ids, *args.ExperimentID)
+	}
+	failed, err := m.db.FailDeletingExperiments(c.Request().Context(), ids, inProgress)
+	if err != nil {
+		return nil, err
+	}
+	if args.ExperimentID != nil && len(failed) == 0 {
+		if _, err := m.db.ExperimentByID(*args.ExperimentID); err != nil {
+			return nil, err
+		}
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("experiment %d is not being deleted", *args.ExperimentID))
+	}
+	return struct {
+		ExperimentIDs []int `json:"experiment_ids"`
+	}{ExperimentIDs: failed}, nil
+}
+
 func (m *Master) patchExperiment(c echo.Context) (interface{}, error) {
 	// Allow clients to apply partial updates to an experiment via the JSON Merge Patch format
 	// (RFC 7386). Clients can only update certain fields of the experiment.
@@ -445,6 +636,42 @@ func getCreateExperimentsProject(
 	return p, nil
 }
 
+// normalizeExperimentLabels applies the master's label rules to an experiment's labels, dropping
+// labels that normalize to the empty string.
+func normalizeExperimentLabels(
+	labels expconf.LabelsV0, rules config.ExperimentLabelsConfig,
+) (expconf.LabelsV0, error) {
+	if labels == nil {
+		return nil, nil
+	}
+	var disallowed *regexp.Regexp
+	if rules.DisallowedPattern != "" {
+		var err error
+		if disallowed, err = regexp.Compile(rules.DisallowedPattern); err != nil {
+			return nil, errors.Wrap(err, "invalid disallowed label pattern")
+		}
+	}
+
+	normalized := expconf.LabelsV0{}
+	for label := range labels {
+		if rules.TrimWhitespace {
+			label = strings.TrimSpace(label)
+		}
+		if rules.Lowercase {
+			label = strings.ToLower(label)
+		}
+		if label == "" {
+			continue
+		}
+		if disallowed != nil && disallowed.MatchString(label) {
+			return nil, errors.Errorf("label %q matches disallowed pattern %q",
+				label, rules.DisallowedPattern)
+		}
+		normalized[label] = true
+	}
+	return normalized, nil
+}
+
 func (m *Master) parseCreateExperiment(params *CreateExperimentParams, user *model.User) (
 	*model.Experiment, expconf.ExperimentConfig, *projectv1.Project, bool, *tasks.TaskSpec, error,
 ) {
@@ -528,6 +755,12 @@ func (m *Master) parseCreateExperiment(params *CreateExperimentParams, user *mod
 		return nil, config, nil, false, nil, errors.Wrap(err, "invalid experiment configuration")
 	}
 
+	labels, err := normalizeExperimentLabels(config.Labels(), m.config.ExperimentLabels)
+	if err != nil {
+		return nil, config, nil, false, nil, errors.Wrap(err, "invalid experiment configuration")
+	}
+	config.SetLabels(labels)
+
 	var modelBytes []byte
 	if params.ParentID != nil {
 		var dbErr error
@@ -566,6 +799,22 @@ func (m *Master) parseCreateExperiment(params *CreateExperimentParams, user *mod
 	return dbExp, config, project, params.ValidateOnly, &taskSpec, err
 }
 
+// existingExperimentDescriptor describes an experiment that was already created, in the same form
+// as the response to creating it.
+func (m *Master) existingExperimentDescriptor(c echo.Context, id int) (interface{}, error) {
+	activeConfig, err := m.db.ActiveExperimentConfig(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading config of experiment %d", id)
+	}
+	c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/experiments/%v", id))
+	return model.ExperimentDescriptor{
+		ID:       id,
+		Config:   activeConfig,
+		Labels:   make([]string, 0),
+		Warnings: []command.LaunchWarning{},
+	}, nil
+}
+
 func (m *Master) postExperiment(c echo.Context) (interface{}, error) {
 	body, err := ioutil.ReadAll(c.Request().Body)
 	if err != nil {
@@ -579,6 +828,21 @@ func (m *Master) postExperiment(c echo.Context) (interface{}, error) {
 		return nil, errors.Wrap(err, "invalid experiment params")
 	}
 	ctx := c.Request().Context()
+
+	// Automation may resubmit an experiment on retry; with an idempotency key, a repeat within the
+	// window returns the experiment created the first time.
+	idempotencyKey := c.Request().Header.Get(api.HeaderIdempotencyKey)
+	idempotencyWindow := time.Duration(m.config.ExperimentIdempotency.Window)
+	useIdempotencyKey := idempotencyKey != "" && idempotencyWindow > 0 && !params.ValidateOnly
+	if useIdempotencyKey {
+		id, err := m.db.ExperimentByIdempotencyKey(ctx, user.ID, idempotencyKey, idempotencyWindow)
+		if err != nil {
+			return nil, err
+		}
+		if id != nil {
+			return m.existingExperimentDescriptor(c, *id)
+		}
+	}
 	if params.ParentID != nil {
 		if _, _, err = echoGetExperimentAndCheckCanDoActions(ctx, c, m, *params.ParentID,
 			expauth.AuthZProvider.Get().CanForkFromExperiment); err != nil {
@@ -609,7 +873,18 @@ func (m *Master) postExperiment(c echo.Context) (interface{}, error) {
 		}
 	}
 
-	e, launchWarnings, err := newExperiment(m, dbExp, activeConf, taskSpec)
+	var key *db.ExperimentIdempotencyKey
+	if useIdempotencyKey {
+		key = &db.ExperimentIdempotencyKey{
+			UserID: user.ID, Key: idempotencyKey, Window: idempotencyWindow,
+		}
+	}
+	e, launchWarnings, err := newExperiment(m, dbExp, activeConf, taskSpec, key)
+	var keyUsed db.ErrIdempotencyKeyUsed
+	if errors.As(err, &keyUsed) {
+		// A concurrent request with the same key created the experiment first.
+		return m.existingExperimentDescriptor(c, keyUsed.ExperimentID)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "starting experiment")
 	}
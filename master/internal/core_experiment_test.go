@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+func TestNormalizeExperimentLabels(t *testing.T) {
+	labels := expconf.LabelsV0{" Team-A ": true, "team-a": true, "  ": true, "Vision": true}
+
+	// Without any rules, labels are kept as given.
+	normalized, err := normalizeExperimentLabels(labels, config.ExperimentLabelsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, expconf.LabelsV0{
+		" Team-A ": true, "team-a": true, "  ": true, "Vision": true,
+	}, normalized)
+
+	rules := config.ExperimentLabelsConfig{TrimWhitespace: true, Lowercase: true}
+	normalized, err = normalizeExperimentLabels(labels, rules)
+	require.NoError(t, err)
+	require.Equal(t, expconf.LabelsV0{"team-a": true, "vision": true}, normalized)
+
+	rules.DisallowedPattern = `^tmp-`
+	_, err = normalizeExperimentLabels(expconf.LabelsV0{"TMP-debug": true}, rules)
+	require.ErrorContains(t, err, "tmp-debug")
+}
+
+func TestCheckpointSize(t *testing.T) {
+	require.Equal(t, int64(0), checkpointSize(nil))
+	require.Equal(t, int64(1536), checkpointSize(model.JSONObj{
+		"model.pt": float64(1024), "metadata.json": float64(512), "code/": float64(0),
+	}))
+}
+
+func TestExperimentDeletions(t *testing.T) {
+	var d experimentDeletions
+	require.Empty(t, d.inProgress())
+
+	d.start(1)
+	d.start(2)
+	require.ElementsMatch(t, []int{1, 2}, d.inProgress())
+
+	d.finish(1)
+	require.Equal(t, []int{2}, d.inProgress())
+}
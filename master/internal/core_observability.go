@@ -2,10 +2,18 @@ package internal
 
 import (
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
+	detContext "github.com/determined-ai/determined/master/internal/context"
 	"github.com/determined-ai/determined/master/internal/prom"
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
 )
@@ -36,3 +44,50 @@ func (m *Master) getPrometheusTargets(c echo.Context) (interface{}, error) {
 
 	return agentTargetsConfig, nil
 }
+
+// capturedProfiles are the runtime profiles written by captureProfiles.
+var capturedProfiles = []string{"heap", "goroutine"}
+
+// captureProfiles writes heap and goroutine profiles to the configured profile capture directory
+// and returns the paths of the written files. Only admins may capture profiles.
+func (m *Master) captureProfiles(c echo.Context) (interface{}, error) {
+	if !c.(*detContext.DetContext).MustGetUser().Admin {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "only admins may capture profiles")
+	}
+
+	dir := m.config.Observability.ProfileCaptureDir
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrapf(err, "creating profile directory %s", dir)
+	}
+
+	timestamp := time.Now().UTC().Format("20060102T150405Z")
+	var paths []string
+	for _, name := range capturedProfiles {
+		path := filepath.Join(dir, fmt.Sprintf("%s-%s.pb.gz", name, timestamp))
+		if err := writeProfile(name, path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	log.Infof("captured profiles: %s", strings.Join(paths, ", "))
+
+	return struct {
+		Paths []string `json:"paths"`
+	}{Paths: paths}, nil
+}
+
+func writeProfile(name, path string) error {
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		return errors.Errorf("unknown profile %s", name)
+	}
+	f, err := os.Create(path) //nolint:gosec // The path is built from trusted configuration.
+	if err != nil {
+		return errors.Wrapf(err, "creating %s profile", name)
+	}
+	defer closeWithErrCheck(path, f)
+	if err := profile.WriteTo(f, 0); err != nil {
+		return errors.Wrapf(err, "writing %s profile", name)
+	}
+	return nil
+}
@@ -1,7 +1,11 @@
 package internal
 
 import (
+	"context"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
@@ -48,9 +52,25 @@ func (m *Master) getSearcherPreview(c echo.Context) (interface{}, error) {
 		return nil, errors.Wrap(err, "invalid experiment configuration")
 	}
 
+	ctx := c.Request().Context()
+	if timeout := time.Duration(m.config.SearcherPreview.Timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	sm := searcher.NewSearchMethod(sc)
 	s := searcher.NewSearcher(0, sm, hc)
-	return searcher.Simulate(s, nil, searcher.RandomValidation, true, config.Searcher().Metric())
+	sim, err := searcher.SimulateWithLimits(
+		ctx, m.config.SearcherPreview.MaxTrials,
+		s, nil, searcher.RandomValidation, true, config.Searcher().Metric(),
+	)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, echo.NewHTTPError(http.StatusGatewayTimeout,
+			fmt.Sprintf("searcher preview did not finish within %s",
+				time.Duration(m.config.SearcherPreview.Timeout)))
+	}
+	return sim, err
 }
 
 // cleanUpExperimentSnapshots deletes all snapshots for terminal state experiments from
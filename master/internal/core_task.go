@@ -1,13 +1,29 @@
 package internal
 
 import (
+	"sync"
+	"time"
+
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
 
 	"github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
 	expauth "github.com/determined-ai/determined/master/internal/experiment"
 	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/model"
 )
 
+// taskSummaryTTL is how long a summary of active tasks is reused, so that dashboards polling it
+// frequently don't each query the database.
+const taskSummaryTTL = 5 * time.Second
+
+var taskSummaryCache struct {
+	mu        sync.Mutex
+	counts    map[model.TaskType]int
+	fetchedAt time.Time
+}
+
 func (m *Master) getTasks(c echo.Context) (interface{}, error) {
 	summary, err := m.rm.GetAllocationSummaries(m.system, sproto.GetAllocationSummaries{})
 	if err != nil {
@@ -38,3 +54,41 @@ func (m *Master) getTasks(c echo.Context) (interface{}, error) {
 	}
 	return summary, nil
 }
+
+// getTaskSummary returns the number of currently active tasks of each type.
+func (m *Master) getTaskSummary(c echo.Context) (interface{}, error) {
+	taskSummaryCache.mu.Lock()
+	defer taskSummaryCache.mu.Unlock()
+	if taskSummaryCache.counts != nil && time.Since(taskSummaryCache.fetchedAt) < taskSummaryTTL {
+		return taskSummaryCache.counts, nil
+	}
+
+	var rows []struct {
+		TaskType model.TaskType
+		Count    int
+	}
+	if err := db.Bun().NewSelect().
+		ColumnExpr("t.task_type").
+		ColumnExpr("count(DISTINCT t.task_id) AS count").
+		TableExpr("tasks t").
+		Join("INNER JOIN allocations a ON t.task_id = a.task_id").
+		Where("a.end_time IS NULL").
+		Group("t.task_type").
+		Scan(c.Request().Context(), &rows); err != nil {
+		return nil, errors.Wrap(err, "counting active tasks")
+	}
+
+	counts := map[model.TaskType]int{
+		model.TaskTypeTrial:        0,
+		model.TaskTypeNotebook:     0,
+		model.TaskTypeShell:        0,
+		model.TaskTypeCommand:      0,
+		model.TaskTypeTensorboard:  0,
+		model.TaskTypeCheckpointGC: 0,
+	}
+	for _, row := range rows {
+		counts[row.TaskType] = row.Count
+	}
+	taskSummaryCache.counts, taskSummaryCache.fetchedAt = counts, time.Now()
+	return counts, nil
+}
@@ -0,0 +1,718 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/config"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/task"
+	"github.com/determined-ai/determined/master/pkg/aproto"
+	"github.com/determined-ai/determined/master/pkg/logger"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestSelectTaskAllocationColumns(t *testing.T) {
+	columns, err := selectTaskAllocationColumns("slots, task_id,username", taskAllocationColumns)
+	require.NoError(t, err)
+	var names []string
+	for _, column := range columns {
+		names = append(names, column.name)
+	}
+	require.Equal(t, []string{"slots", "task_id", "username"}, names)
+
+	_, err = selectTaskAllocationColumns("task_id,not_a_column", taskAllocationColumns)
+	require.ErrorContains(t, err, "not_a_column")
+
+	_, err = selectTaskAllocationColumns("", taskAllocationColumns)
+	require.Error(t, err)
+
+	// The cost is only available when a rate card is configured.
+	_, err = selectTaskAllocationColumns("task_id,cost", availableTaskAllocationColumns(nil))
+	require.ErrorContains(t, err, "cost")
+	columns, err = selectTaskAllocationColumns(
+		"task_id,cost", availableTaskAllocationColumns(&config.RateCardConfig{Default: 1}),
+	)
+	require.NoError(t, err)
+	require.Len(t, columns, 2)
+}
+
+func TestParseTaskAllocationCursor(t *testing.T) {
+	cursor, err := parseTaskAllocationCursor(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, cursor)
+
+	startTime, taskID := "2023-03-01T12:00:00.123456Z", "1.abc"
+	cursor, err = parseTaskAllocationCursor(&startTime, &taskID)
+	require.NoError(t, err)
+	require.Equal(t, model.TaskID(taskID), cursor.taskID)
+	// The cursor is taken from the start_time column, so it must round-trip exactly.
+	require.Equal(t, startTime, formatTaskTimestamp(cursor.startTime))
+
+	_, err = parseTaskAllocationCursor(&startTime, nil)
+	require.Error(t, err)
+	_, err = parseTaskAllocationCursor(nil, &taskID)
+	require.Error(t, err)
+
+	notATime := "yesterday"
+	_, err = parseTaskAllocationCursor(&notATime, &taskID)
+	require.ErrorContains(t, err, "after_start_time")
+}
+
+func TestParseTaskSlotType(t *testing.T) {
+	slotType, err := parseTaskSlotType(nil)
+	require.NoError(t, err)
+	require.Empty(t, slotType)
+
+	for _, valid := range []string{"cpu", "cuda", "rocm", "mixed"} {
+		slotType, err = parseTaskSlotType(&valid)
+		require.NoError(t, err)
+		require.Equal(t, valid, slotType)
+	}
+
+	// An empty slot type would select every task, so it must be left out instead.
+	for _, invalid := range []string{"", "gpu", "CUDA"} {
+		_, err = parseTaskSlotType(&invalid)
+		require.ErrorContains(t, err, "invalid slot_type", invalid)
+	}
+}
+
+func TestParseTaskAllocationArgsProject(t *testing.T) {
+	m := &Master{config: config.DefaultConfig()}
+	parse := func(query string) (*taskAllocationArgs, error) {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet,
+			"/resources/allocation/tasks-raw?timestamp_after=2023-01-01T00:00:00Z&"+
+				"timestamp_before=2023-01-02T00:00:00Z&"+query, nil), httptest.NewRecorder())
+		return m.parseTaskAllocationArgs(c)
+	}
+
+	// Projects in different workspaces may share a name, so the workspace must be given too.
+	_, err := parse("project=p")
+	require.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+
+	args, err := parse("workspace=w&project=p")
+	require.NoError(t, err)
+	require.Equal(t, "w", args.workspace)
+	require.Equal(t, "p", args.project)
+	args, err = parse("workspace=w")
+	require.NoError(t, err)
+	require.Equal(t, "w", args.workspace)
+	require.Empty(t, args.project)
+}
+
+func TestValidateAllocationRange(t *testing.T) {
+	m := &Master{config: config.DefaultConfig()}
+	now := time.Now().UTC()
+
+	// The check is disabled by default.
+	require.NoError(t, m.validateAllocationRange(now, now.AddDate(1, 0, 0)))
+
+	m.config.ResourceAllocation.MaxClockSkew = model.Duration(time.Hour)
+	require.NoError(t, m.validateAllocationRange(now.Add(-time.Hour), now))
+
+	for _, tc := range []struct {
+		start, end time.Time
+		code       string
+	}{
+		{now, now.Add(-time.Hour), errCodeInvalidTimeRange},
+		{now, now.Add(2 * time.Hour), errCodeInvalidEndTime},
+	} {
+		err := m.validateAllocationRange(tc.start, tc.end)
+		var coded *api.CodedError
+		require.ErrorAs(t, err, &coded)
+		require.Equal(t, tc.code, coded.Code)
+		// The same error is returned by the gRPC endpoint.
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	loc, err := parseTimeZone("")
+	require.NoError(t, err)
+	require.Equal(t, time.UTC, loc)
+
+	loc, err = parseTimeZone("America/New_York")
+	require.NoError(t, err)
+	require.Equal(t, "America/New_York", loc.String())
+
+	for _, name := range []string{"Mars/Olympus_Mons", "Local"} {
+		_, err = parseTimeZone(name)
+		var coded *api.CodedError
+		require.ErrorAs(t, err, &coded, name)
+		require.Equal(t, errCodeInvalidTimeZone, coded.Code)
+		require.Equal(t, http.StatusBadRequest, coded.Status)
+	}
+}
+
+func TestInfoCapabilities(t *testing.T) {
+	m := &Master{config: config.DefaultConfig()}
+	require.Equal(t, aproto.CapabilitiesInfo{
+		LogBackend: aproto.LogBackendPostgres,
+	}, m.Info().Capabilities)
+
+	m.config.Observability.EnablePrometheus = true
+	m.config.InternalConfig.AuditLoggingEnabled = true
+	m.config.Logging = model.LoggingConfig{ElasticLoggingConfig: &model.ElasticLoggingConfig{}}
+	require.Equal(t, aproto.CapabilitiesInfo{
+		PrometheusEnabled:   true,
+		AuditLoggingEnabled: true,
+		LogBackend:          aproto.LogBackendElastic,
+	}, m.Info().Capabilities)
+}
+
+func TestJoinLabels(t *testing.T) {
+	labels := []string{"plain", "a,b", `back\slash`, `say "hi"`}
+
+	escaped, err := joinLabels(labels, api.CSVQuotingEscape)
+	require.NoError(t, err)
+	require.Equal(t, `plain,a\,b,back\\slash,say "hi"`, escaped)
+
+	quoted, err := joinLabels(labels, api.CSVQuotingRFC4180)
+	require.NoError(t, err)
+	require.Equal(t, `plain,"a,b",back\slash,"say ""hi"""`, quoted)
+
+	empty, err := joinLabels(nil, api.CSVQuotingRFC4180)
+	require.NoError(t, err)
+	require.Equal(t, "", empty)
+}
+
+func TestParseAggregationPeriods(t *testing.T) {
+	week, err := parseISOWeek("2023-W01")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.January, 2, 0, 0, 0, 0, time.UTC), week)
+
+	// ISO weeks can start in the previous calendar year.
+	week, err = parseISOWeek("2021-W01")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2021, time.January, 4, 0, 0, 0, 0, time.UTC), week)
+	week, err = parseISOWeek("2020-W53")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2020, time.December, 28, 0, 0, 0, 0, time.UTC), week)
+
+	for _, bad := range []string{"2021-W53", "2023-W00", "2023-05", "2023-W5", "2023-W05x"} {
+		_, err = parseISOWeek(bad)
+		require.Error(t, err, bad)
+	}
+
+	quarter, err := parseQuarter("2023-Q3")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC), quarter)
+
+	for _, bad := range []string{"2023-Q0", "2023-Q5", "2023-07", "2023-Q1x"} {
+		_, err = parseQuarter(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestHandleStreamedExportErrors(t *testing.T) {
+	e := echo.New()
+	failing := handleStreamedExportErrors(func(c echo.Context) error {
+		if c.QueryParam("started") != "" {
+			c.Response().WriteHeader(http.StatusOK)
+		}
+		return errors.New("write failed")
+	})
+
+	// Errors before the response starts are returned as usual.
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	require.Error(t, failing(e.NewContext(req, httptest.NewRecorder())))
+
+	// Errors once the response has started are only logged.
+	req = httptest.NewRequest(http.MethodGet, "/export?started=true", nil)
+	require.NoError(t, failing(e.NewContext(req, httptest.NewRecorder())))
+
+	// So are errors caused by the client going away.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req = httptest.NewRequest(http.MethodGet, "/export", nil).WithContext(ctx)
+	require.NoError(t, failing(e.NewContext(req, httptest.NewRecorder())))
+}
+
+func TestGzipSkipperProxiedRequests(t *testing.T) {
+	content := strings.Repeat("determined", 1024)
+	e := echo.New()
+	e.Use(api.Gzip(1024, gzipSkipper))
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, content)
+	}
+	e.GET("/proxy/:service/*", handler)
+	e.GET("/resources/allocation/tasks-raw", handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/resources/allocation/tasks-raw", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+
+	req = httptest.NewRequest(http.MethodGet, "/proxy/notebook/lab", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	require.Equal(t, content, rec.Body.String())
+}
+
+func TestGzipSkipperRangeRequests(t *testing.T) {
+	content := strings.Repeat("determined", 1024)
+	e := echo.New()
+	e.Use(api.Gzip(1024, gzipSkipper))
+	e.GET("/det/static/*", func(c echo.Context) error {
+		http.ServeContent(c.Response(), c.Request(), "main.js", time.Time{},
+			strings.NewReader(content))
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/det/static/main.js", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+
+	req = httptest.NewRequest(http.MethodGet, "/det/static/main.js", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	req.Header.Set("Range", "bytes=0-9")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusPartialContent, rec.Code)
+	require.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	require.Equal(t, "bytes 0-9/10240", rec.Header().Get("Content-Range"))
+	require.Equal(t, content[:10], rec.Body.String())
+}
+
+func TestDrainServers(t *testing.T) {
+	var order []string
+	shutdownHTTP := func(context.Context) error {
+		order = append(order, "http")
+		return nil
+	}
+	drainServers(context.Background(), shutdownHTTP, func() { order = append(order, "grpc") })
+	require.Equal(t, []string{"http", "grpc"}, order)
+
+	// A gRPC server that never stops is abandoned once the grace period is up.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	block := make(chan struct{})
+	defer close(block)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		drainServers(ctx, func(context.Context) error { return nil }, func() { <-block })
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("drainServers waited past the grace period")
+	}
+}
+
+func TestFollowMasterLogs(t *testing.T) {
+	defer func(wait time.Duration) { masterLogsBatchMissWaitTime = wait }(masterLogsBatchMissWaitTime)
+	masterLogsBatchMissWaitTime = 10 * time.Millisecond
+
+	m := &Master{
+		logs:       logger.NewLogBuffer(4),
+		config:     config.DefaultConfig(),
+		logStreams: api.NewStreamLimiter(0, 0),
+		draining:   make(chan struct{}),
+	}
+	log := func(n int) {
+		for i := 0; i < n; i++ {
+			require.NoError(t, m.logs.Fire(&logrus.Entry{
+				Message: fmt.Sprintf("entry %d", m.logs.Len()), Time: time.Now(),
+			}))
+		}
+	}
+
+	// Entries 0 and 1 are evicted before the stream reads them.
+	log(6)
+	batches := make(chan []*logger.Entry, 1)
+	filter := func(entries []*logger.Entry) []*logger.Entry {
+		var odd []*logger.Entry
+		for _, entry := range entries {
+			if entry.ID%2 == 1 {
+				odd = append(odd, entry)
+			}
+		}
+		batches <- entries
+		return odd
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/logs?follow=true", nil), rec)
+	done := make(chan error, 1)
+	go func() { done <- m.followMasterLogs(c, nil, 0, filter) }()
+	require.Len(t, <-batches, 4)
+	log(2)
+	for read := 0; read < 2; {
+		read += len(<-batches)
+	}
+	close(m.draining)
+	require.NoError(t, <-done)
+
+	var lines []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.Len(t, lines, 4)
+	var marker logsEvictedMarker
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &marker))
+	require.Equal(t, logsEvictedMarker{EvictedFromID: 0, EvictedToID: 2}, marker)
+	// Only the entries the filter keeps are written, and nothing was missed after the first batch.
+	for i, id := range []int{3, 5, 7} {
+		var entry logger.Entry
+		require.NoError(t, json.Unmarshal([]byte(lines[i+1]), &entry))
+		require.Equal(t, id, entry.ID)
+	}
+}
+
+func TestFollowMasterLogsLimitsPerUser(t *testing.T) {
+	m := &Master{
+		logs:       logger.NewLogBuffer(4),
+		config:     config.DefaultConfig(),
+		logStreams: api.NewStreamLimiter(0, 1),
+		draining:   make(chan struct{}),
+	}
+	follow := func(remoteAddr, forwardedFor string) (*httptest.ResponseRecorder, chan error) {
+		req := httptest.NewRequest(http.MethodGet, "/logs?follow=true", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		rec := httptest.NewRecorder()
+		e := echo.New()
+		e.IPExtractor = ipExtractor(nil)
+		c := &detContext.DetContext{Context: e.NewContext(req, rec)}
+		c.SetUser(model.User{ID: 1})
+		done := make(chan error, 1)
+		go func() { done <- m.followMasterLogs(c, nil, 0, nil) }()
+		return rec, done
+	}
+
+	_, first := follow("192.0.2.1:1234", "")
+	require.Eventually(t, func() bool { return m.logStreams.Active() == 1 }, time.Second,
+		time.Millisecond)
+	// The same user can't open another stream by connecting from, or claiming, another address.
+	_, second := follow("192.0.2.2:1234", "198.51.100.1")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, <-second, &httpErr)
+	require.Equal(t, http.StatusTooManyRequests, httpErr.Code)
+
+	close(m.draining)
+	require.NoError(t, <-first)
+	require.Equal(t, 0, m.logStreams.Active())
+}
+
+func TestRunHealthChecks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	hang := func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+	start := time.Now()
+	failing := runHealthChecks(ctx, []healthCheck{
+		{name: "hanging", check: hang},
+		{name: "failing", check: func(context.Context) error { return errors.New("down") }},
+		{name: "passing", check: func(context.Context) error { return nil }},
+		{name: "also_hanging", check: hang},
+	})
+	require.Equal(t, []string{"hanging", "failing", "also_hanging"}, failing)
+	// The checks share one deadline rather than each getting their own.
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestGetHealthDraining(t *testing.T) {
+	m := &Master{draining: make(chan struct{})}
+	close(m.draining)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/health", nil), rec)
+	require.NoError(t, m.getHealth(c))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"status": "draining"}`, rec.Body.String())
+}
+
+func TestGetReady(t *testing.T) {
+	m := &Master{}
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/ready", nil), rec)
+		require.NoError(t, m.getReady(c))
+		return rec
+	}
+
+	rec := get()
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "starting", rec.Body.String())
+
+	m.ready.Store(true)
+	rec = get()
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ready", rec.Body.String())
+
+	// Shutting down takes the master out of rotation again.
+	m.ready.Store(false)
+	require.Equal(t, http.StatusServiceUnavailable, get().Code)
+}
+
+func TestTaskLogBatchKey(t *testing.T) {
+	batch := func(taskIDs ...string) []*model.TaskLog {
+		var logs []*model.TaskLog
+		for _, id := range taskIDs {
+			logs = append(logs, &model.TaskLog{TaskID: id})
+		}
+		return logs
+	}
+
+	key := taskLogBatchKey(batch("b", "a", "b"), "k")
+	require.Equal(t, key, taskLogBatchKey(batch("a", "b"), "k"))
+	require.NotEqual(t, key, taskLogBatchKey(batch("a", "b"), "l"))
+	// Another task's batch with the same key must not be taken for a repeat.
+	require.NotEqual(t, key, taskLogBatchKey(batch("c"), "k"))
+	require.NotEqual(t, taskLogBatchKey(batch("a"), "b\nk"), taskLogBatchKey(batch("a", "b"), "k"))
+}
+
+// fakeTaskLogBackend records the task logs added to it.
+type fakeTaskLogBackend struct {
+	task.LogBackend
+	added []*model.TaskLog
+}
+
+func (b *fakeTaskLogBackend) AddTaskLogs(logs []*model.TaskLog) error {
+	b.added = append(b.added, logs...)
+	return nil
+}
+
+func postTaskLogsRequest(
+	m *Master, body, idempotencyKey string,
+) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest(http.MethodPost, "/task-logs", strings.NewReader(body))
+	if idempotencyKey != "" {
+		req.Header.Set(api.HeaderIdempotencyKey, idempotencyKey)
+	}
+	rec := httptest.NewRecorder()
+	return rec, api.Route(m.postTaskLogs)(echo.New().NewContext(req, rec))
+}
+
+func TestPostTaskLogsRejectsInvalidBatches(t *testing.T) {
+	backend := &fakeTaskLogBackend{}
+	m := &Master{config: config.DefaultConfig(), taskLogBackend: backend}
+	m.config.TaskLogs.MaxBatchSize = 2
+
+	_, err := postTaskLogsRequest(m,
+		`[{"task_id": "a", "log": "1"}, {"task_id": "a", "log": "2"}, {"task_id": "a", "log": "3"}]`,
+		"")
+	require.Error(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, err.(*echo.HTTPError).Code)
+
+	_, err = postTaskLogsRequest(m, `[{"task_id": "a", "log": "1"}, {"log": "2"}]`, "")
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	require.Contains(t, err.Error(), "task log 1 is missing a task ID")
+
+	// Nothing from a rejected batch is stored.
+	require.Empty(t, backend.added)
+}
+
+func TestPostTaskLogsReportsAdded(t *testing.T) {
+	backend := &fakeTaskLogBackend{}
+	m := &Master{
+		config:         config.DefaultConfig(),
+		taskLogBackend: backend,
+		taskLogBatches: api.NewIdempotencyCache(time.Hour, 10),
+	}
+	body := `[{"task_id": "a", "log": "1"}, {"task_id": "a", "log": "2"}]`
+
+	rec, err := postTaskLogsRequest(m, body, "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"added": 2}`, rec.Body.String())
+
+	// A replayed batch reports the count from its first delivery without storing it again.
+	for i := 0; i < 2; i++ {
+		rec, err = postTaskLogsRequest(m, body, "batch-1")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"added": 2}`, rec.Body.String())
+	}
+	require.Len(t, backend.added, 4)
+}
+
+func TestGetMasterLogsTruncated(t *testing.T) {
+	m := &Master{logs: logger.NewLogBuffer(10), config: config.DefaultConfig()}
+	m.config.Log.APIMaxTail = 3
+	for i := 0; i < 5; i++ {
+		require.NoError(t, m.logs.Fire(&logrus.Entry{
+			Message: fmt.Sprintf("entry %d", i), Time: time.Now(),
+		}))
+	}
+	get := func(query string) (ids []int, truncated string) {
+		rec := httptest.NewRecorder()
+		c := &detContext.DetContext{Context: echo.New().NewContext(
+			httptest.NewRequest(http.MethodGet, "/logs?"+query, nil), rec,
+		)}
+		c.SetUser(model.User{Admin: true})
+		require.NoError(t, m.getMasterLogs(c))
+		var entries []*logger.Entry
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+		return ids, rec.Header().Get(logsTruncatedHeader)
+	}
+
+	ids, truncated := get("")
+	require.Equal(t, []int{2, 3, 4}, ids)
+	require.Equal(t, "true", truncated)
+	ids, truncated = get("tail=-1")
+	require.Equal(t, []int{2, 3, 4}, ids)
+	require.Equal(t, "true", truncated)
+	// Paging forward gets the oldest entries after greater_than_id.
+	ids, truncated = get("greater_than_id=0")
+	require.Equal(t, []int{1, 2, 3}, ids)
+	require.Equal(t, "true", truncated)
+	ids, truncated = get("greater_than_id=1")
+	require.Equal(t, []int{2, 3, 4}, ids)
+	require.Equal(t, "false", truncated)
+	// A tail within the cap is not truncation.
+	ids, truncated = get("tail=2")
+	require.Equal(t, []int{3, 4}, ids)
+	require.Equal(t, "false", truncated)
+}
+
+// syncRecorder is a ResponseRecorder whose body can be read while a handler is still writing it.
+type syncRecorder struct {
+	*httptest.ResponseRecorder
+	mu sync.Mutex
+}
+
+func (r *syncRecorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ResponseRecorder.Write(b)
+}
+
+func (r *syncRecorder) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Split(strings.TrimSpace(r.Body.String()), "\n")
+}
+
+func TestGetMasterLogsFollowTruncated(t *testing.T) {
+	defer func(wait time.Duration) { masterLogsBatchMissWaitTime = wait }(masterLogsBatchMissWaitTime)
+	masterLogsBatchMissWaitTime = 10 * time.Millisecond
+
+	m := &Master{
+		logs:       logger.NewLogBuffer(10),
+		config:     config.DefaultConfig(),
+		logStreams: api.NewStreamLimiter(0, 0),
+		draining:   make(chan struct{}),
+	}
+	m.config.Log.APIMaxTail = 3
+	for i := 0; i < 5; i++ {
+		require.NoError(t, m.logs.Fire(&logrus.Entry{
+			Message: fmt.Sprintf("entry %d", i), Time: time.Now(),
+		}))
+	}
+
+	rec := &syncRecorder{ResponseRecorder: httptest.NewRecorder()}
+	c := &detContext.DetContext{Context: echo.New().NewContext(
+		httptest.NewRequest(http.MethodGet, "/logs?follow=true&greater_than_id=0", nil), rec,
+	)}
+	c.SetUser(model.User{Admin: true})
+	done := make(chan error, 1)
+	go func() { done <- m.getMasterLogs(c) }()
+
+	// The entry left out of the capped first page is streamed once following starts.
+	require.Eventually(t, func() bool { return len(rec.lines()) == 4 }, 5*time.Second,
+		time.Millisecond)
+	close(m.draining)
+	require.NoError(t, <-done)
+	require.Equal(t, "true", rec.Header().Get(logsTruncatedHeader))
+	for i, line := range rec.lines() {
+		var entry logger.Entry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		require.Equal(t, i+1, entry.ID)
+	}
+}
+
+func TestGetConfigDefaultsRequiresAdmin(t *testing.T) {
+	m := &Master{}
+	get := func(u model.User) (interface{}, error) {
+		// A query string must not get around the admin check.
+		c := &detContext.DetContext{Context: echo.New().NewContext(
+			httptest.NewRequest(http.MethodGet, "/config/defaults?x=1", nil),
+			httptest.NewRecorder(),
+		)}
+		c.SetUser(u)
+		return m.getConfigDefaults(c)
+	}
+
+	_, err := get(model.User{Username: "user"})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+
+	defaults, err := get(model.User{Username: "admin", Admin: true})
+	require.NoError(t, err)
+	require.NotNil(t, defaults)
+}
+
+func TestSeparateGRPCListenerNegotiatesH2(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert := newTestCert(t, &key.PublicKey, key, x509.ECDSAWithSHA256)
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	base, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(tls.NewListener(base, grpcTLSConfig(tlsConfig))) }()
+	defer srv.Stop()
+
+	// The shared config is left alone for the listener cmux sits behind.
+	require.Empty(t, tlsConfig.NextProtos)
+
+	conn, err := grpc.Dial(base.Addr().String(), grpc.WithTransportCredentials(
+		credentials.NewTLS(&tls.Config{InsecureSkipVerify: true}), //nolint:gosec
+	))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var p peer.Peer
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(
+		context.Background(), &grpc_health_v1.HealthCheckRequest{}, grpc.Peer(&p),
+	)
+	require.NoError(t, err)
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	require.True(t, ok)
+	require.Equal(t, "h2", tlsInfo.State.NegotiatedProtocol)
+}
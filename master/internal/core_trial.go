@@ -1,15 +1,27 @@
 package internal
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 
+	"github.com/determined-ai/determined/master/internal/api"
 	detContext "github.com/determined-ai/determined/master/internal/context"
 	"github.com/determined-ai/determined/master/internal/db"
 	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+const (
+	defaultTrialLogsLimit = 1000
+	maxTrialLogsLimit     = 10000
 )
 
 func echoCanGetTrial(c echo.Context, m *Master, trialID string) error {
@@ -49,10 +61,213 @@ func (m *Master) getTrial(c echo.Context) (interface{}, error) {
 	return m.db.RawQuery("get_trial", c.Param("trial_id"))
 }
 
-func (m *Master) getTrialMetrics(c echo.Context) (interface{}, error) {
+func (m *Master) getTrialMetrics(c echo.Context) error {
+	if err := echoCanGetTrial(c, m, c.Param("trial_id")); err != nil {
+		return err
+	}
+
+	metrics, err := m.db.RawQuery("get_trial_metrics", c.Param("trial_id"))
+	if err != nil {
+		return err
+	}
+
+	if api.NegotiateFormat(c, api.FormatJSON) != api.FormatCSV {
+		return c.JSONBlob(http.StatusOK, metrics)
+	}
+	return writeTrialMetricsCSV(c, metrics)
+}
+
+// getTrialLogs returns a trial's logs, optionally only those emitted while it trained a range of
+// batches. Only logs stored as task logs are returned.
+func (m *Master) getTrialLogs(c echo.Context) (interface{}, error) {
 	if err := echoCanGetTrial(c, m, c.Param("trial_id")); err != nil {
 		return nil, err
 	}
+	args := struct {
+		TrialID   int  `path:"trial_id"`
+		FromBatch *int `query:"from_batch"`
+		ToBatch   *int `query:"to_batch"`
+		Limit     *int `query:"limit"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	limit := defaultTrialLogsLimit
+	if args.Limit != nil {
+		limit = *args.Limit
+	}
+	if limit < 1 || limit > maxTrialLogsLimit {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("limit must be between 1 and %d", maxTrialLogsLimit))
+	}
+	if args.FromBatch != nil && args.ToBatch != nil && *args.FromBatch > *args.ToBatch {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "from_batch cannot be after to_batch")
+	}
+
+	trial, err := m.db.TrialByID(args.TrialID)
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []api.Filter
+	ctx := c.Request().Context()
+	if args.FromBatch != nil {
+		after, err := stepEndTime(ctx, args.TrialID, "total_batches <= ?", *args.FromBatch, "DESC")
+		if err != nil {
+			return nil, err
+		}
+		if after != nil {
+			filters = append(filters, api.Filter{
+				Field:     "timestamp",
+				Operation: api.FilterOperationGreaterThan,
+				Values:    *after,
+			})
+		}
+	}
+	if args.ToBatch != nil {
+		before, err := stepEndTime(ctx, args.TrialID, "total_batches >= ?", *args.ToBatch, "ASC")
+		if err != nil {
+			return nil, err
+		}
+		if before != nil {
+			filters = append(filters, api.Filter{
+				Field:     "timestamp",
+				Operation: api.FilterOperationLessThanEqual,
+				Values:    *before,
+			})
+		}
+	}
 
-	return m.db.RawQuery("get_trial_metrics", c.Param("trial_id"))
+	logs, _, err := m.taskLogBackend.TaskLogs(
+		trial.TaskID, limit, filters, apiv1.OrderBy_ORDER_BY_ASC, nil,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching trial logs")
+	}
+	return logs, nil
+}
+
+// stepEndTime returns when the trial reported the step nearest to batches, on the side given by
+// the condition and order, or nil if it reported no such step. It is used to translate a range of
+// batches into the range of time in which the trial trained them.
+func stepEndTime(
+	ctx context.Context, trialID int, condition string, batches int, order string,
+) (*time.Time, error) {
+	var endTimes []time.Time
+	if err := db.Bun().NewSelect().
+		Table("steps").
+		Column("end_time").
+		Where("trial_id = ?", trialID).
+		Where(condition, batches).
+		OrderExpr("total_batches "+order).
+		Limit(1).
+		Scan(ctx, &endTimes); err != nil {
+		return nil, errors.Wrapf(err, "error fetching steps for trial %d", trialID)
+	}
+	if len(endTimes) == 0 {
+		return nil, nil
+	}
+	return &endTimes[0], nil
+}
+
+// trialMetricsSteps is the subset of the get_trial_metrics result that is exported as CSV.
+type trialMetricsSteps struct {
+	Steps []struct {
+		TotalBatches int    `json:"total_batches"`
+		EndTime      string `json:"end_time"`
+		Metrics      struct {
+			AvgMetrics map[string]interface{} `json:"avg_metrics"`
+		} `json:"metrics"`
+		Validation *struct {
+			Metrics struct {
+				ValidationMetrics map[string]interface{} `json:"validation_metrics"`
+			} `json:"metrics"`
+		} `json:"validation"`
+	} `json:"steps"`
+}
+
+// writeTrialMetricsCSV writes one row per step, with a column for each training and validation
+// metric reported by any step. Metrics that a step did not report are left empty.
+func writeTrialMetricsCSV(c echo.Context, raw []byte) error {
+	var trial trialMetricsSteps
+	if err := json.Unmarshal(raw, &trial); err != nil {
+		return errors.Wrap(err, "error parsing trial metrics")
+	}
+	csvOptions, err := api.ParseCSVOptions(c)
+	if err != nil {
+		return err
+	}
+
+	trainingNames, validationNames := map[string]bool{}, map[string]bool{}
+	for _, step := range trial.Steps {
+		for name := range step.Metrics.AvgMetrics {
+			trainingNames[name] = true
+		}
+		if step.Validation != nil {
+			for name := range step.Validation.Metrics.ValidationMetrics {
+				validationNames[name] = true
+			}
+		}
+	}
+	sortedNames := func(names map[string]bool) []string {
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		return sorted
+	}
+	training, validation := sortedNames(trainingNames), sortedNames(validationNames)
+
+	header := []string{"total_batches", "end_time"}
+	for _, name := range training {
+		header = append(header, "training."+name)
+	}
+	for _, name := range validation {
+		header = append(header, "validation."+name)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	csvWriter := csvOptions.NewWriter(c.Response())
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	formatMetric := func(metrics map[string]interface{}, name string) (string, error) {
+		switch value := metrics[name].(type) {
+		case nil:
+			return "", nil
+		case string:
+			return value, nil
+		default:
+			formatted, err := json.Marshal(value)
+			return string(formatted), err
+		}
+	}
+	for _, step := range trial.Steps {
+		fields := []string{strconv.Itoa(step.TotalBatches), step.EndTime}
+		for _, name := range training {
+			value, err := formatMetric(step.Metrics.AvgMetrics, name)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, value)
+		}
+		for _, name := range validation {
+			var validationMetrics map[string]interface{}
+			if step.Validation != nil {
+				validationMetrics = step.Validation.Metrics.ValidationMetrics
+			}
+			value, err := formatMetric(validationMetrics, name)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, value)
+		}
+		if err := csvWriter.Write(fields); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
 }
@@ -37,8 +37,7 @@ func TestTrialAuthZEcho(t *testing.T) {
 			ctx.SetParamNames("trial_id")
 			ctx.SetParamValues(fmt.Sprintf("%d", id))
 			ctx.SetRequest(httptest.NewRequest(http.MethodGet, "/", nil))
-			_, err := api.m.getTrialMetrics(ctx)
-			return err
+			return api.m.getTrialMetrics(ctx)
 		},
 	}
 
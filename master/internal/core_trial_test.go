@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTrialMetricsCSV(t *testing.T) {
+	raw := []byte(`{"steps": [
+		{
+			"total_batches": 100,
+			"end_time": "2023-01-01T00:00:00Z",
+			"metrics": {"avg_metrics": {"loss": 0.5, "note": "a, \"b\""}},
+			"validation": null
+		},
+		{
+			"total_batches": 200,
+			"end_time": "2023-01-01T01:00:00Z",
+			"metrics": {"avg_metrics": {"loss": 0.25, "note": [1, 2]}},
+			"validation": {"metrics": {"validation_metrics": {"accuracy": 0.9}}}
+		}
+	]}`)
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	require.NoError(t, writeTrialMetricsCSV(c, raw))
+	require.Equal(t, "text/csv", rec.Header().Get(echo.HeaderContentType))
+	// Metrics a step didn't report are left empty, and values needing it are quoted.
+	require.Equal(t, `total_batches,end_time,training.loss,training.note,validation.accuracy
+100,2023-01-01T00:00:00Z,0.5,"a, ""b""",
+200,2023-01-01T01:00:00Z,0.25,"[1,2]",0.9
+`, rec.Body.String())
+}
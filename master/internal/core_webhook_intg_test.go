@@ -0,0 +1,78 @@
+//go:build integration
+// +build integration
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/webhooks"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/schemas"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+func TestPostReloadWebhooks(t *testing.T) {
+	api, _, _ := setupAPITest(t, nil)
+	ctx := context.Background()
+
+	// The endpoint holds deliveries until the test ends, so the old shipper can't drain.
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	require.NoError(t, webhooks.AddWebhook(ctx, &webhooks.Webhook{
+		URL: server.URL,
+		Triggers: []*webhooks.Trigger{{
+			TriggerType: webhooks.TriggerTypeStateChange,
+			Condition:   map[string]interface{}{"state": model.CompletedState},
+		}},
+		WebhookType: webhooks.WebhookTypeDefault,
+	}))
+	ws, err := webhooks.GetWebhooks(ctx)
+	require.NoError(t, err)
+
+	webhooks.Init()
+	defer webhooks.Deinit()
+	var config expconf.ExperimentConfig
+	config = schemas.WithDefaults(config)
+	require.NoError(t, webhooks.ReportExperimentStateChanged(ctx, model.Experiment{
+		State: model.CompletedState,
+	}, config))
+	select {
+	case <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("webhook delivery never started")
+	}
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/webhooks/reload", nil), rec)
+	done := make(chan error, 1)
+	go func() { done <- api.m.postReloadWebhooks(c) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		var body map[string]int
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Equal(t, len(ws), body["webhooks"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload waited for the delivery in flight")
+	}
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestPostRotateWebhookSecretValidation(t *testing.T) {
+	m := &Master{config: config.DefaultConfig()}
+	rotate := func(body string) error {
+		req := httptest.NewRequest(
+			http.MethodPost, "/webhooks/1/rotate-secret", strings.NewReader(body))
+		c := &detContext.DetContext{Context: echo.New().NewContext(req, httptest.NewRecorder())}
+		c.SetParamNames("webhook_id")
+		c.SetParamValues("1")
+		c.SetUser(model.User{Username: "admin", Admin: true})
+		_, err := m.postRotateWebhookSecret(c)
+		return err
+	}
+	code := func(err error) int {
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok, "expected an HTTP error, got %v", err)
+		return httpErr.Code
+	}
+
+	require.Equal(t, http.StatusBadRequest, code(rotate(`{"secret": "short"}`)))
+	require.Equal(t, http.StatusBadRequest, code(rotate(`{"secret":`)))
+	long := `{"secret": "` + strings.Repeat("a", maxWebhookSecretBodySize) + `"}`
+	require.Equal(t, http.StatusRequestEntityTooLarge, code(rotate(long)))
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/authz"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/master/internal/workspace"
+	"github.com/determined-ai/determined/proto/pkg/workspacev1"
+)
+
+// workspaceExperimentGCPreview summarizes the checkpoints garbage collection would delete from one
+// experiment of a workspace.
+type workspaceExperimentGCPreview struct {
+	ExperimentID int   `json:"experiment_id"`
+	Checkpoints  int   `json:"checkpoints"`
+	SizeBytes    int64 `json:"size_bytes"`
+}
+
+// getWorkspaceCheckpointsToGC previews garbage collection across every experiment of a workspace
+// that the user can view the checkpoints of, applying each experiment's own retention policy, and
+// totals the storage it would reclaim.
+func (m *Master) getWorkspaceCheckpointsToGC(c echo.Context) (interface{}, error) {
+	args := struct {
+		WorkspaceID int `path:"workspace_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+
+	notFound := echo.NewHTTPError(
+		http.StatusNotFound, fmt.Sprintf("workspace not found: %d", args.WorkspaceID),
+	)
+	w := &workspacev1.Workspace{}
+	err := m.db.QueryProto("get_workspace", w, args.WorkspaceID, user.ID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, notFound
+	} else if err != nil {
+		return nil, err
+	}
+	if ok, err := workspace.AuthZProvider.Get().CanGetWorkspace(ctx, user, w); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, notFound
+	}
+
+	exps, err := m.db.WorkspaceExperiments(args.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each experiment has its own retention policy, so its candidates are selected separately, but
+	// the checkpoints themselves are fetched at once.
+	var candidates []uuid.UUID
+	candidateExps := map[uuid.UUID]int{}
+	for _, exp := range exps {
+		if ok, err := expauth.AuthZProvider.Get().CanGetExperiment(ctx, user, exp); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		err := expauth.AuthZProvider.Get().CanGetExperimentArtifacts(ctx, user, exp)
+		if errors.As(err, &authz.PermissionDeniedError{}) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		uuids, err := m.db.ExperimentCheckpointsToGCRaw(
+			exp.ID,
+			exp.Config.CheckpointStorage.SaveExperimentBest(),
+			exp.Config.CheckpointStorage.SaveTrialBest(),
+			exp.Config.CheckpointStorage.SaveTrialLatest(),
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error previewing garbage collection of experiment %d", exp.ID)
+		}
+		for _, id := range uuids {
+			candidateExps[id] = exp.ID
+		}
+		candidates = append(candidates, uuids...)
+	}
+	checkpoints, err := m.db.CheckpointByUUIDs(candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := map[int]*workspaceExperimentGCPreview{}
+	for _, ckpt := range checkpoints {
+		expID := candidateExps[*ckpt.UUID]
+		preview, ok := previews[expID]
+		if !ok {
+			preview = &workspaceExperimentGCPreview{ExperimentID: expID}
+			previews[expID] = preview
+		}
+		preview.Checkpoints++
+		preview.SizeBytes += checkpointSize(ckpt.Resources)
+	}
+	experiments := []workspaceExperimentGCPreview{}
+	var totalCheckpoints int
+	var totalSize int64
+	for _, exp := range exps {
+		if preview, ok := previews[exp.ID]; ok {
+			experiments = append(experiments, *preview)
+			totalCheckpoints += preview.Checkpoints
+			totalSize += preview.SizeBytes
+		}
+	}
+
+	return map[string]interface{}{
+		"workspace_id":      args.WorkspaceID,
+		"experiments":       experiments,
+		"total_checkpoints": totalCheckpoints,
+		"total_size_bytes":  totalSize,
+	}, nil
+}
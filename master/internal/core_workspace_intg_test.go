@@ -0,0 +1,56 @@
+//go:build integration
+// +build integration
+
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/authz"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestAuthZGetWorkspaceCheckpointsToGC(t *testing.T) {
+	api, authZExp, _, curUser, grpcCtx := setupExpAuthTest(t, nil)
+	workspaceID, projectID := createProjectAndWorkspace(grpcCtx, t, api)
+	exp0 := createTestExpWithProjectID(t, api, curUser, projectID)
+	exp1 := createTestExpWithProjectID(t, api, curUser, projectID)
+
+	preview := func() (interface{}, error) {
+		ctx := newTestEchoContext(curUser)
+		ctx.SetRequest(httptest.NewRequest(http.MethodGet, "/", nil))
+		ctx.SetParamNames("workspace_id")
+		ctx.SetParamValues(strconv.Itoa(workspaceID))
+		wAuthZ.On("CanGetWorkspace", mock.Anything, curUser, mock.Anything).
+			Return(true, nil).Once()
+		return api.m.getWorkspaceCheckpointsToGC(ctx)
+	}
+	isExp := func(exp *model.Experiment) interface{} {
+		return mock.MatchedBy(func(e *model.Experiment) bool { return e.ID == exp.ID })
+	}
+
+	// Experiments whose artifacts the user can't view are left out.
+	authZExp.On("CanGetExperiment", mock.Anything, curUser, mock.Anything).Return(true, nil).Twice()
+	authZExp.On("CanGetExperimentArtifacts", mock.Anything, curUser, isExp(exp0)).
+		Return(authz.PermissionDeniedError{}).Once()
+	authZExp.On("CanGetExperimentArtifacts", mock.Anything, curUser, isExp(exp1)).
+		Return(nil).Once()
+	res, err := preview()
+	require.NoError(t, err)
+	require.Equal(t, 0, res.(map[string]interface{})["total_checkpoints"])
+
+	// Other errors checking access are returned rather than skipped.
+	expectedErr := fmt.Errorf("canGetExperimentArtifactsError")
+	authZExp.On("CanGetExperiment", mock.Anything, curUser, mock.Anything).Return(true, nil).Once()
+	authZExp.On("CanGetExperimentArtifacts", mock.Anything, curUser, isExp(exp0)).
+		Return(expectedErr).Once()
+	_, err = preview()
+	require.ErrorIs(t, err, expectedErr)
+}
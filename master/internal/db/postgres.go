@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -198,6 +199,13 @@ func (db *PgDB) Close() error {
 	return db.sql.Close()
 }
 
+// Ping checks that the database answers a trivial query, which also needs a free connection from
+// the pool.
+func (db *PgDB) Ping(ctx context.Context) error {
+	_, err := db.sql.ExecContext(ctx, "SELECT 1")
+	return err
+}
+
 // namedGet is a convenience method for a named query for a single value.
 func (db *PgDB) namedGet(dest interface{}, query string, arg interface{}) error {
 	nstmt, err := db.sql.PrepareNamed(query)
@@ -74,7 +74,16 @@ func TestClusterAPI(t *testing.T) {
 		"Retrieved cluster heartbeat doesn't match the correct time")
 
 	// Don't complete the above allocation and call CloseOpenAllocations
-	require.NoError(t, db.CloseOpenAllocations(nil))
+	closed, err := db.CloseOpenAllocations(nil)
+	require.NoError(t, err)
+	var closedEndTime time.Time
+	for _, c := range closed {
+		if c.AllocationID == aIn.AllocationID {
+			closedEndTime = c.EndTime
+		}
+	}
+	require.True(t, closedEndTime.Equal(clusterHeartbeat),
+		"expected the open allocation to be reported closed at the cluster heartbeat")
 
 	// Retrieve the open allocation and check if end time is set to cluster_heartbeat
 	aOut, err := db.AllocationByID(aIn.AllocationID)
@@ -12,6 +12,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
 
 	"github.com/determined-ai/determined/master/internal/lttb"
 
@@ -76,6 +77,22 @@ WHERE e.project_id = $1`, id)
 	return experiments, nil
 }
 
+// WorkspaceExperiments returns a list of experiments within the projects of a workspace.
+func (db *PgDB) WorkspaceExperiments(id int) (experiments []*model.Experiment, err error) {
+	if err := db.queryRows(`
+SELECT e.id, state, config, model_definition, start_time, end_time, archived,
+	   git_remote, git_commit, git_committer, git_commit_date, owner_id, notes,
+		 job_id, u.username as username, project_id
+FROM experiments e
+JOIN users u ON (e.owner_id = u.id)
+JOIN projects p ON (e.project_id = p.id)
+WHERE p.workspace_id = $1
+ORDER BY e.id`, &experiments, id); err != nil {
+		return nil, errors.Wrapf(err, "error fetching experiments of workspace %d", id)
+	}
+	return experiments, nil
+}
+
 // ExperimentLabelUsage returns a flattened and deduplicated list of all the
 // labels in use across all experiments.
 func (db *PgDB) ExperimentLabelUsage(projectID int32) (labelUsage map[string]int, err error) {
@@ -881,16 +898,89 @@ func (db *PgDB) AddExperiment(
 		return errors.Errorf("error adding an experiment with non-zero id %v", experiment.ID)
 	}
 	return db.withTransaction("add_experiment", func(tx *sqlx.Tx) error {
-		job := model.Job{
-			JobID:   experiment.JobID,
-			JobType: model.JobTypeExperiment,
-			OwnerID: experiment.OwnerID,
+		return addExperiment(tx, experiment, activeConfig)
+	})
+}
+
+// ExperimentIdempotencyKey is the idempotency key a user created an experiment with, which is
+// honored for the window after the experiment is created.
+type ExperimentIdempotencyKey struct {
+	UserID model.UserID
+	Key    string
+	Window time.Duration
+}
+
+// ErrIdempotencyKeyUsed is returned when adding an experiment with an idempotency key the user
+// already created an experiment with inside the window.
+type ErrIdempotencyKeyUsed struct {
+	ExperimentID int
+}
+
+func (e ErrIdempotencyKeyUsed) Error() string {
+	return fmt.Sprintf("idempotency key already used for experiment %d", e.ExperimentID)
+}
+
+// AddExperimentWithIdempotencyKey adds the experiment to the database and sets its ID, recording
+// the idempotency key in the same transaction. If the key is already recorded, nothing is added and
+// ErrIdempotencyKeyUsed with the experiment it was recorded for is returned; concurrent requests
+// with the same key wait on each other, so only one of them creates an experiment. Records older
+// than the window are removed along the way.
+func (db *PgDB) AddExperimentWithIdempotencyKey(
+	experiment *model.Experiment, activeConfig expconf.ExperimentConfig,
+	key ExperimentIdempotencyKey,
+) error {
+	if experiment.ID != 0 {
+		return errors.Errorf("error adding an experiment with non-zero id %v", experiment.ID)
+	}
+	err := db.withTransaction("add_experiment_with_idempotency_key", func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(`
+DELETE FROM experiment_idempotency_keys WHERE created_at <= $1`,
+			time.Now().Add(-key.Window)); err != nil {
+			return errors.Wrap(err, "removing expired experiment idempotency keys")
+		}
+		if err := addExperiment(tx, experiment, activeConfig); err != nil {
+			return err
 		}
-		if err := addJob(tx, &job); err != nil {
-			return errors.Wrapf(err, "error inserting job %v", job)
+		var added []int
+		if err := tx.Select(&added, `
+INSERT INTO experiment_idempotency_keys (user_id, key, experiment_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, key) DO NOTHING
+RETURNING experiment_id`, key.UserID, key.Key, experiment.ID); err != nil {
+			return errors.Wrap(err, "adding experiment idempotency key")
 		}
-		// HACK: insert literal "null" into the config, which we set in the next query.
-		if err := namedGet(tx, &experiment.ID, `
+		if len(added) > 0 {
+			return nil
+		}
+		var existingID int
+		if err := tx.Get(&existingID, `
+SELECT experiment_id FROM experiment_idempotency_keys WHERE user_id = $1 AND key = $2`,
+			key.UserID, key.Key); err != nil {
+			return errors.Wrap(err, "looking up experiment idempotency key")
+		}
+		// Roll back the experiment, which the earlier one with this key stands in for.
+		return ErrIdempotencyKeyUsed{ExperimentID: existingID}
+	})
+	if err != nil {
+		experiment.ID = 0
+	}
+	return err
+}
+
+// addExperiment inserts the experiment and its job inside tx and sets its ID.
+func addExperiment(
+	tx *sqlx.Tx, experiment *model.Experiment, activeConfig expconf.ExperimentConfig,
+) error {
+	job := model.Job{
+		JobID:   experiment.JobID,
+		JobType: model.JobTypeExperiment,
+		OwnerID: experiment.OwnerID,
+	}
+	if err := addJob(tx, &job); err != nil {
+		return errors.Wrapf(err, "error inserting job %v", job)
+	}
+	// HACK: insert literal "null" into the config, which we set in the next query.
+	if err := namedGet(tx, &experiment.ID, `
 	INSERT INTO experiments
 	(state, config, model_definition, start_time, end_time, archived, parent_id, progress,
 	 git_remote, git_commit, git_committer, git_commit_date, owner_id, original_config, notes, job_id,
@@ -899,15 +989,14 @@ func (db *PgDB) AddExperiment(
 					0, :git_remote, :git_commit, :git_committer, :git_commit_date, :owner_id,
 					:original_config, :notes, :job_id, :project_id)
 	RETURNING id`, experiment); err != nil {
-			return errors.Wrapf(err, "error inserting experiment %v", *experiment)
-		}
-		if _, err := tx.Exec(
-			`UPDATE experiments SET config = $1 WHERE id = $2`, activeConfig, experiment.ID,
-		); err != nil {
-			return errors.Wrapf(err, "error inserting experiment config")
-		}
-		return nil
-	})
+		return errors.Wrapf(err, "error inserting experiment %v", *experiment)
+	}
+	if _, err := tx.Exec(
+		`UPDATE experiments SET config = $1 WHERE id = $2`, activeConfig, experiment.ID,
+	); err != nil {
+		return errors.Wrapf(err, "error inserting experiment config")
+	}
+	return nil
 }
 
 // ExperimentByID looks up an experiment by ID in a database, returning an error if none exists.
@@ -1058,14 +1147,53 @@ WHERE state IN ('ACTIVE', 'PAUSED', 'STOPPING_CANCELED', 'STOPPING_COMPLETED', '
 // FailDeletingExperiment finds all experiments that were deleting when the master crashed and moves
 // them to DELETE_FAILED.
 func (db *PgDB) FailDeletingExperiment() error {
-	if _, err := db.sql.Exec(`
-UPDATE experiments
-SET state = 'DELETE_FAILED'
-WHERE state = 'DELETING'
-`); err != nil {
-		return errors.Wrap(err, "failing deleting experiments")
-	}
-	return nil
+	_, err := db.FailDeletingExperiments(context.TODO(), nil, nil)
+	return err
+}
+
+// FailDeletingExperiments moves the given experiments from DELETING to DELETE_FAILED, or all
+// deleting experiments if no IDs are given, other than those in except. It returns the IDs of the
+// experiments that were moved.
+func (db *PgDB) FailDeletingExperiments(
+	ctx context.Context, ids []int, except []int,
+) ([]int, error) {
+	failed := []int{}
+	q := Bun().NewUpdate().
+		Table("experiments").
+		Set("state = 'DELETE_FAILED'").
+		Where("state = 'DELETING'").
+		Returning("id")
+	if len(ids) > 0 {
+		q = q.Where("id IN (?)", bun.In(ids))
+	}
+	if len(except) > 0 {
+		q = q.Where("id NOT IN (?)", bun.In(except))
+	}
+	if err := q.Scan(ctx, &failed); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "failing deleting experiments")
+	}
+	return failed, nil
+}
+
+// ExperimentByIdempotencyKey returns the ID of the experiment the user created with the given
+// idempotency key within the window, or nil if there is none.
+func (db *PgDB) ExperimentByIdempotencyKey(
+	ctx context.Context, userID model.UserID, key string, window time.Duration,
+) (*int, error) {
+	var ids []int
+	if err := Bun().NewSelect().
+		Table("experiment_idempotency_keys").
+		Column("experiment_id").
+		Where("user_id = ?", userID).
+		Where("key = ?", key).
+		Where("created_at > ?", time.Now().Add(-window)).
+		Scan(ctx, &ids); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrap(err, "looking up experiment idempotency key")
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return &ids[0], nil
 }
 
 // TerminateExperimentInRestart is used during master restart to properly terminate an experiment
@@ -1477,3 +1605,25 @@ SELECT selected_checkpoints.uuid AS ID from selected_checkpoints;`
 
 	return deleteCheckpoints, nil
 }
+
+// ExperimentStateTransition is a state an experiment entered and when it entered it.
+type ExperimentStateTransition struct {
+	bun.BaseModel  `bun:"table:experiment_state_history"`
+	State          model.State `bun:"state" json:"state"`
+	TransitionTime time.Time   `bun:"transition_time" json:"transition_time"`
+}
+
+// ExperimentStateHistory returns the recorded state transitions of an experiment, oldest first.
+func (db *PgDB) ExperimentStateHistory(
+	ctx context.Context, experimentID int,
+) ([]ExperimentStateTransition, error) {
+	history := []ExperimentStateTransition{}
+	if err := Bun().NewSelect().
+		Model(&history).
+		Where("experiment_id = ?", experimentID).
+		Order("transition_time ASC", "id ASC").
+		Scan(ctx); err != nil {
+		return nil, errors.Wrapf(err, "querying state history of experiment %d", experimentID)
+	}
+	return history, nil
+}
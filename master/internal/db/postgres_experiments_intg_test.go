@@ -216,3 +216,80 @@ func TestCheckpointMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestFailDeletingExperiments(t *testing.T) {
+	require.NoError(t, etc.SetRootPath(RootFromDB))
+	db := MustResolveTestPostgres(t)
+	MustMigrateTestPostgres(t, db, MigrationsFromDB)
+
+	user := RequireMockUser(t, db)
+	var ids []int
+	for i := 0; i < 3; i++ {
+		exp := RequireMockExperiment(t, db, user)
+		exp.State = model.DeletingState
+		require.NoError(t, db.SaveExperimentState(exp))
+		ids = append(ids, exp.ID)
+	}
+
+	// Deletions still in progress are left alone.
+	failed, err := db.FailDeletingExperiments(context.TODO(), ids, ids[2:])
+	require.NoError(t, err)
+	require.ElementsMatch(t, ids[:2], failed)
+	for i, id := range ids {
+		exp, err := db.ExperimentByID(id)
+		require.NoError(t, err)
+		if i < 2 {
+			require.Equal(t, model.DeleteFailedState, exp.State)
+		} else {
+			require.Equal(t, model.DeletingState, exp.State)
+		}
+	}
+
+	// Experiments that aren't deleting are not moved.
+	failed, err = db.FailDeletingExperiments(context.TODO(), ids[:1], nil)
+	require.NoError(t, err)
+	require.Empty(t, failed)
+}
+
+func TestAddExperimentWithIdempotencyKey(t *testing.T) {
+	require.NoError(t, etc.SetRootPath(RootFromDB))
+	db := MustResolveTestPostgres(t)
+	MustMigrateTestPostgres(t, db, MigrationsFromDB)
+
+	user := RequireMockUser(t, db)
+	template := RequireMockExperiment(t, db, user)
+	activeConfig, err := db.ActiveExperimentConfig(template.ID)
+	require.NoError(t, err)
+	key := ExperimentIdempotencyKey{UserID: user.ID, Key: uuid.NewString(), Window: time.Hour}
+
+	// Of concurrent requests with the same key, one creates the experiment and the rest get it.
+	const requests = 5
+	created := make(chan int, requests)
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			exp := *template
+			exp.ID = 0
+			exp.JobID = model.NewJobID()
+			if err := db.AddExperimentWithIdempotencyKey(&exp, activeConfig, key); err != nil {
+				errs <- err
+				return
+			}
+			created <- exp.ID
+		}()
+	}
+	id := <-created
+	for i := 1; i < requests; i++ {
+		var used ErrIdempotencyKeyUsed
+		require.ErrorAs(t, <-errs, &used)
+		require.Equal(t, id, used.ExperimentID)
+	}
+
+	found, err := db.ExperimentByIdempotencyKey(context.TODO(), user.ID, key.Key, key.Window)
+	require.NoError(t, err)
+	require.Equal(t, &id, found)
+	var count int
+	require.NoError(t, Bun().NewSelect().Table("experiments").
+		Where("owner_id = ?", user.ID).ColumnExpr("count(*)").Scan(context.TODO(), &count))
+	require.Equal(t, 2, count)
+}
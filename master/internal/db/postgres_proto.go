@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -37,6 +38,23 @@ func (db *PgDB) QueryProtof(
 	)
 }
 
+// QueryProtoRows runs the named query and returns a cursor over its results, to be read one row at
+// a time with ScanProto, for results too large to hold in memory at once.
+func (db *PgDB) QueryProtoRows(
+	ctx context.Context, queryName string, args ...interface{},
+) (*sqlx.Rows, error) {
+	rows, err := db.sql.QueryxContext(ctx, db.queries.getOrLoad(queryName), args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error running query: %v", queryName)
+	}
+	return rows, nil
+}
+
+// ScanProto reads the current row of a cursor from QueryProtoRows into a Protobuf message.
+func ScanProto(rows *sqlx.Rows, message proto.Message) error {
+	return protoParser(rows, message)
+}
+
 func protoParser(rows *sqlx.Rows, val interface{}) error {
 	message, ok := val.(proto.Message)
 	if !ok {
@@ -204,14 +204,45 @@ func (db *PgDB) UpdateAllocationStartTime(a model.Allocation) error {
 	return err
 }
 
+// UpdateAllocationSlotType stores the type of device the allocation was granted.
+func (db *PgDB) UpdateAllocationSlotType(a model.Allocation) error {
+	_, err := db.sql.Exec(`
+		UPDATE allocations
+		SET slot_type = $2
+		WHERE allocation_id = $1
+	`, a.AllocationID, a.SlotType)
+	return err
+}
+
+// CloseAllocation ends a single open allocation now, for allocations orphaned while the master
+// was running. Allocations that never started are given the same start time.
+func (db *PgDB) CloseAllocation(aID model.AllocationID) error {
+	if _, err := db.sql.Exec(`
+UPDATE allocations
+SET start_time = COALESCE(start_time, now()), end_time = now()
+WHERE allocation_id = $1 AND end_time IS NULL`, aID); err != nil {
+		return errors.Wrapf(err, "closing allocation %s", aID)
+	}
+	return nil
+}
+
+// ForceClosedAllocation is an allocation that was still open when the master crashed, along with
+// the end time it was assigned on startup.
+type ForceClosedAllocation struct {
+	AllocationID model.AllocationID `db:"allocation_id" json:"allocation_id"`
+	EndTime      time.Time          `db:"end_time" json:"end_time"`
+}
+
 // CloseOpenAllocations finds all allocations that were open when the master crashed
-// and adds an end time.
-func (db *PgDB) CloseOpenAllocations(exclude []model.AllocationID) error {
+// and adds an end time. It returns the allocations it closed.
+func (db *PgDB) CloseOpenAllocations(
+	exclude []model.AllocationID,
+) ([]ForceClosedAllocation, error) {
 	if _, err := db.sql.Exec(`
 	UPDATE allocations
 	SET start_time = cluster_heartbeat FROM cluster_id
 	WHERE start_time is NULL`); err != nil {
-		return errors.Wrap(err,
+		return nil, errors.Wrap(err,
 			"setting start time to cluster heartbeat when it's assigned to zero value")
 	}
 
@@ -225,16 +256,18 @@ func (db *PgDB) CloseOpenAllocations(exclude []model.AllocationID) error {
 		excludedFilter = strings.Join(excludeStr, ",")
 	}
 
-	if _, err := db.sql.Exec(`
+	closed := []ForceClosedAllocation{}
+	if err := db.sql.Select(&closed, `
 	UPDATE allocations
 	SET end_time = greatest(cluster_heartbeat, start_time)
 	FROM cluster_id
 	WHERE end_time IS NULL AND
 	($1 = '' OR allocation_id NOT IN (
-		SELECT unnest(string_to_array($1, ','))))`, excludedFilter); err != nil {
-		return errors.Wrap(err, "closing old allocations")
+		SELECT unnest(string_to_array($1, ','))))
+	RETURNING allocation_id, end_time`, excludedFilter); err != nil {
+		return nil, errors.Wrap(err, "closing old allocations")
 	}
-	return nil
+	return closed, nil
 }
 
 // taskLogsFieldMap is used to map fields in filters to expressions. This was used historically
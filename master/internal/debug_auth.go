@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/user"
+)
+
+// debugEndpointsAuthMiddleware authenticates requests to the metrics and profiling endpoints.
+// Requests bearing the configured token are let through; all others need a user session, which
+// must belong to an admin if the config requires it. Requests that fail authentication get a 404
+// rather than a 401 or 403, so as not to advertise the endpoints.
+func debugEndpointsAuthMiddleware(
+	userService *user.Service, conf config.DebugEndpointsAuthConfig,
+) echo.MiddlewareFunc {
+	requireUser := userService.RequireAuthentication
+	if conf.RequireAdmin {
+		requireUser = userService.RequireAdminAuthentication
+	}
+	requireUser = hideAuthFailures(requireUser)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		authenticated := requireUser(next)
+		return func(c echo.Context) error {
+			if conf.BearerToken != "" && subtle.ConstantTimeCompare(
+				[]byte(c.Request().Header.Get("Authorization")),
+				[]byte("Bearer "+conf.BearerToken),
+			) == 1 {
+				return next(c)
+			}
+			return authenticated(c)
+		}
+	}
+}
+
+// hideAuthFailures wraps an authentication middleware so that requests it rejects as unauthorized
+// or forbidden get a 404 instead. Errors from the handler itself are passed through unchanged.
+func hideAuthFailures(auth echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			reached := false
+			err := auth(func(c echo.Context) error {
+				reached = true
+				return next(c)
+			})(c)
+			var httpErr *echo.HTTPError
+			if !reached && errors.As(err, &httpErr) && (httpErr.Code == http.StatusUnauthorized ||
+				httpErr.Code == http.StatusForbidden) {
+				return echo.ErrNotFound
+			}
+			return err
+		}
+	}
+}
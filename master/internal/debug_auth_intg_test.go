@@ -0,0 +1,55 @@
+//go:build integration
+// +build integration
+
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestDebugEndpointsAuthSession(t *testing.T) {
+	api, _, _ := setupAPITest(t, nil)
+	user.InitService(api.m.db, nil, &model.ExternalSessions{})
+	nonAdmin := db.RequireMockUser(t, api.m.db)
+	token, err := api.m.db.StartUserSession(&nonAdmin)
+	require.NoError(t, err)
+
+	get := func(conf config.DebugEndpointsAuthConfig, authorization string) int {
+		e := echo.New()
+		e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				return next(&detContext.DetContext{Context: c})
+			}
+		})
+		e.GET("/debug/prom/metrics", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}, debugEndpointsAuthMiddleware(user.GetService(), conf))
+
+		req := httptest.NewRequest(http.MethodGet, "/debug/prom/metrics", nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, get(config.DebugEndpointsAuthConfig{}, "Bearer "+token))
+	// Non-admin sessions get a 404 when admins are required.
+	requireAdmin := config.DebugEndpointsAuthConfig{RequireAdmin: true}
+	require.Equal(t, http.StatusNotFound, get(requireAdmin, "Bearer "+token))
+	// The configured token doesn't let non-admin sessions through instead.
+	requireAdmin.BearerToken = "s3cret"
+	require.Equal(t, http.StatusNotFound, get(requireAdmin, "Bearer "+token))
+	require.Equal(t, http.StatusOK, get(requireAdmin, "Bearer s3cret"))
+	require.Equal(t, http.StatusNotFound, get(requireAdmin, "Bearer bogus"))
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestDebugEndpointsAuthBearerToken(t *testing.T) {
+	e := echo.New()
+	// The token is checked before any user session, so no user service is needed here.
+	e.GET("/debug/prom/metrics", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, debugEndpointsAuthMiddleware(
+		&user.Service{}, config.DebugEndpointsAuthConfig{RequireAdmin: true, BearerToken: "s3cret"},
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/prom/metrics", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestDebugEndpointsAuthNoSession(t *testing.T) {
+	// Requests without a token are rejected before any session lookup.
+	for _, conf := range []config.DebugEndpointsAuthConfig{
+		{},
+		{RequireAdmin: true},
+		{BearerToken: "s3cret"},
+	} {
+		e := echo.New()
+		e.GET("/debug/prom/metrics", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		}, debugEndpointsAuthMiddleware(&user.Service{}, conf))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/prom/metrics", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code, conf)
+	}
+}
+
+func TestHideAuthFailures(t *testing.T) {
+	e := echo.New()
+	reject := func(code int) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				if code != 0 {
+					return echo.NewHTTPError(code)
+				}
+				return next(c)
+			}
+		}
+	}
+	get := func(auth echo.MiddlewareFunc, handlerCode int) int {
+		handler := func(c echo.Context) error {
+			if handlerCode != http.StatusOK {
+				return echo.NewHTTPError(handlerCode)
+			}
+			return c.NoContent(http.StatusOK)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/debug/pprof/heap", nil), rec)
+		if err := hideAuthFailures(auth)(handler)(c); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusNotFound, get(reject(http.StatusUnauthorized), http.StatusOK))
+	require.Equal(t, http.StatusNotFound, get(reject(http.StatusForbidden), http.StatusOK))
+	require.Equal(t, http.StatusOK, get(reject(0), http.StatusOK))
+	// Only authentication failures are hidden, not those of the endpoint itself.
+	require.Equal(t, http.StatusForbidden, get(reject(0), http.StatusForbidden))
+}
+
+func TestCaptureProfilesRequiresAdmin(t *testing.T) {
+	dir := t.TempDir()
+	m := &Master{config: &config.Config{}}
+	m.config.Observability.ProfileCaptureDir = dir
+	capture := func(u model.User) error {
+		c := &detContext.DetContext{Context: echo.New().NewContext(
+			httptest.NewRequest(http.MethodPost, "/debug/capture-profiles", nil),
+			httptest.NewRecorder(),
+		)}
+		c.SetUser(u)
+		_, err := m.captureProfiles(c)
+		return err
+	}
+
+	err := capture(model.User{Username: "user"})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	require.NoError(t, capture(model.User{Username: "admin", Admin: true}))
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, len(capturedProfiles))
+}
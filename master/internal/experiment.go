@@ -120,12 +120,13 @@ type (
 
 // Create a new experiment object from the given model experiment object, along with its searcher
 // and log. If the input object has no ID set, also create a new experiment in the database and set
-// the returned object's ID appropriately.
+// the returned object's ID appropriately, recording the idempotency key with it if one is given.
 func newExperiment(
 	m *Master,
 	expModel *model.Experiment,
 	activeConfig expconf.ExperimentConfig,
 	taskSpec *tasks.TaskSpec,
+	idempotencyKey *db.ExperimentIdempotencyKey,
 ) (*experiment, []command.LaunchWarning, error) {
 	resources := activeConfig.Resources()
 	poolName, err := m.rm.ResolveResourcePool(
@@ -161,7 +162,12 @@ func newExperiment(
 	}
 
 	if expModel.ID == 0 {
-		if err = m.db.AddExperiment(expModel, activeConfig); err != nil {
+		if idempotencyKey != nil {
+			err = m.db.AddExperimentWithIdempotencyKey(expModel, activeConfig, *idempotencyKey)
+		} else {
+			err = m.db.AddExperiment(expModel, activeConfig)
+		}
+		if err != nil {
 			return nil, launchWarnings, err
 		}
 		telemetry.ReportExperimentCreated(m.system, expModel.ID, activeConfig)
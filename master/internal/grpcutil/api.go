@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"runtime/debug"
+	"time"
 
 	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpclogrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
@@ -18,18 +19,25 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 
+	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/pkg/model"
 	proto "github.com/determined-ai/determined/proto/pkg/apiv1"
 )
 
-const jsonPretty = "application/json+pretty"
+const (
+	jsonPretty = "application/json+pretty"
+	// defaultKeepaliveMinTime matches the gRPC library's default keepalive enforcement policy.
+	defaultKeepaliveMinTime = 5 * time.Minute
+)
 
 // NewGRPCServer creates a Determined gRPC service.
 func NewGRPCServer(db *db.PgDB, srv proto.DeterminedServer, enablePrometheus bool,
-	extConfig *model.ExternalSessions,
+	extConfig *model.ExternalSessions, grpcConfig config.GRPCConfig,
 ) *grpc.Server {
 	// In go-grpc, the INFO log level is used primarily for debugging
 	// purposes, so omit INFO messages from the master log.
@@ -67,15 +75,33 @@ func NewGRPCServer(db *db.PgDB, srv proto.DeterminedServer, enablePrometheus boo
 		grpc_prometheus.EnableHandlingTimeHistogram()
 	}
 
-	grpcS := grpc.NewServer(
+	serverOpts := []grpc.ServerOption{
 		grpc.StreamInterceptor(grpcmiddleware.ChainStreamServer(streamInterceptors...)),
 		grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(unaryInterceptors...)),
 		// Allow receiving messages _slightly_ larger than the maximum allowed context
 		// directory. We should either just move these back to echo or have a chunker for
 		// .tar.gz files long term.
-		grpc.MaxRecvMsgSize(96*1024*1024),
-	)
+		grpc.MaxRecvMsgSize(96 * 1024 * 1024),
+	}
+	if grpcConfig.MaxConcurrentStreams > 0 {
+		serverOpts = append(serverOpts, grpc.MaxConcurrentStreams(grpcConfig.MaxConcurrentStreams))
+	}
+	if grpcConfig.KeepaliveMinTime > 0 || grpcConfig.KeepalivePermitWithoutStream {
+		minTime := time.Duration(grpcConfig.KeepaliveMinTime)
+		if minTime == 0 {
+			minTime = defaultKeepaliveMinTime
+		}
+		serverOpts = append(serverOpts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             minTime,
+			PermitWithoutStream: grpcConfig.KeepalivePermitWithoutStream,
+		}))
+	}
+
+	grpcS := grpc.NewServer(serverOpts...)
 	proto.RegisterDeterminedServer(grpcS, srv)
+	if grpcConfig.EnableReflection {
+		reflection.Register(grpcS)
+	}
 	return grpcS
 }
 
@@ -0,0 +1,45 @@
+package grpcutil
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const forwardedForHeader = "x-forwarded-for"
+
+// ClientIP returns the IP address of the client that made the request in ctx. Requests proxied
+// through the local gRPC gateway are attributed to the address the gateway saw, rather than the
+// gateway itself.
+func ClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	// Only trust forwarding metadata from ourselves; anything else could be spoofed by the client.
+	if parsed := net.ParseIP(ip); parsed == nil || !parsed.IsLoopback() {
+		return ip
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ip
+	}
+	forwarded := md.Get(forwardedForHeader)
+	if len(forwarded) == 0 {
+		return ip
+	}
+	// The gateway appends the remote address it saw to any existing header value.
+	hops := strings.Split(forwarded[len(forwarded)-1], ",")
+	if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+		return last
+	}
+	return ip
+}
@@ -19,6 +19,12 @@ func AddProviderInfoToMasterResponse(config *config.Config, masterResp *apiv1.Ge
 // provider information. In OSS this is a no-op.
 func AddProviderInfoToMasterInfo(config *config.Config, masterInfo *aproto.MasterInfo) {}
 
+// ProviderInfo returns the SSO providers configured for the master. In OSS
+// there are none, so this always returns an empty list.
+func ProviderInfo(config *config.Config) []aproto.SSOProviderInfo {
+	return []aproto.SSOProviderInfo{}
+}
+
 // RegisterAPIHandlers registers needed API handlers
 // determined by master config. In OSS this is just a no-op.
 func RegisterAPIHandlers(config *config.Config, db *db.PgDB, echo *echo.Echo) error {
@@ -1,6 +1,9 @@
 package prom
 
 import (
+	"sync/atomic"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
@@ -55,6 +58,123 @@ Exposes mapping of Determined's container ID to the GPU UUID/device ID as given
 `,
 	}, []string{"gpu_uuid", "container_id"})
 
+	// ActiveLogStreams tracks the number of master log streams currently being followed.
+	ActiveLogStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "active_log_streams",
+		Help:      "the number of master log streams currently being followed",
+	})
+
+	// ExperimentRestores tracks the number of experiments in the current restore run.
+	ExperimentRestores = promauto.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "experiment_restores",
+		Help:      "the number of experiments to restore in the current restore run",
+	})
+
+	// ExperimentRestoresCompleted tracks the number of experiments restored so far in the current
+	// restore run, whether or not they restored successfully.
+	ExperimentRestoresCompleted = promauto.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "experiment_restores_completed",
+		Help:      "the number of experiments finished restoring in the current restore run",
+	})
+
+	// ExperimentRestoreSuccesses counts experiments restored successfully since the master started.
+	ExperimentRestoreSuccesses = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "experiment_restore_successes_total",
+		Help:      "the number of experiments restored successfully since the master started",
+	})
+
+	// ExperimentRestoreFailures counts experiments that failed to restore, and were marked as
+	// errored, since the master started.
+	ExperimentRestoreFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "experiment_restore_failures_total",
+		Help:      "the number of experiments that failed to restore since the master started",
+	})
+
+	// ExperimentRestoreDuration tracks how long restoring each experiment takes, not counting time
+	// spent waiting for a restore slot.
+	ExperimentRestoreDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Subsystem: "det",
+		Name:      "experiment_restore_duration_seconds",
+		Help:      "the time taken to restore each experiment, excluding time queued for a slot",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	})
+
+	// SecondsSinceLastHeartbeat tracks how long ago the cluster heartbeat was last written. It is
+	// computed when scraped, so it keeps growing between heartbeats while writes fail.
+	SecondsSinceLastHeartbeat = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "seconds_since_last_heartbeat",
+		Help:      "the number of seconds since the cluster heartbeat was last successfully written",
+	}, func() float64 {
+		return time.Since(time.Unix(0, lastHeartbeat.Load())).Seconds()
+	})
+
+	// HeartbeatConsecutiveFailures tracks the number of cluster heartbeat writes that have failed
+	// since the last successful one.
+	HeartbeatConsecutiveFailures = promauto.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "heartbeat_consecutive_failures",
+		Help:      "the number of consecutive failed cluster heartbeat writes",
+	})
+
+	// TelemetryExperimentReportsDropped counts experiment state change reports that were dropped
+	// because the telemetry report queue was full.
+	TelemetryExperimentReportsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "telemetry_experiment_reports_dropped_total",
+		Help:      "the number of experiment state change telemetry reports dropped due to a full queue",
+	})
+
+	// UncleanCloses counts failures to cleanly close critical resources, such as the database, on
+	// shutdown.
+	UncleanCloses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "unclean_closes_total",
+		Help:      "the number of critical resources that failed to close cleanly on shutdown",
+	}, []string{"closer"})
+
+	// SavedConnections tracks the number of connections currently saved for retrieval by handlers.
+	SavedConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "saved_connections",
+		Help:      "the number of connections currently saved for retrieval by request handlers",
+	})
+
+	// WebUIAssetsServed counts requests for WebUI static assets by the cache-control category they
+	// were given and whether they were served from a matching file or fell back to the index page.
+	WebUIAssetsServed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "webui_assets_served_total",
+		Help:      "the number of WebUI static asset requests, by cache category and source",
+	}, []string{"cache", "source"})
+
+	// WebhookDeliveryAttempts counts attempts to deliver webhook events, including retries.
+	WebhookDeliveryAttempts = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "the number of attempts to deliver webhook events, including retries",
+	})
+
+	// WebhookDeliveries counts webhook events delivered successfully.
+	WebhookDeliveries = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "webhook_deliveries_total",
+		Help:      "the number of webhook events delivered successfully",
+	})
+
+	// WebhookDeadLetters counts webhook events recorded as dead letters after their delivery
+	// failed for good.
+	WebhookDeadLetters = promauto.NewCounter(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "webhook_dead_letters_total",
+		Help:      "the number of webhook events dead-lettered after exhausting their retries",
+	})
+
 	// DetStateMetrics is a prometheus registry containing all exported user-facing metrics.
 	DetStateMetrics = prometheus.NewRegistry()
 )
@@ -208,3 +328,15 @@ func DisassociateContainerGPU(cID cproto.ID, d device.Device) {
 	gpuUUIDToContainerID.WithLabelValues(d.UUID, cID.String()).Dec()
 	gpuUUIDToContainerID.DeleteLabelValues(d.UUID, cID.String())
 }
+
+// lastHeartbeat is when the cluster heartbeat was last successfully written, in Unix nanoseconds.
+var lastHeartbeat = func() *atomic.Int64 {
+	var t atomic.Int64
+	t.Store(time.Now().UnixNano())
+	return &t
+}()
+
+// SetLastHeartbeat records a successful cluster heartbeat write at the given time.
+func SetLastHeartbeat(t time.Time) {
+	lastHeartbeat.Store(t.UnixNano())
+}
@@ -23,6 +23,10 @@ type (
 		URL             *url.URL
 		ProxyTCP        bool
 		Unauthenticated bool
+		// FlushInterval is the httputil.ReverseProxy flush interval of HTTP responses from the
+		// service: negative to flush after every write, zero to flush only when the response
+		// buffer fills, or positive to also flush periodically.
+		FlushInterval time.Duration
 	}
 	// Unregister removes the service from the proxy. All future requests until the service name is
 	// registered again will be responded with a 404 response. If the service is not registered with
@@ -43,6 +47,7 @@ type Service struct {
 	LastRequested        time.Time
 	ProxyTCP             bool
 	AllowUnauthenticated bool
+	FlushInterval        time.Duration
 }
 
 // ProxyHTTPAuth processes a proxy request, returning true if the request should terminate
@@ -74,6 +79,7 @@ func (p *Proxy) Receive(ctx *actor.Context) error {
 			LastRequested:        time.Now(),
 			ProxyTCP:             msg.ProxyTCP,
 			AllowUnauthenticated: msg.Unauthenticated,
+			FlushInterval:        msg.FlushInterval,
 		}
 
 		if ctx.ExpectingResponse() {
@@ -112,6 +118,7 @@ func (p *Proxy) getService(serviceName string) *Service {
 		LastRequested:        service.LastRequested,
 		ProxyTCP:             service.ProxyTCP,
 		AllowUnauthenticated: service.AllowUnauthenticated,
+		FlushInterval:        service.FlushInterval,
 	}
 }
 
@@ -156,7 +163,9 @@ func (p *Proxy) newProxyHandler(serviceID string) echo.HandlerFunc {
 		case c.IsWebSocket():
 			proxy = newSingleHostReverseWebSocketProxy(c, service.URL)
 		default:
-			proxy = httputil.NewSingleHostReverseProxy(service.URL)
+			reverseProxy := httputil.NewSingleHostReverseProxy(service.URL)
+			reverseProxy.FlushInterval = service.FlushInterval
+			proxy = reverseProxy
 		}
 		proxy.ServeHTTP(c.Response(), req)
 
@@ -176,6 +185,7 @@ func (p *Proxy) getSummary() map[string]Service {
 			LastRequested:        service.LastRequested,
 			ProxyTCP:             service.ProxyTCP,
 			AllowUnauthenticated: service.AllowUnauthenticated,
+			FlushInterval:        service.FlushInterval,
 		}
 	}
 
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// rateLimitExemptPaths are the paths of health and readiness checks, which load balancers and
+// orchestrators poll.
+var rateLimitExemptPaths = map[string]bool{
+	"/health": true,
+	"/ready":  true,
+}
+
+// webuiStaticAssetDirs are the directories under the webui route that hold static assets.
+var webuiStaticAssetDirs = map[string]bool{
+	"themes":     true,
+	"static":     true,
+	"determined": true,
+}
+
+// rateLimitExempt reports whether a request is never rate limited: health and readiness checks,
+// and webui static assets, which browsers fetch many of at once.
+func rateLimitExempt(c echo.Context) bool {
+	path := c.Request().URL.Path
+	if rateLimitExemptPaths[path] {
+		return true
+	}
+	if !strings.HasPrefix(path, webuiBaseRoute+"/") {
+		return false
+	}
+	dir, _, found := strings.Cut(strings.TrimPrefix(path, webuiBaseRoute+"/"), "/")
+	return found && webuiStaticAssetDirs[dir]
+}
+
+// ipExtractor returns how the master determines client IP addresses. X-Forwarded-For headers are
+// only trusted when the request comes through one of the trusted proxies; otherwise clients could
+// pick their own address, for example to get a fresh rate limit.
+func ipExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range trustedProxies {
+		// The config validates the ranges.
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			options = append(options, echo.TrustIPRange(ipNet))
+		}
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// clientKey identifies the client of a request for rate and stream limits: the authenticated
+// user, or else the IP address.
+func clientKey(c echo.Context) string {
+	if user, ok := c.Get("user").(model.User); ok {
+		return fmt.Sprintf("user:%d", user.ID)
+	}
+	return "ip:" + c.RealIP()
+}
+
+// rateLimitMiddleware rejects requests beyond the configured rates with HTTP status 429 and a
+// Retry-After header. The limit of the route with the longest matching path prefix applies, or
+// else the global limit. It must run after authentication to tell users apart.
+func rateLimitMiddleware(cfg config.RateLimitConfig) echo.MiddlewareFunc {
+	type routeLimiter struct {
+		prefix string
+		// limiter is nil for routes that aren't limited.
+		limiter *api.RateLimiter
+	}
+	newLimiter := func(requestsPerSecond float64, burst int) *api.RateLimiter {
+		if requestsPerSecond <= 0 {
+			return nil
+		}
+		return api.NewRateLimiter(requestsPerSecond, burst)
+	}
+
+	routes := make([]routeLimiter, 0, len(cfg.Routes)+1)
+	for _, route := range cfg.Routes {
+		routes = append(routes, routeLimiter{
+			prefix:  route.PathPrefix,
+			limiter: newLimiter(route.RequestsPerSecond, route.Burst),
+		})
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].prefix) > len(routes[j].prefix)
+	})
+	routes = append(routes, routeLimiter{limiter: newLimiter(cfg.RequestsPerSecond, cfg.Burst)})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if rateLimitExempt(c) {
+				return next(c)
+			}
+			path := c.Request().URL.Path
+			for _, route := range routes {
+				if !strings.HasPrefix(path, route.prefix) {
+					continue
+				}
+				if route.limiter == nil {
+					break
+				}
+				if ok, retryAfter := route.limiter.Allow(clientKey(c)); !ok {
+					seconds := int(math.Ceil(retryAfter.Seconds()))
+					c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+					return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+				}
+				break
+			}
+			return next(c)
+		}
+	}
+}
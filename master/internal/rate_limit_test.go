@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	e := echo.New()
+	// Stand in for authentication by taking the user from a header.
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Header.Get("X-User") != "" {
+				c.Set("user", model.User{ID: 1})
+			}
+			return next(c)
+		}
+	})
+	e.Use(rateLimitMiddleware(config.RateLimitConfig{
+		RequestsPerSecond: 0.001,
+		Burst:             1,
+		Routes: []config.RouteRateLimitConfig{
+			{PathPrefix: "/resources", RequestsPerSecond: 0.001, Burst: 2},
+			// Longer prefixes take precedence.
+			{PathPrefix: "/resources/allocation/by-label"},
+		},
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	for _, path := range []string{"/info", "/health", "/ready", "/det/*", "/resources/*"} {
+		e.GET(path, ok)
+	}
+	get := func(path string, user bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if user {
+			req.Header.Set("X-User", "1")
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, get("/info", false).Code)
+	rec := get("/info", false)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1000", rec.Header().Get("Retry-After"))
+	// Authenticated users are limited separately from their IP address.
+	require.Equal(t, http.StatusOK, get("/info", true).Code)
+	require.Equal(t, http.StatusTooManyRequests, get("/info", true).Code)
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, get("/health", false).Code)
+		require.Equal(t, http.StatusOK, get("/ready", false).Code)
+		require.Equal(t, http.StatusOK, get("/det/static/main.js", false).Code)
+		require.Equal(t, http.StatusOK, get("/resources/allocation/by-label", false).Code)
+	}
+	require.Equal(t, http.StatusOK, get("/resources/allocation/raw", false).Code)
+	require.Equal(t, http.StatusOK, get("/resources/allocation/raw", false).Code)
+	require.Equal(t, http.StatusTooManyRequests, get("/resources/allocation/raw", false).Code)
+}
+
+func TestRateLimitIgnoresUntrustedForwardedFor(t *testing.T) {
+	limited := func(trustedProxies []string) func(remoteAddr, forwardedFor, path string) int {
+		e := echo.New()
+		e.IPExtractor = ipExtractor(trustedProxies)
+		e.Use(rateLimitMiddleware(config.RateLimitConfig{RequestsPerSecond: 0.001, Burst: 1}))
+		e.GET("/*", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+		return func(remoteAddr, forwardedFor, path string) int {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+			req.Header.Set(echo.HeaderXRealIP, forwardedFor)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec.Code
+		}
+	}
+
+	// Without trusted proxies, spoofed headers don't get a client a new bucket.
+	get := limited(nil)
+	require.Equal(t, http.StatusOK, get("192.0.2.1:1234", "198.51.100.1", "/info"))
+	require.Equal(t, http.StatusTooManyRequests, get("192.0.2.1:1234", "198.51.100.2", "/info"))
+	require.Equal(t, http.StatusOK, get("192.0.2.2:1234", "198.51.100.2", "/info"))
+	// Webui paths are only exempt for the static asset directories.
+	require.Equal(t, http.StatusOK, get("192.0.2.1:1234", "", "/det/themes/dark.css"))
+	require.Equal(t, http.StatusTooManyRequests, get("192.0.2.1:1234", "", "/det/staticx/a"))
+	require.Equal(t, http.StatusTooManyRequests, get("192.0.2.1:1234", "", "/api/det/static/a"))
+
+	// Trusted proxies forward the client address, and others still can't spoof it.
+	get = limited([]string{"10.0.0.0/8"})
+	require.Equal(t, http.StatusOK, get("10.0.0.1:1234", "198.51.100.1", "/info"))
+	require.Equal(t, http.StatusTooManyRequests, get("10.0.0.2:1234", "198.51.100.1", "/info"))
+	require.Equal(t, http.StatusOK, get("10.0.0.1:1234", "198.51.100.2", "/info"))
+	require.Equal(t, http.StatusOK, get("192.0.2.1:1234", "198.51.100.3", "/info"))
+	require.Equal(t, http.StatusTooManyRequests, get("192.0.2.1:1234", "198.51.100.4", "/info"))
+}
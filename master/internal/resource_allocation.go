@@ -1,15 +1,328 @@
 package internal
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"time"
 
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/pkg/actor"
 	"github.com/determined-ai/determined/master/pkg/actor/actors"
+	"github.com/determined-ai/determined/proto/pkg/masterv1"
 )
 
 type aggregateTick struct{}
 
+// experimentNames captures the human-readable names associated with an experiment.
+type experimentNames struct {
+	bun.BaseModel `bun:"table:experiments,alias:e"`
+	ID            int32  `bun:"id"`
+	Name          string `bun:"name"`
+	ProjectName   string `bun:"project_name"`
+	WorkspaceName string `bun:"workspace_name"`
+}
+
+// fetchExperimentNames returns the names of the given experiments keyed by experiment ID.
+// Experiments that no longer exist are omitted from the result.
+func fetchExperimentNames(ctx context.Context, ids []int32) (map[int32]experimentNames, error) {
+	names := map[int32]experimentNames{}
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	var rows []experimentNames
+	if err := db.Bun().NewSelect().Model(&rows).
+		ColumnExpr("e.id").
+		ColumnExpr("e.config->>'name' AS name").
+		ColumnExpr("p.name AS project_name").
+		ColumnExpr("w.name AS workspace_name").
+		Join("LEFT JOIN projects p ON e.project_id = p.id").
+		Join("LEFT JOIN workspaces w ON p.workspace_id = w.id").
+		Where("e.id IN (?)", bun.In(ids)).
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		names[row.ID] = row
+	}
+	return names, nil
+}
+
+// maxLabelAllocationBuckets bounds the number of time buckets in a by-label allocation query.
+const maxLabelAllocationBuckets = 10000
+
+// labelAllocationSeries is the slot-seconds consumed by experiments with a given label, per bucket.
+type labelAllocationSeries struct {
+	Label       string    `json:"label"`
+	SlotSeconds []float64 `json:"slot_seconds"`
+}
+
+// labelAllocation is a time series of slot-seconds consumed per experiment label.
+type labelAllocation struct {
+	Buckets []time.Time             `json:"buckets"`
+	Series  []labelAllocationSeries `json:"series"`
+	// Truncated is set when more than maxSeries labels matched and only the largest were kept.
+	Truncated bool `json:"truncated"`
+}
+
+// allocationByLabel spreads the slot-seconds of each labeled raw allocation entry over the buckets
+// of [start, end) it overlaps. Only labels in filter are counted, unless filter is empty. At most
+// maxSeries labels are returned, preferring those that consumed the most.
+func allocationByLabel(
+	entries []*masterv1.ResourceAllocationRawEntry,
+	start, end time.Time,
+	bucket time.Duration,
+	filter map[string]bool,
+	maxSeries int,
+) labelAllocation {
+	var result labelAllocation
+	for t := start; t.Before(end); t = t.Add(bucket) {
+		result.Buckets = append(result.Buckets, t)
+	}
+
+	series := map[string][]float64{}
+	totals := map[string]float64{}
+	for _, entry := range entries {
+		if len(entry.Labels) == 0 || entry.StartTime == nil || entry.EndTime == nil {
+			continue
+		}
+		first, overlaps := bucketOverlaps(
+			entry.StartTime.AsTime(), entry.EndTime.AsTime(), start, end, bucket, len(result.Buckets),
+		)
+		if len(overlaps) == 0 {
+			continue
+		}
+		for _, label := range entry.Labels {
+			if len(filter) > 0 && !filter[label] {
+				continue
+			}
+			if _, ok := series[label]; !ok {
+				series[label] = make([]float64, len(result.Buckets))
+			}
+			for i, seconds := range overlaps {
+				slotSeconds := seconds * float64(entry.Slots)
+				series[label][first+i] += slotSeconds
+				totals[label] += slotSeconds
+			}
+		}
+	}
+
+	labels := make([]string, 0, len(series))
+	for label := range series {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if totals[labels[i]] != totals[labels[j]] {
+			return totals[labels[i]] > totals[labels[j]]
+		}
+		return labels[i] < labels[j]
+	})
+	if len(labels) > maxSeries {
+		labels, result.Truncated = labels[:maxSeries], true
+	}
+
+	result.Series = make([]labelAllocationSeries, 0, len(labels))
+	for _, label := range labels {
+		result.Series = append(result.Series, labelAllocationSeries{
+			Label: label, SlotSeconds: series[label],
+		})
+	}
+	return result
+}
+
+// bucketOverlaps returns how many seconds of [entryStart, entryEnd) fall into each of the
+// numBuckets buckets of [start, end) that it overlaps, along with the index of the first of them.
+// The buckets are found by arithmetic rather than by checking each one, so that long periods with
+// many buckets stay cheap.
+func bucketOverlaps(
+	entryStart, entryEnd, start, end time.Time, bucket time.Duration, numBuckets int,
+) (int, []float64) {
+	if entryStart.Before(start) {
+		entryStart = start
+	}
+	if entryEnd.After(end) {
+		entryEnd = end
+	}
+	if !entryEnd.After(entryStart) {
+		return 0, nil
+	}
+
+	first := int(entryStart.Sub(start) / bucket)
+	last := int((entryEnd.Sub(start) - 1) / bucket)
+	if last >= numBuckets {
+		last = numBuckets - 1
+	}
+	overlaps := make([]float64, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		bucketStart := start.Add(time.Duration(i) * bucket)
+		overlapStart, overlapEnd := bucketStart, bucketStart.Add(bucket)
+		if entryStart.After(overlapStart) {
+			overlapStart = entryStart
+		}
+		if entryEnd.Before(overlapEnd) {
+			overlapEnd = entryEnd
+		}
+		overlaps = append(overlaps, overlapEnd.Sub(overlapStart).Seconds())
+	}
+	return first, overlaps
+}
+
+// aggregationTypes are the aggregation types reported in aggregated resource allocation CSVs, in
+// output order.
+var aggregationTypes = []string{"experiment_label", "username", "resource_pool", "total"}
+
+// aggregatedValues returns the seconds allocated in entry for each key of the given aggregation
+// type, or nil if the type is unknown.
+func aggregatedValues(
+	entry *masterv1.ResourceAllocationAggregatedEntry, aggType string,
+) map[string]float32 {
+	switch aggType {
+	case "experiment_label":
+		return entry.ByExperimentLabel
+	case "username":
+		return entry.ByUsername
+	case "resource_pool":
+		return entry.ByResourcePool
+	case "total":
+		return map[string]float32{"total": entry.Seconds}
+	default:
+		return nil
+	}
+}
+
+// aggregatedCost prices the slot-seconds allocated to a key of an aggregated entry. Only resource
+// pools and the total can be priced, since the other aggregation types are not broken down by
+// resource pool.
+func aggregatedCost(
+	entry *masterv1.ResourceAllocationAggregatedEntry, aggType, key string, seconds float32,
+	rateCard *config.RateCardConfig,
+) (float64, bool) {
+	switch aggType {
+	case "resource_pool":
+		return float64(seconds) / 3600 * rateCard.SlotHourPrice(key), true
+	case "total":
+		var cost float64
+		for pool, poolSeconds := range entry.ByResourcePool {
+			cost += float64(poolSeconds) / 3600 * rateCard.SlotHourPrice(pool)
+		}
+		return cost, true
+	default:
+		return 0, false
+	}
+}
+
+// pivotAggregatedAllocation lays out the aggregated allocation for one aggregation type as a
+// matrix with a row per key and a column per period, for spreadsheet consumption. Keys absent
+// from a period are left empty.
+func pivotAggregatedAllocation(
+	entries []*masterv1.ResourceAllocationAggregatedEntry, aggType string,
+) [][]string {
+	header := []string{"aggregation_key"}
+	cells := map[string][]string{}
+	for i, entry := range entries {
+		header = append(header, entry.PeriodStart)
+		for key, seconds := range aggregatedValues(entry, aggType) {
+			if _, ok := cells[key]; !ok {
+				cells[key] = make([]string, len(entries))
+			}
+			cells[key][i] = fmt.Sprintf("%f", seconds)
+		}
+	}
+
+	keys := make([]string, 0, len(cells))
+	for key := range cells {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := [][]string{header}
+	for _, key := range keys {
+		rows = append(rows, append([]string{key}, cells[key]...))
+	}
+	return rows
+}
+
+// allocationDelta is the change in seconds allocated to an aggregation key between two periods.
+type allocationDelta struct {
+	AggregationType string  `json:"aggregation_type"`
+	AggregationKey  string  `json:"aggregation_key"`
+	BaseSeconds     float64 `json:"base_seconds"`
+	TargetSeconds   float64 `json:"target_seconds"`
+	DeltaSeconds    float64 `json:"delta_seconds"`
+	// PercentChange is relative to the base period, and unset if the key had no base seconds.
+	PercentChange *float64 `json:"percent_change"`
+	// Change is "added" or "removed" for keys present in only the target or base period, and
+	// otherwise "changed" or "unchanged".
+	Change string `json:"change"`
+}
+
+// sumAggregatedValues totals the seconds of each key of an aggregation type across entries.
+func sumAggregatedValues(
+	entries []*masterv1.ResourceAllocationAggregatedEntry, aggType string,
+) map[string]float64 {
+	sums := map[string]float64{}
+	for _, entry := range entries {
+		for key, seconds := range aggregatedValues(entry, aggType) {
+			sums[key] += float64(seconds)
+		}
+	}
+	return sums
+}
+
+// compareAggregatedAllocation diffs the aggregated allocation of a base and a target period, per
+// aggregation type and key.
+func compareAggregatedAllocation(
+	base, target []*masterv1.ResourceAllocationAggregatedEntry,
+) []allocationDelta {
+	deltas := []allocationDelta{}
+	for _, aggType := range aggregationTypes {
+		baseSums, targetSums := sumAggregatedValues(base, aggType), sumAggregatedValues(target, aggType)
+
+		keys := make([]string, 0, len(baseSums)+len(targetSums))
+		for key := range baseSums {
+			keys = append(keys, key)
+		}
+		for key := range targetSums {
+			if _, ok := baseSums[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			baseSeconds, inBase := baseSums[key]
+			targetSeconds, inTarget := targetSums[key]
+			delta := allocationDelta{
+				AggregationType: aggType,
+				AggregationKey:  key,
+				BaseSeconds:     baseSeconds,
+				TargetSeconds:   targetSeconds,
+				DeltaSeconds:    targetSeconds - baseSeconds,
+			}
+			if baseSeconds != 0 {
+				percent := 100 * delta.DeltaSeconds / baseSeconds
+				delta.PercentChange = &percent
+			}
+			switch {
+			case !inBase:
+				delta.Change = "added"
+			case !inTarget:
+				delta.Change = "removed"
+			case delta.DeltaSeconds != 0:
+				delta.Change = "changed"
+			default:
+				delta.Change = "unchanged"
+			}
+			deltas = append(deltas, delta)
+		}
+	}
+	return deltas
+}
+
 func nextAllocationTime(now time.Time) time.Time {
 	target := time.Date(now.Year(), now.Month(), now.Day(), 0, 1, 0, 0, time.UTC)
 	if target.Before(now) {
@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/proto/pkg/masterv1"
+)
+
+func TestAllocationByLabel(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(
+		from, to time.Duration, slots int32, labels ...string,
+	) *masterv1.ResourceAllocationRawEntry {
+		return &masterv1.ResourceAllocationRawEntry{
+			StartTime: timestamppb.New(start.Add(from)),
+			EndTime:   timestamppb.New(start.Add(to)),
+			Slots:     slots,
+			Labels:    labels,
+		}
+	}
+	entries := []*masterv1.ResourceAllocationRawEntry{
+		// Spans both buckets and starts before the period.
+		entry(-30*time.Minute, 90*time.Minute, 2, "a", "b"),
+		entry(75*time.Minute, 3*time.Hour, 1, "c"),
+		// Unlabeled entries, like agent and instance stats, are ignored.
+		entry(0, time.Hour, 8),
+	}
+
+	result := allocationByLabel(entries, start, start.Add(2*time.Hour), time.Hour, nil, 10)
+	require.Equal(t, []time.Time{start, start.Add(time.Hour)}, result.Buckets)
+	require.False(t, result.Truncated)
+	require.Equal(t, []labelAllocationSeries{
+		{Label: "a", SlotSeconds: []float64{7200, 3600}},
+		{Label: "b", SlotSeconds: []float64{7200, 3600}},
+		{Label: "c", SlotSeconds: []float64{0, 2700}},
+	}, result.Series)
+
+	result = allocationByLabel(
+		entries, start, start.Add(2*time.Hour), time.Hour, map[string]bool{"c": true}, 10,
+	)
+	require.Equal(t, []labelAllocationSeries{
+		{Label: "c", SlotSeconds: []float64{0, 2700}},
+	}, result.Series)
+
+	result = allocationByLabel(entries, start, start.Add(2*time.Hour), time.Hour, nil, 1)
+	require.True(t, result.Truncated)
+	require.Len(t, result.Series, 1)
+	require.Equal(t, "a", result.Series[0].Label)
+}
+
+func TestBucketOverlaps(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Three buckets, the last cut short by the end of the period.
+	end := start.Add(150 * time.Minute)
+	overlaps := func(from, to time.Duration) (int, []float64) {
+		return bucketOverlaps(start.Add(from), start.Add(to), start, end, time.Hour, 3)
+	}
+
+	first, seconds := overlaps(90*time.Minute, 4*time.Hour)
+	require.Equal(t, 1, first)
+	require.Equal(t, []float64{1800, 1800}, seconds)
+
+	first, seconds = overlaps(time.Hour, 2*time.Hour)
+	require.Equal(t, 1, first)
+	require.Equal(t, []float64{3600}, seconds)
+
+	_, seconds = overlaps(-2*time.Hour, -time.Hour)
+	require.Empty(t, seconds)
+	_, seconds = overlaps(3*time.Hour, 4*time.Hour)
+	require.Empty(t, seconds)
+}
+
+func TestPivotAggregatedAllocation(t *testing.T) {
+	entries := []*masterv1.ResourceAllocationAggregatedEntry{
+		{
+			PeriodStart:    "2023-01-01",
+			ByUsername:     map[string]float32{"alice": 10, "bob": 20},
+			ByResourcePool: map[string]float32{"default": 30},
+			Seconds:        30,
+		},
+		{
+			PeriodStart: "2023-01-02",
+			ByUsername:  map[string]float32{"bob": 5},
+			Seconds:     5,
+		},
+	}
+
+	require.Equal(t, [][]string{
+		{"aggregation_key", "2023-01-01", "2023-01-02"},
+		{"alice", "10.000000", ""},
+		{"bob", "20.000000", "5.000000"},
+	}, pivotAggregatedAllocation(entries, "username"))
+
+	require.Equal(t, [][]string{
+		{"aggregation_key", "2023-01-01", "2023-01-02"},
+		{"total", "30.000000", "5.000000"},
+	}, pivotAggregatedAllocation(entries, "total"))
+}
+
+func TestCompareAggregatedAllocation(t *testing.T) {
+	base := []*masterv1.ResourceAllocationAggregatedEntry{{
+		PeriodStart:    "2023-01",
+		Seconds:        40,
+		ByUsername:     map[string]float32{"alice": 10, "bob": 30},
+		ByResourcePool: map[string]float32{"default": 40},
+	}}
+	target := []*masterv1.ResourceAllocationAggregatedEntry{{
+		PeriodStart:    "2023-02",
+		Seconds:        60,
+		ByUsername:     map[string]float32{"alice": 15, "carol": 45},
+		ByResourcePool: map[string]float32{"default": 60},
+	}}
+
+	percent := func(p float64) *float64 { return &p }
+	require.Equal(t, []allocationDelta{
+		{"username", "alice", 10, 15, 5, percent(50), "changed"},
+		{"username", "bob", 30, 0, -30, percent(-100), "removed"},
+		{"username", "carol", 0, 45, 45, nil, "added"},
+		{"resource_pool", "default", 40, 60, 20, percent(50), "changed"},
+		{"total", "total", 40, 60, 20, percent(50), "changed"},
+	}, compareAggregatedAllocation(base, target))
+}
+
+func TestAggregatedCost(t *testing.T) {
+	rateCard := &config.RateCardConfig{
+		Default:       1,
+		ResourcePools: map[string]float64{"gpu": 3},
+	}
+	entry := &masterv1.ResourceAllocationAggregatedEntry{
+		Seconds:        3 * 3600,
+		ByResourcePool: map[string]float32{"gpu": 3600, "cpu": 2 * 3600},
+		ByUsername:     map[string]float32{"alice": 3 * 3600},
+	}
+
+	cost, ok := aggregatedCost(entry, "resource_pool", "gpu", 3600, rateCard)
+	require.True(t, ok)
+	require.InDelta(t, 3, cost, 1e-9)
+
+	cost, ok = aggregatedCost(entry, "resource_pool", "cpu", 2*3600, rateCard)
+	require.True(t, ok)
+	require.InDelta(t, 2, cost, 1e-9)
+
+	cost, ok = aggregatedCost(entry, "total", "total", entry.Seconds, rateCard)
+	require.True(t, ok)
+	require.InDelta(t, 5, cost, 1e-9)
+
+	_, ok = aggregatedCost(entry, "username", "alice", 3*3600, rateCard)
+	require.False(t, ok)
+}
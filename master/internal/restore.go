@@ -4,12 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/determined-ai/determined/master/internal/db"
-	"github.com/determined-ai/determined/master/internal/telemetry"
+	"github.com/determined-ai/determined/master/internal/prom"
 	"github.com/determined-ai/determined/master/internal/user"
 	"github.com/determined-ai/determined/master/internal/webhooks"
 	"github.com/determined-ai/determined/master/pkg/actor"
@@ -22,6 +23,42 @@ import (
 // shimmed. Experiment and trial snapshots share a version currently.
 const experimentSnapshotVersion = 5
 
+// restoreProgress tracks how many experiments a restore run has finished out of its total.
+type restoreProgress struct {
+	mu        sync.Mutex
+	total     int
+	completed int
+}
+
+// restoreProgressSnapshot is the externally visible state of a restore run.
+type restoreProgressSnapshot struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+}
+
+// reset starts a new restore run of total experiments.
+func (p *restoreProgress) reset(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total, p.completed = total, 0
+	prom.ExperimentRestores.Set(float64(total))
+	prom.ExperimentRestoresCompleted.Set(0)
+}
+
+// done records that one more experiment has finished restoring.
+func (p *restoreProgress) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed++
+	prom.ExperimentRestoresCompleted.Set(float64(p.completed))
+}
+
+func (p *restoreProgress) snapshot() restoreProgressSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return restoreProgressSnapshot{Total: p.total, Completed: p.completed}
+}
+
 // Restore works by restoring from distributed consistent snapshots taken through the course
 // of an experiment. Snapshots within the system flow from the bottom up, starting with the
 // trial workload sequencer, to the trial, and finally to the experiment. Any event that the
@@ -64,7 +101,7 @@ func (m *Master) restoreExperiment(expModel *model.Experiment) error {
 			return errors.Wrapf(err, "terminating experiment %d", expModel.ID)
 		}
 		expModel.State = terminal
-		telemetry.ReportExperimentStateChanged(m.system, m.db, *expModel)
+		m.expStateReports.Report(*expModel)
 		if err := webhooks.ReportExperimentStateChanged(
 			context.TODO(), *expModel, activeConfig,
 		); err != nil {
@@ -117,7 +154,7 @@ func (m *Master) restoreExperiment(expModel *model.Experiment) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to restore experiment %d", expModel.ID)
 	}
-	e, _, err := newExperiment(m, expModel, activeConfig, &taskSpec)
+	e, _, err := newExperiment(m, expModel, activeConfig, &taskSpec, nil)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create experiment %d from model", expModel.ID)
 	}
@@ -127,6 +127,8 @@ func (a *agent) receive(ctx *actor.Context, msg interface{}) error {
 		a.slots, _ = ctx.ActorOf("slots", &slots{})
 	case model.AgentSummary:
 		ctx.Respond(a.summarize(ctx))
+	case getAgentConnection:
+		ctx.Respond(agentConnection{connected: a.socket != nil && !a.awaitingReconnect})
 	case ws.WebSocketConnected:
 		check.Panic(check.True(a.socket == nil, "websocket already connected"))
 		socket, ok := msg.Accept(ctx, aproto.MasterMessage{}, true)
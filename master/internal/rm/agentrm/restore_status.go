@@ -0,0 +1,69 @@
+package agentrm
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/actor"
+	"github.com/determined-ai/determined/master/pkg/aproto"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+const agentConnectionAskTimeout = 5 * time.Second
+
+// getAgentConnection asks an agent whether it is connected.
+type getAgentConnection struct{}
+
+// agentConnection is the response to getAgentConnection.
+type agentConnection struct {
+	connected bool
+}
+
+// ExpectedContainer is a container that the agent resource manager expects to find on an agent
+// when restoring an allocation, along with whether that agent has reconnected yet.
+type ExpectedContainer struct {
+	AgentID     aproto.ID    `json:"agent_id"`
+	ContainerID cproto.ID    `json:"container_id"`
+	State       cproto.State `json:"state"`
+	// AgentRegistered is whether the agent is known to the resource manager at all; agents that
+	// failed to reconnect in time are removed.
+	AgentRegistered bool `json:"agent_registered"`
+	// AgentReconnected is whether the agent has connected to this master since it started.
+	AgentReconnected bool `json:"agent_reconnected"`
+}
+
+// ExpectedContainers returns the containers the agent resource manager expects the given
+// allocation to have, as recorded in the database, and whether their agents have reconnected.
+func ExpectedContainers(
+	ctx context.Context, system *actor.System, allocationID model.AllocationID,
+) ([]ExpectedContainer, error) {
+	var snapshots []containerSnapshot
+	if err := db.Bun().NewSelect().Model(&snapshots).
+		Relation("ResourcesWithState").
+		Where("resources_with_state.allocation_id = ?", allocationID).
+		Scan(ctx); err != nil {
+		return nil, errors.Wrapf(err, "fetching containers for allocation %s", allocationID)
+	}
+
+	expected := make([]ExpectedContainer, 0, len(snapshots))
+	for _, cs := range snapshots {
+		c := ExpectedContainer{AgentID: cs.AgentID, ContainerID: cs.ID, State: cs.State}
+		if ref := system.Get(sproto.AgentsAddr.Child(string(cs.AgentID))); ref != nil {
+			c.AgentRegistered = true
+			resp, ok := system.Ask(ref, getAgentConnection{}).GetOrTimeout(agentConnectionAskTimeout)
+			if !ok {
+				return nil, errors.Errorf("timed out asking agent %s for its connection", cs.AgentID)
+			}
+			if conn, ok := resp.(agentConnection); ok {
+				c.AgentReconnected = conn.connected
+			}
+		}
+		expected = append(expected, c)
+	}
+	return expected, nil
+}
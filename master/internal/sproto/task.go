@@ -65,6 +65,7 @@ type (
 		Port            int
 		ProxyTCP        bool
 		Unauthenticated bool
+		FlushInterval   time.Duration
 	}
 
 	// EventStreamConfig configures an event stream.
@@ -311,3 +312,27 @@ func (ev *Event) ToTaskLog() model.TaskLog {
 
 // ResourceList is a wrapper for a list of resources.
 type ResourceList map[ResourcesID]Resources
+
+// SlotTypeMixed is the slot type of resources backed by more than one type of device.
+const SlotTypeMixed = "mixed"
+
+// SlotType returns the type of device backing the resources, SlotTypeMixed if they are backed by
+// more than one type, or "" if it is unknown, e.g. because the RM does not report devices.
+func (rl ResourceList) SlotType() string {
+	var slotType device.Type
+	for _, r := range rl {
+		for _, devs := range r.Summary().AgentDevices {
+			for _, d := range devs {
+				switch {
+				case d.Type == device.ZeroSlot:
+					continue
+				case slotType == device.ZeroSlot:
+					slotType = d.Type
+				case slotType != d.Type:
+					return SlotTypeMixed
+				}
+			}
+		}
+	}
+	return string(slotType)
+}
@@ -441,6 +441,12 @@ func (a *Allocation) ResourcesAllocated(ctx *actor.Context, msg sproto.Resources
 	if err := a.db.UpdateAllocationState(a.model); err != nil {
 		return errors.Wrap(err, "updating allocation state")
 	}
+	if slotType := msg.Resources.SlotType(); !a.req.Restore && slotType != "" {
+		a.model.SlotType = &slotType
+		if err := a.db.UpdateAllocationSlotType(a.model); err != nil {
+			return errors.Wrap(err, "updating allocation slot type")
+		}
+	}
 
 	now := time.Now().UTC()
 	err := a.db.RecordTaskStats(&model.TaskStats{
@@ -843,6 +849,7 @@ func (a *Allocation) registerProxies(ctx *actor.Context, addresses []cproto.Addr
 			},
 			ProxyTCP:        cfg.ProxyTCP,
 			Unauthenticated: cfg.Unauthenticated,
+			FlushInterval:   cfg.FlushInterval,
 		})
 		a.proxies = append(a.proxies, cfg.ServiceID)
 	}
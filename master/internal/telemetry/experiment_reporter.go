@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"context"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/prom"
+	"github.com/determined-ai/determined/master/pkg/actor"
+	"github.com/determined-ai/determined/master/pkg/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// ExperimentStateReporter reports experiment state changes to the telemetry actor in the
+// background. Reports are queued without blocking and sent in batches, so that callers fanning
+// out over many experiments (e.g. restore) are never held up by the database lookups a report
+// requires. Reports that arrive while the queue is full are dropped and counted.
+type ExperimentStateReporter struct {
+	system    *actor.System
+	db        *db.PgDB
+	batchSize int
+	queue     chan model.Experiment
+}
+
+// NewExperimentStateReporter starts a reporter with the given configuration, which stops sending
+// reports once ctx is done. If telemetry is disabled, the returned reporter discards all reports.
+func NewExperimentStateReporter(
+	ctx context.Context, system *actor.System, db *db.PgDB, conf config.TelemetryConfig,
+) *ExperimentStateReporter {
+	r := &ExperimentStateReporter{
+		system:    system,
+		db:        db,
+		batchSize: conf.ExperimentStateReports.BatchSize,
+	}
+	if !conf.Enabled || conf.SegmentMasterKey == "" {
+		return r
+	}
+
+	r.queue = make(chan model.Experiment, conf.ExperimentStateReports.BufferSize)
+	for i := 0; i < conf.ExperimentStateReports.Workers; i++ {
+		go r.run(ctx)
+	}
+	return r
+}
+
+// Report queues a report that the state of an experiment has changed. It never blocks.
+func (r *ExperimentStateReporter) Report(e model.Experiment) {
+	if r.queue == nil {
+		return
+	}
+	select {
+	case r.queue <- e:
+	default:
+		prom.TelemetryExperimentReportsDropped.Inc()
+	}
+}
+
+func (r *ExperimentStateReporter) run(ctx context.Context) {
+	for {
+		var e model.Experiment
+		select {
+		case e = <-r.queue:
+		case <-ctx.Done():
+			return
+		}
+		batch := trackBatch{experimentStateChangedTrack(r.db, e)}
+	fill:
+		for len(batch) < r.batchSize {
+			select {
+			case e := <-r.queue:
+				batch = append(batch, experimentStateChangedTrack(r.db, e))
+			default:
+				break fill
+			}
+		}
+		r.system.TellAt(actor.Addr("telemetry"), batch)
+	}
+}
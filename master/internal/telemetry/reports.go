@@ -166,6 +166,11 @@ func fetchTotalStepTime(db *db.PgDB, experimentID int) *float64 {
 
 // ReportExperimentStateChanged reports that the state of an experiment has changed.
 func ReportExperimentStateChanged(system *actor.System, db *db.PgDB, e model.Experiment) {
+	system.TellAt(actor.Addr("telemetry"), experimentStateChangedTrack(db, e))
+}
+
+// experimentStateChangedTrack builds the telemetry event for an experiment state change.
+func experimentStateChangedTrack(db *db.PgDB, e model.Experiment) analytics.Track {
 	var numTrials *int64
 	var numSteps *int64
 	var totalStepTime *float64
@@ -178,21 +183,18 @@ func ReportExperimentStateChanged(system *actor.System, db *db.PgDB, e model.Exp
 		totalStepTime = fetchTotalStepTime(db, e.ID)
 	}
 
-	system.TellAt(
-		actor.Addr("telemetry"),
-		analytics.Track{
-			Event: "experiment_state_changed",
-			Properties: map[string]interface{}{
-				"id":              e.ID,
-				"state":           e.State,
-				"start_time":      e.StartTime,
-				"end_time":        e.EndTime,
-				"num_trials":      numTrials,
-				"num_steps":       numSteps,
-				"total_step_time": totalStepTime,
-			},
+	return analytics.Track{
+		Event: "experiment_state_changed",
+		Properties: map[string]interface{}{
+			"id":              e.ID,
+			"state":           e.State,
+			"start_time":      e.StartTime,
+			"end_time":        e.EndTime,
+			"num_trials":      numTrials,
+			"num_steps":       numSteps,
+			"total_step_time": totalStepTime,
 		},
-	)
+	}
 }
 
 // ReportUserCreated reports that a user has been created.
@@ -22,6 +22,7 @@ func Setup(
 			rm,
 			clusterID,
 			conf.SegmentMasterKey,
+			conf.DeploymentProperties(),
 		); tErr != nil {
 			log.WithError(tErr).Errorf("failed to initialize telemetry")
 		} else {
@@ -29,12 +29,17 @@ type telemetryRPFetcher interface {
 
 type telemetryTick struct{}
 
+// trackBatch is a batch of events to enqueue together.
+type trackBatch []analytics.Track
+
 // TelemetryActor manages gathering and sending telemetry data.
 type TelemetryActor struct {
 	db        db.DB
 	rm        telemetryRPFetcher
 	client    analytics.Client
 	clusterID string
+	// deployment holds properties identifying this deployment that are added to every track.
+	deployment analytics.Properties
 }
 
 // New creates an actor to handle collecting and sending telemetry information.
@@ -43,6 +48,7 @@ func New(
 	rm telemetryRPFetcher,
 	clusterID string,
 	segmentKey string,
+	deployment analytics.Properties,
 ) (*TelemetryActor, error) {
 	client, err := analytics.NewWithConfig(
 		segmentKey,
@@ -52,16 +58,36 @@ func New(
 		return nil, err
 	}
 
+	traits := analytics.Traits{
+		"master_version": version.Version,
+	}
+	for k, v := range deployment {
+		traits[k] = v
+	}
 	if err := client.Enqueue(analytics.Identify{
 		UserId: clusterID,
-		Traits: analytics.Traits{
-			"master_version": version.Version,
-		},
+		Traits: traits,
 	}); err != nil {
 		logrus.WithError(err).Warnf("failed to enqueue identity %s", clusterID)
 	}
 
-	return &TelemetryActor{db, rm, client, clusterID}, nil
+	return &TelemetryActor{db, rm, client, clusterID, deployment}, nil
+}
+
+// enqueue attributes a track to this cluster and deployment and queues it to be sent.
+func (s *TelemetryActor) enqueue(track analytics.Track) error {
+	track.UserId = s.clusterID
+	if len(s.deployment) > 0 {
+		props := make(analytics.Properties, len(track.Properties)+len(s.deployment))
+		for k, v := range track.Properties {
+			props[k] = v
+		}
+		for k, v := range s.deployment {
+			props[k] = v
+		}
+		track.Properties = props
+	}
+	return s.client.Enqueue(track)
 }
 
 // Receive implements the actor.Actor interface.
@@ -71,11 +97,17 @@ func (s *TelemetryActor) Receive(ctx *actor.Context) error {
 		actors.NotifyAfter(ctx, 0, telemetryTick{})
 
 	case analytics.Track:
-		msg.UserId = s.clusterID
-		if err := s.client.Enqueue(msg); err != nil {
+		if err := s.enqueue(msg); err != nil {
 			ctx.Log().WithError(err).Warnf("failed to enqueue track %s", msg.Event)
 		}
 
+	case trackBatch:
+		for _, track := range msg {
+			if err := s.enqueue(track); err != nil {
+				ctx.Log().WithError(err).Warnf("failed to enqueue track %s", track.Event)
+			}
+		}
+
 	case telemetryTick:
 		// Tick in a random interval.
 		//nolint:gosec // Weak RNG is fine here.
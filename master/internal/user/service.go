@@ -45,7 +45,12 @@ var unauthenticatedPointsList = []string{
 	"/",
 	"/docs/.*",
 	"/info",
+	"/info/uptime",
+	"/ready",
+	"/health",
+	"/sso/providers",
 	"/task-logs",
+	"/task-logs/bulk",
 	"/agents",
 	"/det",
 	"/det/.*",
@@ -53,6 +58,11 @@ var unauthenticatedPointsList = []string{
 	"/api/v1/.*",
 	"/proxy/:service/.*",
 	"/agents\\?id=.*",
+	// The metrics and profiling endpoints authenticate requests themselves, since they may be
+	// configured to accept a bearer token in place of a user session.
+	"/debug/pprof/.*",
+	"/debug/prom/metrics",
+	"/prom/.*",
 }
 
 // adminAuthPointsList contains the paths that require admin authentication.
@@ -164,36 +174,53 @@ func (s *Service) getAuthLevel(c echo.Context) int {
 // to authenticate incoming HTTP requests.
 func (s *Service) ProcessAuthentication(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		var adminOnly bool
 		switch s.getAuthLevel(c) {
 		case authNone:
 			return next(c)
-		case authStandard:
-			adminOnly = false
 		case authAdmin:
-			adminOnly = true
+			return s.authenticate(c, true, next)
+		default:
+			return s.authenticate(c, false, next)
 		}
+	}
+}
 
-		user, session, err := s.UserAndSessionFromRequest(c.Request())
-		switch err {
-		case nil:
-			if !user.Active {
-				return echo.NewHTTPError(http.StatusForbidden, "user not active")
-			}
-			if adminOnly && !user.Admin {
-				return echo.NewHTTPError(http.StatusForbidden, "user not admin")
-			}
-
-			// Set data on the request context that might be useful to
-			// event handlers.
-			c.(*detContext.DetContext).SetUser(*user)
-			c.(*detContext.DetContext).SetUserSession(*session)
-			return next(c)
-		case db.ErrNotFound:
-			return echo.NewHTTPError(http.StatusUnauthorized)
-		default:
-			return err
+// RequireAuthentication is a middleware processing function that authenticates
+// incoming HTTP requests even if their path is otherwise exempted from authentication.
+func (s *Service) RequireAuthentication(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return s.authenticate(c, false, next)
+	}
+}
+
+// RequireAdminAuthentication is a middleware processing function that authenticates incoming
+// HTTP requests as coming from an admin, regardless of their path.
+func (s *Service) RequireAdminAuthentication(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return s.authenticate(c, true, next)
+	}
+}
+
+func (s *Service) authenticate(c echo.Context, adminOnly bool, next echo.HandlerFunc) error {
+	user, session, err := s.UserAndSessionFromRequest(c.Request())
+	switch err {
+	case nil:
+		if !user.Active {
+			return echo.NewHTTPError(http.StatusForbidden, "user not active")
 		}
+		if adminOnly && !user.Admin {
+			return echo.NewHTTPError(http.StatusForbidden, "user not admin")
+		}
+
+		// Set data on the request context that might be useful to
+		// event handlers.
+		c.(*detContext.DetContext).SetUser(*user)
+		c.(*detContext.DetContext).SetUserSession(*session)
+		return next(c)
+	case db.ErrNotFound:
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	default:
+		return err
 	}
 }
 
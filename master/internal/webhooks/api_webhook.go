@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"time"
@@ -109,51 +110,28 @@ func (a *WebhooksAPIServer) TestWebhook(
 	log.Infof("creating webhook payload for event %v", eventID)
 
 	var tReq *http.Request
+	t := time.Now().Unix()
+	p, err := testEventPayload(webhook.WebhookType, t)
+	if err != nil {
+		return nil, err
+	}
 	switch webhook.WebhookType {
 	case WebhookTypeDefault:
-		t := time.Now().Unix()
-		p, perr := json.Marshal(EventPayload{
-			ID:        uuid.New(),
-			Timestamp: t,
-			Type:      TriggerTypeStateChange,
-			Condition: Condition{
-				State: "COMPLETED",
-			},
-			Data: EventData{
-				TestData: ptrs.Ptr("test"),
-			},
-		})
-		if perr != nil {
-			return nil, err
-		}
-
 		tr, rerr := generateWebhookRequest(ctx, webhook.URL, p, t)
 		if rerr != nil {
 			return nil, status.Errorf(codes.InvalidArgument,
 				"failed to create webhook request for event %v error : %v ", eventID, err)
 		}
+		if secrets := webhook.activeSigningSecrets(time.Now()); len(secrets) > 0 {
+			tr.Header.Set(signatureHeader, payloadSignatures(p, secrets))
+		}
 		tReq = tr
 	case WebhookTypeSlack:
-		slackMessage, serr := json.Marshal(SlackMessageBody{
-			Blocks: []SlackBlock{
-				{
-					Text: SlackField{
-						Text: "test",
-						Type: "plain_text",
-					},
-					Type: "section",
-				},
-			},
-		})
-		if serr != nil {
-			return nil, err
-		}
-
 		tr, rerr := http.NewRequestWithContext(
 			ctx,
 			http.MethodPost,
 			webhook.URL,
-			bytes.NewBuffer(slackMessage),
+			bytes.NewBuffer(p),
 		)
 		if rerr != nil {
 			return nil, status.Errorf(codes.InvalidArgument,
@@ -183,3 +161,35 @@ func (a *WebhooksAPIServer) TestWebhook(
 	}
 	return &apiv1.TestWebhookResponse{}, nil
 }
+
+// testEventPayload returns the payload of a test event for a webhook of the given type.
+func testEventPayload(webhookType WebhookType, t int64) ([]byte, error) {
+	switch webhookType {
+	case WebhookTypeDefault:
+		return json.Marshal(EventPayload{
+			ID:        uuid.New(),
+			Timestamp: t,
+			Type:      TriggerTypeStateChange,
+			Condition: Condition{
+				State: "COMPLETED",
+			},
+			Data: EventData{
+				TestData: ptrs.Ptr("test"),
+			},
+		})
+	case WebhookTypeSlack:
+		return json.Marshal(SlackMessageBody{
+			Blocks: []SlackBlock{
+				{
+					Text: SlackField{
+						Text: "test",
+						Type: "plain_text",
+					},
+					Type: "section",
+				},
+			},
+		})
+	default:
+		return nil, fmt.Errorf("unknown webhook type %q", webhookType)
+	}
+}
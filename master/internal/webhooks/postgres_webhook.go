@@ -67,6 +67,45 @@ func GetWebhooks(ctx context.Context) (Webhooks, error) {
 	return webhooks, nil
 }
 
+// GetWebhooksWithTrigger returns the Webhooks from the DB with Triggers of the given type, along
+// with only those Triggers.
+func GetWebhooksWithTrigger(ctx context.Context, triggerType TriggerType) (Webhooks, error) {
+	webhooks := Webhooks{}
+	err := db.Bun().NewSelect().
+		Model(&webhooks).
+		Relation("Triggers", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("trigger_type = ?", triggerType)
+		}).
+		Where(`EXISTS (
+	SELECT 1 FROM webhook_triggers t WHERE t.webhook_id = webhook.id AND t.trigger_type = ?
+)`, triggerType).
+		Order("webhook.id").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// RotateSigningSecret makes secret the signing secret of a Webhook. The secret it replaces keeps
+// signing payloads as well until the transition period ends, so receivers can switch over.
+func RotateSigningSecret(
+	ctx context.Context, id WebhookID, secret string, transition time.Duration,
+) (*Webhook, error) {
+	var webhook Webhook
+	if err := db.Bun().NewUpdate().
+		Model(&webhook).
+		Set("previous_signing_secret = signing_secret").
+		Set("previous_signing_secret_expires_at = ?", time.Now().Add(transition)).
+		Set("signing_secret = ?", secret).
+		Where("id = ?", id).
+		Returning("*").
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
 // DeleteWebhook deletes a Webhook and its Triggers from the DB.
 func DeleteWebhook(ctx context.Context, id WebhookID) error {
 	_, err := db.Bun().NewDelete().Model((*Webhook)(nil)).Where("id = ?", id).Exec(ctx)
@@ -76,6 +115,43 @@ func DeleteWebhook(ctx context.Context, id WebhookID) error {
 	return nil
 }
 
+// GetDeadLetters returns all dead-lettered events from the DB, oldest first.
+func GetDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	deadLetters := []DeadLetter{}
+	if err := db.Bun().NewSelect().Model(&deadLetters).Order("id").Scan(ctx); err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+// RedeliverDeadLetter moves a dead-lettered event back to the queue to be delivered again.
+func RedeliverDeadLetter(ctx context.Context, id DeadLetterID) error {
+	err := db.Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var deadLetter DeadLetter
+		if err := tx.NewDelete().
+			Model(&deadLetter).
+			Where("id = ?", id).
+			Returning("*").
+			Scan(ctx); err != nil {
+			return err
+		}
+		_, err := tx.NewInsert().
+			Model(&Event{
+				URL:       deadLetter.URL,
+				Payload:   deadLetter.Payload,
+				WebhookID: deadLetter.WebhookID,
+			}).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	wakeShipper()
+	return nil
+}
+
 // ReportExperimentStateChanged adds webhook events to the queue.
 // TODO(DET-8577): Remove unnecessary active config usage (remove the activeConfig parameter).
 func ReportExperimentStateChanged(
@@ -106,13 +182,13 @@ func ReportExperimentStateChanged(
 		if err != nil {
 			return fmt.Errorf("error generating event payload: %w", err)
 		}
-		es = append(es, Event{Payload: p, URL: t.Webhook.URL})
+		es = append(es, Event{Payload: p, URL: t.Webhook.URL, WebhookID: &t.Webhook.ID})
 	}
 	if _, err := db.Bun().NewInsert().Model(&es).Exec(ctx); err != nil {
 		return err
 	}
 
-	singletonShipper.Wake()
+	wakeShipper()
 	return nil
 }
 
@@ -312,5 +388,37 @@ WHERE q.id = webhook_events_queue.id RETURNING webhook_events_queue.*
 `, limit).Scan(ctx, &events); err != nil {
 		return nil, fmt.Errorf("scanning events: %w", err)
 	}
+	if err = loadSigningSecrets(ctx, tx, events); err != nil {
+		return nil, fmt.Errorf("loading webhook signing secrets: %w", err)
+	}
 	return &eventBatch{tx: &tx, events: events}, nil
 }
+
+// loadSigningSecrets sets the active signing secrets of the webhooks of events, in one query for
+// the batch rather than one for each delivery attempt. Events of deleted webhooks get none.
+func loadSigningSecrets(ctx context.Context, tx bun.Tx, events []Event) error {
+	var ids []WebhookID
+	for _, e := range events {
+		if e.WebhookID != nil {
+			ids = append(ids, *e.WebhookID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var webhooks []Webhook
+	if err := tx.NewSelect().Model(&webhooks).Where("id IN (?)", bun.In(ids)).Scan(ctx); err != nil {
+		return err
+	}
+	now := time.Now()
+	secrets := make(map[WebhookID][]string, len(webhooks))
+	for i := range webhooks {
+		secrets[webhooks[i].ID] = webhooks[i].activeSigningSecrets(now)
+	}
+	for i, e := range events {
+		if e.WebhookID != nil {
+			events[i].signingSecrets = secrets[*e.WebhookID]
+		}
+	}
+	return nil
+}
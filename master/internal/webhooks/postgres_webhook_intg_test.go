@@ -58,6 +58,39 @@ func TestWebhooks(t *testing.T) {
 			"did not retriee correct number of triggers")
 	})
 
+	t.Run("filtering webhooks by trigger type should work", func(t *testing.T) {
+		w := Webhook{
+			URL:         "http://localhost:8080/metric",
+			WebhookType: WebhookTypeDefault,
+			Triggers: Triggers{
+				{
+					TriggerType: TriggerTypeMetricThresholdExceeded,
+					Condition:   map[string]interface{}{"metric": "loss"},
+				},
+				{
+					TriggerType: TriggerTypeStateChange,
+					Condition:   map[string]interface{}{"state": "COMPLETED"},
+				},
+			},
+		}
+		require.NoError(t, AddWebhook(ctx, &w))
+
+		for _, triggerType := range []TriggerType{
+			TriggerTypeMetricThresholdExceeded, TriggerTypeStateChange,
+		} {
+			webhooks, err := GetWebhooksWithTrigger(ctx, triggerType)
+			require.NoError(t, err)
+			require.Contains(t, getWebhookIds(webhooks), w.ID)
+			require.Len(t, getWebhookByID(webhooks, w.ID).Triggers, 1)
+			for _, webhook := range webhooks {
+				require.NotEmpty(t, webhook.Triggers)
+				for _, trigger := range webhook.Triggers {
+					require.Equal(t, triggerType, trigger.TriggerType)
+				}
+			}
+		}
+	})
+
 	t.Run("Deleting a webhook should work", func(t *testing.T) {
 		testWebhookThree.Triggers = testTriggersThree
 
@@ -6,9 +6,12 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,27 +20,73 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	conf "github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/prom"
 )
 
 const (
 	maxWorkers        = 3
 	maxEventBatchSize = 10
-
-	backoffAttempts = 2
-	backoffInterval = time.Second
-	backoffMax      = time.Minute
 )
 
-var singletonShipper *shipper
+// testEventTimeout bounds how long a test event waits on a webhook's endpoint, since an admin is
+// waiting on the response.
+var testEventTimeout = 10 * time.Second
+
+var (
+	singletonShipperMu sync.RWMutex
+	singletonShipper   *shipper
+	// drainingShippers are the shippers replaced by Reload that are still finishing their batches.
+	drainingShippers = map[*shipper]bool{}
+	drains           sync.WaitGroup
+)
 
 // Init creates a shipper singleton.
 func Init() {
+	singletonShipperMu.Lock()
+	defer singletonShipperMu.Unlock()
 	singletonShipper = newShipper()
 }
 
-// Deinit closes a shipper.
+// Deinit closes a shipper, along with any shippers still draining after a reload.
 func Deinit() {
+	singletonShipperMu.Lock()
 	singletonShipper.Close()
+	for s := range drainingShippers {
+		s.Close()
+	}
+	singletonShipperMu.Unlock()
+
+	drains.Wait()
+}
+
+// Reload replaces the shipper singleton with a new one, which restarts any crashed workers and
+// picks up the webhook configuration and queued events afresh. The old shipper finishes the
+// batches it is delivering in the background, which may take as long as their retries, before it
+// stops; since events are only dequeued when their batch commits, nothing in flight is lost or
+// delivered twice, and the two shippers never take the same events.
+func Reload() {
+	singletonShipperMu.Lock()
+	old := singletonShipper
+	singletonShipper = newShipper()
+	drainingShippers[old] = true
+	drains.Add(1)
+	singletonShipperMu.Unlock()
+
+	go func() {
+		defer drains.Done()
+		old.Drain()
+
+		singletonShipperMu.Lock()
+		defer singletonShipperMu.Unlock()
+		delete(drainingShippers, old)
+	}()
+}
+
+// wakeShipper wakes the shipper singleton.
+func wakeShipper() {
+	singletonShipperMu.RLock()
+	defer singletonShipperMu.RUnlock()
+	singletonShipper.Wake()
 }
 
 type shipper struct {
@@ -45,9 +94,11 @@ type shipper struct {
 	log *log.Entry
 
 	// Internal state.
-	wake   chan<- struct{}
-	wg     sync.WaitGroup
-	cancel context.CancelFunc
+	wake     chan<- struct{}
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 func newShipper() *shipper {
@@ -59,6 +110,7 @@ func newShipper() *shipper {
 		log:    log.WithField("component", "webhook-sender"),
 		wake:   wake,
 		cancel: cancel,
+		stop:   make(chan struct{}),
 	}
 
 	for i := 0; i < maxWorkers; i++ {
@@ -67,7 +119,7 @@ func newShipper() *shipper {
 		s.wg.Add(1)
 		go func() {
 			defer s.wg.Done()
-			w.work(ctx, wake)
+			w.work(ctx, wake, s.stop)
 		}()
 	}
 
@@ -87,10 +139,19 @@ func (s *shipper) Wake() {
 }
 
 func (s *shipper) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
 	s.cancel()
 	s.wg.Wait()
 }
 
+// Drain stops the shipper once its workers finish delivering their current batches, leaving the
+// rest of the queue for another shipper.
+func (s *shipper) Drain() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.wg.Wait()
+	s.cancel()
+}
+
 func newWorker(id int) *worker {
 	return &worker{
 		log: log.WithFields(log.Fields{"component": "webhook-shipper-worker", "id": id}),
@@ -104,7 +165,7 @@ type worker struct {
 	cl  *http.Client
 }
 
-func (w *worker) work(ctx context.Context, wake <-chan struct{}) {
+func (w *worker) work(ctx context.Context, wake <-chan struct{}, stop <-chan struct{}) {
 	defer func() {
 		if rec := recover(); rec != nil {
 			w.log.Errorf("uncaught error, webhook worker crashed: %v", rec)
@@ -114,18 +175,27 @@ func (w *worker) work(ctx context.Context, wake <-chan struct{}) {
 	for {
 		select {
 		case <-wake:
-			if err := w.ship(ctx); err != nil {
+			if err := w.ship(ctx, stop); err != nil {
 				w.log.WithError(err).Error("failed to ship batch")
 			}
+		case <-stop:
+			return
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (w *worker) ship(ctx context.Context) error {
+func (w *worker) ship(ctx context.Context, stop <-chan struct{}) error {
 loop:
 	for {
+		select {
+		case <-stop:
+			// Leave the remaining events to whichever shipper replaces this one.
+			return nil
+		default:
+		}
+
 		switch n, err := w.shipBatch(ctx); {
 		case err != nil:
 			return err
@@ -150,60 +220,148 @@ func (w *worker) shipBatch(ctx context.Context) (int, error) {
 	}()
 
 	var wg sync.WaitGroup
-	for _, e := range b.events {
+	deadLetters := make([]*DeadLetter, len(b.events))
+	for i, e := range b.events {
 		wg.Add(1)
-		go func(e Event) {
+		go func(i int, e Event) {
 			defer wg.Done()
+			attempts := 0
 			if err := back.Retry(
-				func() error { return w.deliver(ctx, e) },
+				func() error {
+					attempts++
+					return w.deliver(ctx, e)
+				},
 				backoff(),
 			); err != nil {
 				w.log.WithError(err).Error("failed to deliver webhook")
+				deadLetters[i] = &DeadLetter{
+					URL:       e.URL,
+					Payload:   e.Payload,
+					WebhookID: e.WebhookID,
+					Error:     err.Error(),
+					Attempts:  attempts,
+				}
 			}
-		}(e)
+		}(i, e)
 	}
 	wg.Wait()
 	if err := ctx.Err(); err != nil {
 		return 0, err
 	}
 
+	// Events that failed for good are kept in the same transaction that dequeues them, so they
+	// are either dead-lettered or left in the queue.
+	for _, deadLetter := range deadLetters {
+		if deadLetter == nil {
+			continue
+		}
+		if _, err := b.tx.NewInsert().Model(deadLetter).Exec(ctx); err != nil {
+			return 0, fmt.Errorf("dead-lettering event: %w", err)
+		}
+	}
+
 	if err := b.commit(); err != nil {
 		return 0, fmt.Errorf("consuming batch: %w", err)
 	}
+	for _, deadLetter := range deadLetters {
+		if deadLetter != nil {
+			prom.WebhookDeadLetters.Inc()
+		}
+	}
 	return len(b.events), nil
 }
 
 func backoff() back.BackOff {
+	retry := conf.GetMasterConfig().Webhooks.Retry
 	bf := back.NewExponentialBackOff()
-	bf.InitialInterval = backoffInterval
-	bf.MaxInterval = backoffMax
-	return back.WithMaxRetries(bf, backoffAttempts)
+	bf.InitialInterval = time.Duration(retry.InitialInterval)
+	bf.MaxInterval = time.Duration(retry.MaxInterval)
+	// Retries are limited by count rather than time.
+	bf.MaxElapsedTime = 0
+	return back.WithMaxRetries(bf, uint64(retry.MaxRetries))
 }
 
 func (w *worker) deliver(ctx context.Context, e Event) error {
-	req, err := generateWebhookRequest(ctx, e.URL, e.Payload, time.Now().Unix())
+	prom.WebhookDeliveryAttempts.Inc()
+	statusCode, err := sendEvent(ctx, w.cl, w.log, e)
 	if err != nil {
 		return err
 	}
 
-	resp, err := w.cl.Do(req)
+	switch {
+	case statusCode >= 500:
+		return fmt.Errorf("request returned %v", statusCode)
+	case statusCode >= 400:
+		return back.Permanent(fmt.Errorf("request returned %v", statusCode))
+	default:
+		prom.WebhookDeliveries.Inc()
+		return nil
+	}
+}
+
+// sendEvent signs and sends an event to its webhook, returning the status code of the response.
+func sendEvent(ctx context.Context, cl *http.Client, logger *log.Entry, e Event) (int, error) {
+	req, err := generateWebhookRequest(ctx, e.URL, e.Payload, time.Now().Unix())
 	if err != nil {
-		return fmt.Errorf("sending webhook request: %w", err)
+		return 0, err
 	}
-	defer func() {
-		if err = resp.Body.Close(); err != nil {
-			w.log.WithError(err).Warn("failed to close response body")
-		}
-	}()
+	if len(e.signingSecrets) > 0 {
+		req.Header.Set(signatureHeader, payloadSignatures(e.Payload, e.signingSecrets))
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending webhook request: %w", err)
+	}
+	if err = resp.Body.Close(); err != nil {
+		logger.WithError(err).Warn("failed to close response body")
+	}
+	return resp.StatusCode, nil
+}
+
+// TestDelivery is the outcome of sending a test event to a webhook.
+type TestDelivery struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMS  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
 
+// SendTestEvent sends a test event to a webhook the same way queued events are delivered, but
+// without retries, and reports how the webhook's endpoint responded. Failures to reach the
+// endpoint, such as DNS or TLS errors, and error responses are reported in the TestDelivery rather
+// than returned, including endpoints that don't respond within testEventTimeout.
+func SendTestEvent(ctx context.Context, webhookID int) (*TestDelivery, error) {
+	webhook, err := GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := testEventPayload(webhook.WebhookType, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	logger := log.WithFields(log.Fields{"component": "webhook-tester", "webhook-id": webhookID})
+	cl := cleanhttp.DefaultClient()
+	cl.Timeout = testEventTimeout
+	start := time.Now()
+	statusCode, err := sendEvent(ctx, cl, logger, Event{
+		URL:            webhook.URL,
+		Payload:        payload,
+		WebhookID:      &webhook.ID,
+		signingSecrets: webhook.activeSigningSecrets(time.Now()),
+	})
+	delivery := &TestDelivery{StatusCode: statusCode, LatencyMS: time.Since(start).Milliseconds()}
+	var netErr net.Error
 	switch {
-	case resp.StatusCode >= 500:
-		return fmt.Errorf("request returned %v: %w", resp.StatusCode, err)
-	case resp.StatusCode >= 400:
-		return back.Permanent(fmt.Errorf("request returned %v: %w", resp.StatusCode, err))
-	default:
-		return nil
+	case errors.As(err, &netErr) && netErr.Timeout():
+		delivery.Error = fmt.Sprintf("webhook endpoint did not respond within %s", testEventTimeout)
+	case err != nil:
+		delivery.Error = err.Error()
+	case statusCode >= 400:
+		delivery.Error = fmt.Sprintf("webhook endpoint returned %d %s",
+			statusCode, http.StatusText(statusCode))
 	}
+	return delivery, nil
 }
 
 func generateWebhookRequest(
@@ -224,6 +382,20 @@ func generateWebhookRequest(
 	return req, nil
 }
 
+// signatureHeader carries the HMAC-SHA256 signatures of a payload made with the signing secrets of
+// its webhook, as comma-separated "sha256=<hex digest>" values, the current secret's first.
+const signatureHeader = "X-Determined-Signature"
+
+func payloadSignatures(payload []byte, secrets []string) string {
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		signatures = append(signatures, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return strings.Join(signatures, ",")
+}
+
 func generateSignedPayload(req *http.Request, t int64, key []byte) string {
 	body := req.GetBody
 	bodyCopy, _ := body()
@@ -5,8 +5,13 @@ package webhooks
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
@@ -203,6 +208,219 @@ func TestShipper(t *testing.T) {
 	}
 }
 
+func TestSendTestEvent(t *testing.T) {
+	ctx := context.Background()
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+	clearWebhooksTables(ctx, t)
+
+	statusCode := atomic.NewInt64(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(statusCode.Load()))
+	}))
+	defer server.Close()
+
+	w := &Webhook{
+		URL: server.URL,
+		Triggers: []*Trigger{{
+			TriggerType: TriggerTypeStateChange,
+			Condition:   map[string]interface{}{"state": model.CompletedState},
+		}},
+		WebhookType: WebhookTypeDefault,
+	}
+	require.NoError(t, AddWebhook(ctx, w))
+
+	delivery, err := SendTestEvent(ctx, int(w.ID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, delivery.StatusCode)
+	require.Empty(t, delivery.Error)
+
+	statusCode.Store(http.StatusUnauthorized)
+	delivery, err = SendTestEvent(ctx, int(w.ID))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, delivery.StatusCode)
+	require.NotEmpty(t, delivery.Error)
+
+	server.Close()
+	delivery, err = SendTestEvent(ctx, int(w.ID))
+	require.NoError(t, err)
+	require.Zero(t, delivery.StatusCode)
+	require.NotEmpty(t, delivery.Error)
+}
+
+func TestSendTestEventTimeout(t *testing.T) {
+	ctx := context.Background()
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+	clearWebhooksTables(ctx, t)
+
+	defer func(timeout time.Duration) { testEventTimeout = timeout }(testEventTimeout)
+	testEventTimeout = 50 * time.Millisecond
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	w := mockWebhook()
+	w.URL = server.URL
+	require.NoError(t, AddWebhook(ctx, w))
+
+	delivery, err := SendTestEvent(ctx, int(w.ID))
+	require.NoError(t, err)
+	require.Zero(t, delivery.StatusCode)
+	require.Equal(t, "webhook endpoint did not respond within 50ms", delivery.Error)
+}
+
+func TestDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+	clearWebhooksTables(ctx, t)
+	_, err := db.Bun().NewDelete().Model((*DeadLetter)(nil)).Where("true").Exec(ctx)
+	require.NoError(t, err)
+
+	statusCode := atomic.NewInt64(http.StatusBadRequest)
+	received := atomic.NewInt64(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Inc()
+		w.WriteHeader(int(statusCode.Load()))
+	}))
+	defer server.Close()
+
+	singletonShipper = newShipper()
+	defer func() {
+		singletonShipper.Close()
+	}()
+
+	t.Log("an event rejected by its webhook is dead-lettered")
+	_, err = db.Bun().NewInsert().
+		Model(&Event{URL: server.URL, Payload: []byte(`{"test":true}`)}).
+		Exec(ctx)
+	require.NoError(t, err)
+	wakeShipper()
+
+	var deadLetters []DeadLetter
+	require.Eventually(t, func() bool {
+		deadLetters, err = GetDeadLetters(ctx)
+		require.NoError(t, err)
+		return len(deadLetters) == 1
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, server.URL, deadLetters[0].URL)
+	require.Equal(t, 1, deadLetters[0].Attempts)
+	require.NotEmpty(t, deadLetters[0].Error)
+
+	t.Log("a redelivered dead letter is delivered again")
+	statusCode.Store(http.StatusOK)
+	require.NoError(t, RedeliverDeadLetter(ctx, deadLetters[0].ID))
+	require.Eventually(t, func() bool {
+		return received.Load() == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	deadLetters, err = GetDeadLetters(ctx)
+	require.NoError(t, err)
+	require.Empty(t, deadLetters)
+
+	require.ErrorIs(t, RedeliverDeadLetter(ctx, 0), sql.ErrNoRows)
+}
+
+func TestReloadDrainsOldShipper(t *testing.T) {
+	ctx := context.Background()
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+	clearWebhooksTables(ctx, t)
+
+	inFlight := make(chan struct{}, 1)
+	release := make(chan struct{})
+	received := atomic.NewInt64(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case inFlight <- struct{}{}:
+			<-release
+		default:
+		}
+		received.Inc()
+	}))
+	defer server.Close()
+
+	Init()
+	events := make([]Event, 5)
+	for i := range events {
+		events[i] = Event{URL: server.URL, Payload: []byte(`{"test":true}`)}
+	}
+	_, err := db.Bun().NewInsert().Model(&events).Exec(ctx)
+	require.NoError(t, err)
+	wakeShipper()
+
+	t.Log("reloading while the old shipper is delivering loses no events")
+	<-inFlight
+	old := singletonShipper
+	Reload()
+	close(release)
+	require.Eventually(t, func() bool {
+		return received.Load() == int64(len(events))
+	}, 10*time.Second, 10*time.Millisecond)
+
+	t.Log("the old shipper is stopped by the time Deinit returns")
+	Deinit()
+	require.Empty(t, drainingShippers)
+	select {
+	case <-old.stop:
+	default:
+		t.Error("old shipper was not stopped")
+	}
+}
+
+func TestPayloadSignatures(t *testing.T) {
+	payload := []byte(`{"event_type":"EXPERIMENT_STATE_CHANGE"}`)
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	now := time.Now()
+	w := Webhook{SigningSecret: "new"}
+	require.Equal(t, sign("new"), payloadSignatures(payload, w.activeSigningSecrets(now)))
+
+	t.Log("the previous secret signs payloads until its transition ends")
+	w.PreviousSigningSecret = "old"
+	w.PreviousSigningSecretExpiresAt = ptrs.Ptr(now.Add(time.Hour))
+	require.Equal(t, sign("new")+","+sign("old"),
+		payloadSignatures(payload, w.activeSigningSecrets(now)))
+	require.Equal(t, []string{"new"}, w.activeSigningSecrets(now.Add(2*time.Hour)))
+
+	require.Empty(t, (&Webhook{}).activeSigningSecrets(now))
+}
+
+func TestDequeueEventsLoadsSigningSecrets(t *testing.T) {
+	ctx := context.Background()
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+	clearWebhooksTables(ctx, t)
+
+	w := mockWebhook()
+	w.SigningSecret = "0123456789abcdef0123456789abcdef"
+	require.NoError(t, AddWebhook(ctx, w))
+	_, err := db.Bun().NewInsert().Model(&[]Event{
+		{URL: w.URL, Payload: []byte(`{"signed":true}`), WebhookID: &w.ID},
+		{URL: w.URL, Payload: []byte(`{"signed":false}`)},
+	}).Exec(ctx)
+	require.NoError(t, err)
+
+	b, err := dequeueEvents(ctx, maxEventBatchSize)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, b.rollback()) }()
+	require.Len(t, b.events, 2)
+	for _, e := range b.events {
+		if e.WebhookID != nil {
+			require.Equal(t, []string{w.SigningSecret}, e.signingSecrets)
+		} else {
+			require.Empty(t, e.signingSecrets)
+		}
+	}
+}
+
 func scheduledWaitToDuration(factor int) time.Duration {
 	return 10 * time.Duration(factor) * time.Millisecond
 }
@@ -2,6 +2,7 @@ package webhooks
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/uptrace/bun"
 
@@ -33,9 +34,29 @@ type Webhook struct {
 	WebhookType WebhookType `bun:"webhook_type,notnull"`
 	URL         string      `bun:"url,notnull"`
 
+	// SigningSecret, if set, signs the payloads sent to the webhook. While rotating it, the secret
+	// it replaced also signs them until PreviousSigningSecretExpiresAt.
+	SigningSecret                  string     `bun:"signing_secret,nullzero"`
+	PreviousSigningSecret          string     `bun:"previous_signing_secret,nullzero"`
+	PreviousSigningSecretExpiresAt *time.Time `bun:"previous_signing_secret_expires_at"`
+
 	Triggers Triggers `bun:"rel:has-many,join:id=webhook_id"`
 }
 
+// activeSigningSecrets returns the secrets payloads sent to the webhook are signed with, the
+// current one first.
+func (w *Webhook) activeSigningSecrets(now time.Time) []string {
+	var secrets []string
+	if w.SigningSecret != "" {
+		secrets = append(secrets, w.SigningSecret)
+	}
+	if w.PreviousSigningSecret != "" && w.PreviousSigningSecretExpiresAt != nil &&
+		now.Before(*w.PreviousSigningSecretExpiresAt) {
+		secrets = append(secrets, w.PreviousSigningSecret)
+	}
+	return secrets
+}
+
 // WebhookFromProto returns a model Webhook from a proto definition.
 func WebhookFromProto(w *webhookv1.Webhook) Webhook {
 	return Webhook{
@@ -215,6 +236,29 @@ type Event struct {
 	ID      WebhookEventID `bun:"id,pk,autoincrement"`
 	URL     string         `bun:"url,notnull"`
 	Payload []byte         `bun:"payload,notnull"`
+	// WebhookID is the webhook the event is for, to sign it with the webhook's secrets. It is nil
+	// for events queued before webhooks could be signed and once the webhook is deleted.
+	WebhookID *WebhookID `bun:"webhook_id"`
+
+	// signingSecrets are the active signing secrets of the webhook, loaded along with the event.
+	signingSecrets []string `bun:"-"`
+}
+
+// DeadLetterID is the type for DeadLetter IDs.
+type DeadLetterID int
+
+// DeadLetter is an event whose delivery failed for good, kept to be inspected or redelivered. It
+// corresponds to a row in the "webhook_dead_letters" DB table.
+type DeadLetter struct {
+	bun.BaseModel `bun:"table:webhook_dead_letters"`
+
+	ID        DeadLetterID `bun:"id,pk,autoincrement"`
+	URL       string       `bun:"url,notnull"`
+	Payload   []byte       `bun:"payload,notnull"`
+	WebhookID *WebhookID   `bun:"webhook_id"`
+	Error     string       `bun:"error,notnull"`
+	Attempts  int          `bun:"attempts,notnull"`
+	FailedAt  time.Time    `bun:"failed_at,notnull,default:current_timestamp"`
 }
 
 // SlackMessageBody corresponds to an entire message as a Slack Block.
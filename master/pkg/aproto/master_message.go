@@ -23,6 +23,15 @@ type TelemetryInfo struct {
 	SegmentKey               string `json:"segment_key,omitempty"`
 	OtelEnabled              bool   `json:"otel_enabled"`
 	OtelExportedOtlpEndpoint string `json:"otel_endpoint"`
+	DeploymentLabel          string `json:"deployment_label,omitempty"`
+	Environment              string `json:"environment,omitempty"`
+}
+
+// SSOProviderInfo describes a single SSO provider offered on the login page.
+type SSOProviderInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	LoginURL string `json:"login_url"`
 }
 
 // MasterInfo contains the master information that the agent has connected to.
@@ -32,6 +41,23 @@ type MasterInfo struct {
 	ClusterID   string        `json:"cluster_id"`
 	ClusterName string        `json:"cluster_name"`
 	Telemetry   TelemetryInfo `json:"telemetry"`
+	// Capabilities advertises the optional features that are enabled, so that clients need not
+	// probe for their routes.
+	Capabilities CapabilitiesInfo `json:"capabilities"`
+}
+
+// Task log backends reported in CapabilitiesInfo.
+const (
+	LogBackendPostgres = "postgres"
+	LogBackendElastic  = "elastic"
+)
+
+// CapabilitiesInfo describes the optional features enabled on the master.
+type CapabilitiesInfo struct {
+	PrometheusEnabled   bool `json:"prometheus_enabled"`
+	AuditLoggingEnabled bool `json:"audit_logging_enabled"`
+	// LogBackend is where task logs are stored, LogBackendPostgres or LogBackendElastic.
+	LogBackend string `json:"log_backend"`
 }
 
 // MasterMessage is a union type for all messages sent from agents.
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/determined-ai/determined/master/pkg/checkpoints/archive"
@@ -73,3 +75,42 @@ func storageConfig2Str(config any) string {
 		return "unknown"
 	}
 }
+
+// StorageLocation returns the type of storage a checkpoint is in and the location of its files,
+// as a URL for cloud storage or a path on the host for shared_fs.
+func StorageLocation(
+	id string, storageConfig *expconf.CheckpointStorageConfig,
+) (storageType string, location string) {
+	storage := storageConfig.GetUnionMember()
+	storageType = storageConfig2Str(storage)
+	switch storage := storage.(type) {
+	case expconf.S3Config:
+		return storageType, cloudLocation("s3", storage.Bucket(), storage.Prefix(), id)
+	case expconf.GCSConfig:
+		return storageType, cloudLocation("gs", storage.Bucket(), storage.Prefix(), id)
+	case expconf.AzureConfig:
+		return storageType, cloudLocation("azure", storage.Container(), nil, id)
+	case expconf.HDFSConfig:
+		return storageType, strings.TrimRight(storage.URL(), "/") + path.Join("/", storage.Path(), id)
+	case expconf.SharedFSConfig:
+		base := storage.HostPath()
+		if storage.StoragePath() != nil {
+			if filepath.IsAbs(*storage.StoragePath()) {
+				base = *storage.StoragePath()
+			} else {
+				base = filepath.Join(base, *storage.StoragePath())
+			}
+		}
+		return storageType, filepath.Join(base, id)
+	default:
+		return storageType, ""
+	}
+}
+
+func cloudLocation(scheme, bucket string, prefix *string, id string) string {
+	key := id
+	if prefix != nil {
+		key = strings.TrimLeft(*prefix+"/"+id, "/")
+	}
+	return fmt.Sprintf("%s://%s/%s", scheme, bucket, key)
+}
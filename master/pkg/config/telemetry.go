@@ -1,10 +1,76 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+)
+
+// Deployment environments that telemetry may be tagged with.
+const (
+	TelemetryEnvironmentDev     = "dev"
+	TelemetryEnvironmentStaging = "staging"
+	TelemetryEnvironmentProd    = "prod"
+)
+
 // TelemetryConfig is the configuration for telemetry.
 type TelemetryConfig struct {
-	Enabled                  bool   `json:"enabled"`
-	SegmentMasterKey         string `json:"segment_master_key"`
-	OtelEnabled              bool   `json:"otel_enabled"`
-	OtelExportedOtlpEndpoint string `json:"otel_endpoint"`
-	SegmentWebUIKey          string `json:"segment_webui_key"`
+	Enabled                  bool                         `json:"enabled"`
+	SegmentMasterKey         string                       `json:"segment_master_key"`
+	OtelEnabled              bool                         `json:"otel_enabled"`
+	OtelExportedOtlpEndpoint string                       `json:"otel_endpoint"`
+	SegmentWebUIKey          string                       `json:"segment_webui_key"`
+	ExperimentStateReports   ExperimentStateReportsConfig `json:"experiment_state_reports"`
+	// DeploymentLabel and Environment are attached to every telemetry report, to tell apart the
+	// reports of different deployments.
+	DeploymentLabel string `json:"deployment_label"`
+	Environment     string `json:"environment"`
+}
+
+// Validate implements the check.Validatable interface.
+func (t TelemetryConfig) Validate() []error {
+	switch t.Environment {
+	case "", TelemetryEnvironmentDev, TelemetryEnvironmentStaging, TelemetryEnvironmentProd:
+		return nil
+	default:
+		return []error{fmt.Errorf(
+			"environment must be one of %s, %s or %s, got %q", TelemetryEnvironmentDev,
+			TelemetryEnvironmentStaging, TelemetryEnvironmentProd, t.Environment,
+		)}
+	}
+}
+
+// DeploymentProperties returns the properties identifying this deployment to attach to telemetry
+// reports, omitting any that are unset.
+func (t TelemetryConfig) DeploymentProperties() map[string]interface{} {
+	props := map[string]interface{}{}
+	if t.DeploymentLabel != "" {
+		props["deployment_label"] = t.DeploymentLabel
+	}
+	if t.Environment != "" {
+		props["environment"] = t.Environment
+	}
+	return props
+}
+
+// ExperimentStateReportsConfig configures how experiment state change reports made during restore
+// are queued and batched before being sent to the telemetry actor.
+type ExperimentStateReportsConfig struct {
+	BufferSize int `json:"buffer_size"`
+	BatchSize  int `json:"batch_size"`
+	Workers    int `json:"workers"`
+}
+
+// Validate implements the check.Validatable interface.
+func (e ExperimentStateReportsConfig) Validate() []error {
+	var errs []error
+	if e.BufferSize < 1 {
+		errs = append(errs, errors.New("experiment_state_reports.buffer_size must be at least 1"))
+	}
+	if e.BatchSize < 1 {
+		errs = append(errs, errors.New("experiment_state_reports.batch_size must be at least 1"))
+	}
+	if e.Workers < 1 {
+		errs = append(errs, errors.New("experiment_state_reports.workers must be at least 1"))
+	}
+	return errs
 }
@@ -9,8 +9,9 @@ import (
 // DefaultConfig returns the default configuration of logger.
 func DefaultConfig() *Config {
 	return &Config{
-		Level: "info",
-		Color: true,
+		Level:      "info",
+		Color:      true,
+		APIMaxTail: 10000,
 	}
 }
 
@@ -18,14 +19,25 @@ func DefaultConfig() *Config {
 type Config struct {
 	Level string `json:"level"`
 	Color bool   `json:"color"`
+	// ReportCaller records the source file and line of each log entry, at some runtime cost.
+	ReportCaller bool `json:"report_caller"`
+	// APIFields filters the structured fields of master log entries returned to non-admin API
+	// clients.
+	APIFields FieldFilter `json:"api_fields"`
+	// APIMaxTail is the most master log entries an API request may ask for with `tail`.
+	APIMaxTail int `json:"api_max_tail"`
 }
 
 // Validate implements the check.Validatable interface.
 func (c Config) Validate() []error {
+	var errs []error
 	if _, err := logrus.ParseLevel(c.Level); err != nil {
-		return []error{err}
+		errs = append(errs, err)
 	}
-	return nil
+	if c.APIMaxTail < 1 {
+		errs = append(errs, fmt.Errorf("api_max_tail must be positive, got %d", c.APIMaxTail))
+	}
+	return errs
 }
 
 // SetLogrus sets logrus globally.
@@ -36,6 +48,7 @@ func SetLogrus(c Config) {
 	}
 
 	logrus.SetLevel(level)
+	logrus.SetReportCaller(c.ReportCaller)
 	logrus.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
 		ForceColors:   true,
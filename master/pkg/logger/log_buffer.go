@@ -44,35 +44,124 @@ func computeSlice(startID int, endID int, limit int, totalEntries int, capacity
 	return startID % capacity, limit
 }
 
-func logrusMessageAndData(entry *logrus.Entry) string {
+func logrusFields(entry *logrus.Entry) map[string]string {
 	if len(entry.Data) == 0 {
-		return entry.Message
+		return nil
+	}
+	fields := make(map[string]string, len(entry.Data))
+	for key, value := range entry.Data {
+		fields[key] = fmt.Sprintf("%v", value)
+	}
+	return fields
+}
+
+func messageAndFields(message string, fields map[string]string) string {
+	if len(fields) == 0 {
+		return message
 	}
 
 	// Stringify the fields in a sorted order.
-	keys := make([]string, 0, len(entry.Data))
-	for key := range entry.Data {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
 		keys = append(keys, key)
 	}
 	sort.Strings(keys)
-	var fields []string
+	var pairs []string
 	for _, key := range keys {
-		strValue := fmt.Sprintf("%v", entry.Data[key])
-		fields = append(
-			fields,
-			fmt.Sprintf("%s=%q", key, strValue),
-		)
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, fields[key]))
 	}
 
-	return entry.Message + "  " + strings.Join(fields, " ")
+	return message + "  " + strings.Join(pairs, " ")
 }
 
+// ComponentField is the logrus field used to tag log entries with the subsystem emitting them.
+const ComponentField = "component"
+
+// actorTypeField is the logrus field set on all actor logs, used as the component if no explicit
+// component is set.
+const actorTypeField = "go-type"
+
 // Entry captures the interesting attributes of logrus.Entry.
 type Entry struct {
-	ID      int          `json:"id"`
-	Message string       `json:"message"`
-	Time    time.Time    `json:"time"`
-	Level   logrus.Level `json:"level"`
+	ID        int          `json:"id"`
+	Message   string       `json:"message"`
+	Time      time.Time    `json:"time"`
+	Level     logrus.Level `json:"level"`
+	Component string       `json:"component,omitempty"`
+	Source    string       `json:"source,omitempty"`
+
+	// baseMessage and fields are the parts Message was built from, kept to allow redaction.
+	baseMessage string
+	fields      map[string]string
+}
+
+// redactedValue replaces the values of fields a FieldFilter excludes.
+const redactedValue = "[redacted]"
+
+// FieldFilter selects which structured fields of log entries are shown to API clients.
+type FieldFilter struct {
+	// Allow, if not empty, lists the only fields that are shown.
+	Allow []string `json:"allow"`
+	// Deny lists fields that are never shown.
+	Deny []string `json:"deny"`
+}
+
+// IsEmpty returns whether the filter shows every field.
+func (f FieldFilter) IsEmpty() bool {
+	return len(f.Allow) == 0 && len(f.Deny) == 0
+}
+
+// Shows returns whether the filter shows the given field.
+func (f FieldFilter) Shows(field string) bool {
+	for _, denied := range f.Deny {
+		if denied == field {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, allowed := range f.Allow {
+		if allowed == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns the entry with the values of the fields the filter doesn't show redacted from
+// its message. The entry itself is left unmodified, since it is shared with the log buffer.
+func (e *Entry) Redacted(filter FieldFilter) *Entry {
+	if filter.IsEmpty() || len(e.fields) == 0 {
+		return e
+	}
+	fields := make(map[string]string, len(e.fields))
+	for key, value := range e.fields {
+		if !filter.Shows(key) {
+			value = redactedValue
+		}
+		fields[key] = value
+	}
+	redacted := *e
+	redacted.Message = messageAndFields(e.baseMessage, fields)
+	redacted.fields = fields
+	return &redacted
+}
+
+func entryComponent(entry *logrus.Entry) string {
+	for _, key := range []string{ComponentField, actorTypeField} {
+		if value, ok := entry.Data[key]; ok {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+	return ""
+}
+
+func entrySource(entry *logrus.Entry) string {
+	if entry.Caller == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
 }
 
 // EntriesBatch is a batch of logger.Entry.
@@ -130,7 +219,11 @@ func (lb *LogBuffer) write(entry *Entry) {
 func (lb *LogBuffer) Entries(startID int, endID int, limit int) []*Entry {
 	lb.lock.RLock()
 	defer lb.lock.RUnlock()
+	return lb.entriesLocked(startID, endID, limit)
+}
 
+// entriesLocked is Entries for callers that already hold the lock.
+func (lb *LogBuffer) entriesLocked(startID int, endID int, limit int) []*Entry {
 	startIndex, entryCount := computeSlice(startID, endID, limit, lb.totalEntries, len(lb.buffer))
 	if entryCount <= 0 {
 		return nil
@@ -147,6 +240,61 @@ func (lb *LogBuffer) Entries(startID int, endID int, limit int) []*Entry {
 	return entries
 }
 
+// EntriesMatching is like Entries, but only considers entries for which match returns true. The
+// limit applies to the matching entries, so the newest matching entries are returned when no
+// startID is given.
+func (lb *LogBuffer) EntriesMatching(
+	startID int, endID int, limit int, match func(*Entry) bool,
+) []*Entry {
+	var matching []*Entry
+	for _, entry := range lb.Entries(startID, endID, -1) {
+		if match(entry) {
+			matching = append(matching, entry)
+		}
+	}
+	if limit < 0 || len(matching) <= limit {
+		return matching
+	}
+	if startID == -1 {
+		return matching[len(matching)-limit:]
+	}
+	return matching[:limit]
+}
+
+// EntriesBetween returns the buffered entries logged at or after `after` and before `before`. It
+// also reports whether entries from the start of that window may have been evicted already,
+// because the buffer has wrapped and its oldest entry was logged after `after`.
+func (lb *LogBuffer) EntriesBetween(after, before time.Time) ([]*Entry, bool) {
+	// The entries must be read together with whether the buffer has wrapped, or an entry logged
+	// in between could evict the start of the window unnoticed.
+	lb.lock.RLock()
+	defer lb.lock.RUnlock()
+	wrapped := lb.totalEntries > len(lb.buffer)
+	entries := lb.entriesLocked(-1, -1, -1)
+	evicted := wrapped && len(entries) > 0 && entries[0].Time.After(after)
+
+	var matching []*Entry
+	for _, entry := range entries {
+		if !entry.Time.Before(after) && entry.Time.Before(before) {
+			matching = append(matching, entry)
+		}
+	}
+	return matching, evicted
+}
+
+// Entry returns the entry with the given ID, or nil if it hasn't been logged yet or has already
+// been evicted.
+func (lb *LogBuffer) Entry(id int) *Entry {
+	if id < 0 {
+		return nil
+	}
+	entries := lb.Entries(id, id+1, 1)
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries[0]
+}
+
 // Len returns the total number of entries written to the buffer.
 func (lb *LogBuffer) Len() int {
 	lb.lock.RLock()
@@ -156,10 +304,15 @@ func (lb *LogBuffer) Len() int {
 
 // Fire implements the logrus.Hook interface.
 func (lb *LogBuffer) Fire(entry *logrus.Entry) error {
+	fields := logrusFields(entry)
 	lb.write(&Entry{
-		Message: logrusMessageAndData(entry),
-		Time:    entry.Time,
-		Level:   entry.Level,
+		Message:     messageAndFields(entry.Message, fields),
+		Time:        entry.Time,
+		Level:       entry.Level,
+		Component:   entryComponent(entry),
+		Source:      entrySource(entry),
+		baseMessage: entry.Message,
+		fields:      fields,
 	})
 	return nil
 }
@@ -3,6 +3,7 @@ package logger
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -243,6 +244,34 @@ func TestComputeSlice(t *testing.T) {
 	startIndex, length = computeSlice(3, 4, -1, 4, 4)
 	assert.Equal(t, startIndex, 3)
 	assert.Equal(t, length, 1)
+
+	// A start after the end is an empty range rather than an error.
+	_, length = computeSlice(3, 1, -1, 4, 4)
+	assert.Equal(t, length, 0)
+}
+
+func TestLogBufferEntry(t *testing.T) {
+	buffer := NewLogBuffer(2)
+	assert.Assert(t, buffer.Entry(0) == nil)
+	for i := 0; i < 3; i++ {
+		buffer.write(&Entry{ID: i})
+	}
+
+	// The first entry has been evicted, and the fourth not yet logged.
+	for _, id := range []int{-1, 0, 3} {
+		assert.Assert(t, buffer.Entry(id) == nil, id)
+	}
+	for _, id := range []int{1, 2} {
+		assert.Equal(t, buffer.Entry(id).ID, id)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.Equal(t, len(DefaultConfig().Validate()), 0)
+
+	config := DefaultConfig()
+	config.APIMaxTail = 0
+	assert.Equal(t, len(config.Validate()), 1)
 }
 
 func TestEntryFieldInclusion(t *testing.T) {
@@ -267,3 +296,86 @@ func TestEntryFieldInclusion(t *testing.T) {
 	savedEntry = buffer.Entries(-1, -1, -1)[1]
 	assert.Equal(t, savedEntry.Message, originalEntry.Message+`  keyA="my great \"quote\""`)
 }
+
+func TestEntriesMatching(t *testing.T) {
+	buffer := NewLogBuffer(10)
+	logger := logrus.StandardLogger()
+
+	for i := 0; i < 6; i++ {
+		component := "scheduler"
+		if i%2 == 0 {
+			component = "provisioner"
+		}
+		entry := logger.WithField(ComponentField, component)
+		entry.Message = fmt.Sprintf("message %d", i)
+		assert.NilError(t, buffer.Fire(entry))
+	}
+
+	isScheduler := func(e *Entry) bool { return e.Component == "scheduler" }
+
+	entries := buffer.EntriesMatching(-1, -1, -1, isScheduler)
+	assert.Equal(t, len(entries), 3)
+	for _, e := range entries {
+		assert.Equal(t, e.Component, "scheduler")
+	}
+
+	entries = buffer.EntriesMatching(-1, -1, 2, isScheduler)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].ID, 3)
+	assert.Equal(t, entries[1].ID, 5)
+
+	entries = buffer.EntriesMatching(0, -1, 2, isScheduler)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].ID, 1)
+	assert.Equal(t, entries[1].ID, 3)
+}
+
+func TestEntryRedacted(t *testing.T) {
+	buffer := NewLogBuffer(1)
+	assert.NilError(t, buffer.Fire(&logrus.Entry{
+		Message: "query failed",
+		Data:    logrus.Fields{"sql": "SELECT 1", "token": "abc", "component": "db"},
+	}))
+	entry := buffer.Entries(-1, -1, -1)[0]
+	full := `query failed  component="db" sql="SELECT 1" token="abc"`
+	assert.Equal(t, entry.Message, full)
+
+	assert.Equal(t, entry.Redacted(FieldFilter{}).Message, full)
+	assert.Equal(t, entry.Redacted(FieldFilter{Deny: []string{"token"}}).Message,
+		`query failed  component="db" sql="SELECT 1" token="[redacted]"`)
+	assert.Equal(t, entry.Redacted(FieldFilter{Allow: []string{"component"}}).Message,
+		`query failed  component="db" sql="[redacted]" token="[redacted]"`)
+	// The buffered entry is left as it was.
+	assert.Equal(t, entry.Message, full)
+}
+
+func TestEntriesBetween(t *testing.T) {
+	buffer := NewLogBuffer(3)
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		assert.NilError(t, buffer.Fire(&logrus.Entry{
+			Message: fmt.Sprintf("message %d", i),
+			Time:    start.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	entries, evicted := buffer.EntriesBetween(start, start.Add(2*time.Minute))
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].ID, 0)
+	assert.Equal(t, entries[1].ID, 1)
+	assert.Assert(t, !evicted)
+
+	// Once the first entry is evicted, windows reaching back to it are reported as incomplete.
+	assert.NilError(t, buffer.Fire(&logrus.Entry{
+		Message: "message 3",
+		Time:    start.Add(3 * time.Minute),
+	}))
+	entries, evicted = buffer.EntriesBetween(start, start.Add(time.Hour))
+	assert.Equal(t, len(entries), 3)
+	assert.Equal(t, entries[0].ID, 1)
+	assert.Assert(t, evicted)
+
+	entries, evicted = buffer.EntriesBetween(start.Add(time.Minute), start.Add(time.Hour))
+	assert.Equal(t, len(entries), 3)
+	assert.Assert(t, !evicted)
+}
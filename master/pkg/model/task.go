@@ -96,6 +96,7 @@ type Allocation struct {
 	EndTime      *time.Time       `db:"end_time" bun:"end_time"`
 	State        *AllocationState `db:"state" bun:"state"`
 	IsReady      *bool            `db:"is_ready" bun:"is_ready"`
+	SlotType     *string          `db:"slot_type" bun:"slot_type"`
 }
 
 // AllocationState represents the current state of the task. Value indicates a partial ordering.
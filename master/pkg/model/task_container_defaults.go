@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/docker/docker/api/types"
 
@@ -48,6 +49,54 @@ func DefaultTaskContainerDefaults() *TaskContainerDefaultsConfig {
 	}
 }
 
+// Printable returns a copy of the defaults with registry credentials hidden.
+func (c TaskContainerDefaultsConfig) Printable() TaskContainerDefaultsConfig {
+	const hiddenValue = "********"
+	if c.RegistryAuth != nil {
+		// RegistryAuth is a pointer, so replace the pointer rather than the contents behind it.
+		printable := *c.RegistryAuth
+		for _, secret := range []*string{
+			&printable.Password, &printable.Auth, &printable.IdentityToken, &printable.RegistryToken,
+		} {
+			if *secret != "" {
+				*secret = hiddenValue
+			}
+		}
+		c.RegistryAuth = &printable
+	}
+	return c
+}
+
+// Redacted returns a printable copy of the defaults that also hides the values of environment
+// variables and leaves out the pod specs, either of which may hold secrets, for non-admins.
+func (c TaskContainerDefaultsConfig) Redacted() TaskContainerDefaultsConfig {
+	c = c.Printable()
+	if c.EnvironmentVariables != nil {
+		c.EnvironmentVariables = &RuntimeItems{
+			CPU:  redactEnvironmentVariables(c.EnvironmentVariables.CPU),
+			CUDA: redactEnvironmentVariables(c.EnvironmentVariables.CUDA),
+			ROCM: redactEnvironmentVariables(c.EnvironmentVariables.ROCM),
+		}
+	}
+	c.CPUPodSpec = nil
+	c.GPUPodSpec = nil
+	return c
+}
+
+func redactEnvironmentVariables(vars []string) []string {
+	if vars == nil {
+		return nil
+	}
+	redacted := make([]string, 0, len(vars))
+	for _, v := range vars {
+		if name, _, found := strings.Cut(v, "="); found {
+			v = name + "=********"
+		}
+		redacted = append(redacted, v)
+	}
+	return redacted
+}
+
 // UnmarshalJSON implements the json.Unmarshaler interface.
 // Setting defaults here is necessary over our usual "Define a default struct and unmarshal into it"
 // strategy because there are places (resource pool configs) where we need to know if the task
@@ -1,6 +1,7 @@
 package searcher
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
@@ -49,11 +50,22 @@ func (s SimulationResults) MarshalJSON() ([]byte, error) {
 type Simulation struct {
 	Results SimulationResults `json:"results"`
 	Seed    int64             `json:"seed"`
+	// Truncated is set when the simulation stopped early after creating its maximum of trials.
+	Truncated bool `json:"truncated"`
 }
 
 // Simulate simulates the searcher.
 func Simulate(
 	s *Searcher, seed *int64, valFunc ValidationFunction, randomOrder bool, metricName string,
+) (Simulation, error) {
+	return SimulateWithLimits(context.Background(), 0, s, seed, valFunc, randomOrder, metricName)
+}
+
+// SimulateWithLimits simulates the searcher until it completes, ctx is done, or maxTrials trials
+// have been created. A non-positive maxTrials means no limit.
+func SimulateWithLimits(
+	ctx context.Context, maxTrials int,
+	s *Searcher, seed *int64, valFunc ValidationFunction, randomOrder bool, metricName string,
 ) (Simulation, error) {
 	simulation := Simulation{
 		Results: make(SimulationResults),
@@ -89,6 +101,9 @@ func Simulate(
 	nextTrialID := 1
 	trialOpIdxs := map[model.RequestID]int{}
 	for !shutdown {
+		if err := ctx.Err(); err != nil {
+			return simulation, err
+		}
 		requestID, err := pickTrial(random, pending, requestIDs, randomOrder)
 		if err != nil {
 			return simulation, err
@@ -98,6 +113,10 @@ func Simulate(
 
 		switch operation := operation.(type) {
 		case Create:
+			if maxTrials > 0 && nextTrialID > maxTrials {
+				simulation.Truncated = true
+				return simulation, nil
+			}
 			simulation.Results[requestID] = []ValidateAfter{}
 			trialIDs[requestID] = nextTrialID
 			ops, err := s.TrialCreated(operation.RequestID)
@@ -0,0 +1,43 @@
+//nolint:exhaustivestruct
+package searcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/master/pkg/schemas"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+func TestSimulateWithLimits(t *testing.T) {
+	conf := expconf.RandomConfig{
+		RawMaxTrials: ptrs.Ptr(4), RawMaxLength: ptrs.Ptr(expconf.NewLengthInBatches(300)),
+	}
+	conf = schemas.WithDefaults(conf)
+	newSearcher := func() *Searcher { return NewSearcher(0, newRandomSearch(conf), nil) }
+
+	sim, err := SimulateWithLimits(
+		context.Background(), 0, newSearcher(), new(int64), ConstantValidation, true, defaultMetric,
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, len(sim.Results), 4)
+	assert.Assert(t, !sim.Truncated)
+
+	sim, err = SimulateWithLimits(
+		context.Background(), 2, newSearcher(), new(int64), ConstantValidation, true, defaultMetric,
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, len(sim.Results), 2)
+	assert.Assert(t, sim.Truncated)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = SimulateWithLimits(
+		ctx, 0, newSearcher(), new(int64), ConstantValidation, true, defaultMetric,
+	)
+	assert.Assert(t, errors.Is(err, context.Canceled))
+}
@@ -3,6 +3,7 @@ package tasks
 import (
 	"archive/tar"
 	"encoding/json"
+	"time"
 
 	"github.com/determined-ai/determined/master/pkg/archive"
 	"github.com/determined-ai/determined/master/pkg/cproto"
@@ -50,6 +51,9 @@ type GenericCommandSpec struct {
 	Port            *int
 	ProxyTCP        bool
 	Unauthenticated bool
+	// ProxyFlushInterval is how often HTTP responses proxied from Port are flushed; see
+	// proxy.Register.
+	ProxyFlushInterval time.Duration
 
 	WatchProxyIdleTimeout  bool
 	WatchRunnerIdleTimeout bool
@@ -0,0 +1 @@
+DROP TABLE experiment_idempotency_keys;
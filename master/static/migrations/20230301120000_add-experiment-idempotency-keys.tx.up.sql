@@ -0,0 +1,10 @@
+CREATE TABLE experiment_idempotency_keys (
+	user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	key text NOT NULL,
+	experiment_id integer NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX ix_experiment_idempotency_keys_created_at
+	ON experiment_idempotency_keys(created_at);
@@ -0,0 +1 @@
+ALTER TABLE allocations DROP COLUMN slot_type;
@@ -0,0 +1 @@
+ALTER TABLE allocations ADD COLUMN slot_type text;
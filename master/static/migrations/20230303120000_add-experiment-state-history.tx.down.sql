@@ -0,0 +1,3 @@
+DROP TRIGGER autoupdate_experiment_state_history ON experiments;
+DROP FUNCTION IF EXISTS public.record_experiment_state_change;
+DROP TABLE experiment_state_history;
@@ -0,0 +1,31 @@
+CREATE TABLE experiment_state_history (
+    id serial PRIMARY KEY,
+    experiment_id integer NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+    state public.experiment_state NOT NULL,
+    transition_time timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ix_experiment_state_history_experiment_id
+    ON experiment_state_history (experiment_id, transition_time);
+
+-- Seed the history with the state each existing experiment is in now.
+INSERT INTO experiment_state_history (experiment_id, state, transition_time)
+SELECT id, state, COALESCE(end_time, start_time) FROM experiments;
+
+CREATE OR REPLACE FUNCTION public.record_experiment_state_change ()
+    RETURNS TRIGGER
+    AS $$
+BEGIN
+    IF (TG_OP = 'INSERT' OR NEW.state IS DISTINCT FROM OLD.state) THEN
+        INSERT INTO experiment_state_history (experiment_id, state)
+        VALUES (NEW.id, NEW.state);
+    END IF;
+    RETURN NEW;
+END;
+$$
+LANGUAGE plpgsql;
+
+CREATE TRIGGER autoupdate_experiment_state_history
+    AFTER INSERT OR UPDATE OF state ON experiments
+    FOR EACH ROW
+    EXECUTE PROCEDURE record_experiment_state_change ();
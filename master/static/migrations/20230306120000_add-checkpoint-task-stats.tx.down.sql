@@ -0,0 +1,2 @@
+-- Nothing to do for down: dropping the CHECKPOINT value from stats_type would need its task stats
+-- to be deleted first, and an unused enum value is harmless to earlier versions.
@@ -0,0 +1,11 @@
+ALTER TYPE public.stats_type RENAME TO _stats_type;
+
+CREATE TYPE public.stats_type AS ENUM (
+    'QUEUED',
+    'IMAGEPULL',
+    'CHECKPOINT'
+);
+
+ALTER TABLE public.task_stats ALTER COLUMN event_type SET DATA TYPE public.stats_type USING (event_type::text::stats_type);
+
+DROP TYPE public._stats_type;
@@ -0,0 +1,8 @@
+CREATE TABLE webhook_dead_letters (
+  id SERIAL PRIMARY KEY,
+  url text NOT NULL,
+  payload bytea NOT NULL,
+  error text NOT NULL,
+  attempts integer NOT NULL,
+  failed_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,8 @@
+ALTER TABLE webhook_dead_letters DROP COLUMN webhook_id;
+
+ALTER TABLE webhook_events_queue DROP COLUMN webhook_id;
+
+ALTER TABLE webhooks
+  DROP COLUMN signing_secret,
+  DROP COLUMN previous_signing_secret,
+  DROP COLUMN previous_signing_secret_expires_at;
@@ -0,0 +1,10 @@
+ALTER TABLE webhooks
+  ADD COLUMN signing_secret text,
+  ADD COLUMN previous_signing_secret text,
+  ADD COLUMN previous_signing_secret_expires_at timestamptz;
+
+ALTER TABLE webhook_events_queue
+  ADD COLUMN webhook_id integer REFERENCES webhooks(id) ON DELETE SET NULL;
+
+ALTER TABLE webhook_dead_letters
+  ADD COLUMN webhook_id integer REFERENCES webhooks(id) ON DELETE SET NULL;
@@ -0,0 +1,72 @@
+WITH const AS (
+    SELECT
+        daterange($1 :: date, $2 :: date, '[]') AS period
+),
+quarters AS (
+    SELECT
+        date_trunc('quarter', resource_aggregates.date :: date) AT time zone 'UTC' AS period_start,
+        aggregation_type,
+        resource_aggregates.aggregation_key,
+        sum(seconds) AS seconds
+    FROM
+        resource_aggregates,
+        const
+    WHERE
+        -- `@>` determines whether the range contains the time.
+        const.period @> resource_aggregates.date
+    GROUP BY
+        date_trunc('quarter', resource_aggregates.date :: date) AT time zone 'UTC',
+        resource_aggregates.aggregation_type,
+        resource_aggregates.aggregation_key
+),
+starts AS (
+    SELECT
+        DISTINCT(period_start) AS period_start
+    FROM
+        quarters
+)
+SELECT
+    to_char(period_start, 'YYYY-"Q"Q') AS period_start,
+    'RESOURCE_ALLOCATION_AGGREGATION_PERIOD_QUARTERLY' AS period,
+    (
+        SELECT
+            seconds
+        FROM
+            quarters
+        WHERE
+            aggregation_type = 'total'
+            AND quarters.period_start = starts.period_start
+        LIMIT
+            1
+    ) AS seconds,
+    (
+        SELECT
+            jsonb_object_agg(aggregation_key, seconds)
+        FROM
+            quarters
+        WHERE
+            aggregation_type = 'username'
+            AND quarters.period_start = starts.period_start
+    ) AS by_username,
+    (
+        SELECT
+            jsonb_object_agg(aggregation_key, seconds)
+        FROM
+            quarters
+        WHERE
+            aggregation_type = 'experiment_label'
+            AND quarters.period_start = starts.period_start
+    ) AS by_experiment_label,
+    (
+        SELECT
+            jsonb_object_agg(aggregation_key, seconds)
+        FROM
+            quarters
+        WHERE
+            aggregation_type = 'resource_pool'
+            AND quarters.period_start = starts.period_start
+    ) AS by_resource_pool
+FROM
+    starts
+ORDER BY
+    period_start
@@ -0,0 +1,178 @@
+-- Aggregates allocation into periods that start at midnight in the time zone $4. Unlike the other
+-- aggregated allocation queries, this computes the seconds from the allocations themselves, since
+-- resource_aggregates is bucketed by UTC day.
+WITH const AS (
+    SELECT
+        tstzrange($1 :: timestamptz, $2 :: timestamptz) AS period,
+        $3 :: text AS unit,
+        $4 :: text AS tz
+),
+-- The start of each period in local time, along with the part of the requested range it covers.
+periods AS (
+    SELECT
+        local_start,
+        -- `*` computes the intersection of the two ranges.
+        const.period * tstzrange(
+            local_start AT time zone const.tz,
+            (local_start + steps.step) AT time zone const.tz
+        ) AS range
+    FROM
+        const,
+        (
+            SELECT
+                CASE
+                    WHEN const.unit = 'quarter' THEN interval '3 months'
+                    ELSE ('1 ' || const.unit) :: interval
+                END AS step
+            FROM
+                const
+        ) AS steps,
+        generate_series(
+            date_trunc(const.unit, lower(const.period) AT time zone const.tz),
+            upper(const.period) AT time zone const.tz - interval '1 microsecond',
+            steps.step
+        ) AS local_start
+),
+allocs_in_range AS (
+    SELECT
+        periods.local_start,
+        a.task_id,
+        a.resource_pool,
+        extract(
+            epoch
+            FROM
+                upper(periods.range * a.range) - lower(periods.range * a.range)
+        ) * a.slots :: float AS seconds
+    FROM
+        (
+            SELECT
+                *,
+                tstzrange(start_time, end_time) AS range
+            FROM
+                allocations
+            WHERE
+                start_time IS NOT NULL
+        ) AS a,
+        periods
+    WHERE
+        -- `&&` determines whether the ranges overlap.
+        periods.range && a.range
+),
+aggs AS (
+    SELECT
+        allocs_in_range.local_start,
+        'total' AS aggregation_type,
+        'total' AS aggregation_key,
+        sum(allocs_in_range.seconds) AS seconds
+    FROM
+        allocs_in_range
+    GROUP BY
+        allocs_in_range.local_start
+    UNION ALL
+    SELECT
+        allocs_in_range.local_start,
+        'username' AS aggregation_type,
+        users.username AS aggregation_key,
+        sum(allocs_in_range.seconds) AS seconds
+    FROM
+        allocs_in_range,
+        tasks,
+        jobs,
+        users
+    WHERE
+        allocs_in_range.task_id = tasks.task_id
+        AND tasks.job_id = jobs.job_id
+        AND jobs.owner_id = users.id
+    GROUP BY
+        allocs_in_range.local_start,
+        users.username
+    UNION ALL
+    SELECT
+        allocs_in_range.local_start,
+        'experiment_label' AS aggregation_type,
+        labels.label #>> '{}' AS aggregation_key,
+        sum(allocs_in_range.seconds) AS seconds
+    FROM
+        allocs_in_range,
+        trials,
+        (
+            SELECT
+                id,
+                jsonb_array_elements(
+                    CASE
+                        WHEN config ->> 'labels' IS NULL THEN '[]' :: jsonb
+                        ELSE config -> 'labels'
+                    END
+                ) AS label
+            FROM
+                experiments
+        ) AS labels
+    WHERE
+        allocs_in_range.task_id = trials.task_id
+        AND trials.experiment_id = labels.id
+    GROUP BY
+        allocs_in_range.local_start,
+        labels.label
+    UNION ALL
+    SELECT
+        allocs_in_range.local_start,
+        'resource_pool' AS aggregation_type,
+        allocs_in_range.resource_pool AS aggregation_key,
+        sum(allocs_in_range.seconds) AS seconds
+    FROM
+        allocs_in_range
+    GROUP BY
+        allocs_in_range.local_start,
+        allocs_in_range.resource_pool
+),
+starts AS (
+    SELECT
+        DISTINCT(local_start) AS local_start
+    FROM
+        aggs
+)
+SELECT
+    to_char(local_start, $5 :: text) AS period_start,
+    $6 :: text AS period,
+    (
+        SELECT
+            seconds
+        FROM
+            aggs
+        WHERE
+            aggregation_type = 'total'
+            AND aggs.local_start = starts.local_start
+        LIMIT
+            1
+    ) AS seconds,
+    (
+        SELECT
+            jsonb_object_agg(aggregation_key, seconds)
+        FROM
+            aggs
+        WHERE
+            aggregation_type = 'username'
+            AND aggs.local_start = starts.local_start
+    ) AS by_username,
+    (
+        SELECT
+            jsonb_object_agg(aggregation_key, seconds)
+        FROM
+            aggs
+        WHERE
+            aggregation_type = 'experiment_label'
+            AND aggs.local_start = starts.local_start
+    ) AS by_experiment_label,
+    (
+        SELECT
+            jsonb_object_agg(aggregation_key, seconds)
+        FROM
+            aggs
+        WHERE
+            aggregation_type = 'resource_pool'
+            AND aggs.local_start = starts.local_start
+    ) AS by_resource_pool
+FROM
+    starts
+ORDER BY
+    local_start